@@ -35,6 +35,8 @@ func SetupHandlers(r *mux.Router) {
 	r.HandleFunc("/status/formatted", getFormattedStatus).Methods("GET")
 	r.HandleFunc("/{component}/status", componentStatusHandler).Methods("POST")
 	r.HandleFunc("/{component}/configs", componentConfigHandler).Methods("GET")
+	r.HandleFunc("/metadata/{collector}/send", sendMetadata).Methods("POST")
+	r.HandleFunc("/config-check", getConfigCheck).Methods("GET")
 }
 
 func stopAgent(w http.ResponseWriter, r *http.Request) {
@@ -89,6 +91,57 @@ func makeFlare(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(filePath))
 }
 
+func sendMetadata(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if common.MetadataScheduler == nil {
+		body, _ := json.Marshal(map[string]string{"error": "metadata collection is disabled"})
+		http.Error(w, string(body), 503)
+		return
+	}
+
+	collector := mux.Vars(r)["collector"]
+	log.Infof("Forcing a run of the '%s' metadata collector", collector)
+	if err := common.MetadataScheduler.SendNow(collector); err != nil {
+		log.Errorf("Unable to send '%s' metadata: %v", collector, err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+
+	j, _ := json.Marshal(collector)
+	w.Write(j)
+}
+
+// getConfigCheck dumps what autodiscovery knows about the configs, the
+// templates and the services, the raw configs are part of it when the
+// `verbose` parameter is true
+func getConfigCheck(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if common.AC == nil {
+		body, _ := json.Marshal(map[string]string{"error": "autoconfig is disabled"})
+		http.Error(w, string(body), 503)
+		return
+	}
+
+	verbose := r.URL.Query().Get("verbose") == "true"
+	j, err := json.Marshal(common.AC.GetConfigCheck(verbose))
+	if err != nil {
+		log.Errorf("Unable to marshal the config check response: %s", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+	w.Write(j)
+}
+
 func componentConfigHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	component := vars["component"]
@@ -9,18 +9,44 @@
 package check
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 
+	log "github.com/cihub/seelog"
 	"github.com/gorilla/mux"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	apiutil "github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/collector/autodiscovery"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
 )
 
+// apiProvider is the provider of the configs scheduled through the API
+const apiProvider = "API"
+
+// maxBodySize limits the size of the configs sent to the API
+const maxBodySize = 1 << 20
+
 // SetupHandlers adds the specific handlers for /check endpoints
 func SetupHandlers(r *mux.Router) {
 	r.HandleFunc("/", listChecks).Methods("GET")
-	r.HandleFunc("/{name}", listCheck).Methods("GET", "DELETE")
+	r.HandleFunc("/", scheduleConfig).Methods("POST")
+	r.HandleFunc("/{name}", listCheck).Methods("GET")
+	r.HandleFunc("/{id}", patchInstance).Methods("PATCH")
+	r.HandleFunc("/{id}", unscheduleCheck).Methods("DELETE")
 	r.HandleFunc("/{name}/reload", reloadCheck).Methods("POST")
 }
 
+// configPayload is the config of a check sent to the API, in JSON or YAML
+type configPayload struct {
+	Name       string               `yaml:"name"`
+	InitConfig interface{}          `yaml:"init_config"`
+	Instances  []check.ConfigRawMap `yaml:"instances"`
+}
+
 func reloadCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	w.Write([]byte("Not yet implemented."))
@@ -35,3 +61,187 @@ func listCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	w.Write([]byte("Not yet implemented."))
 }
+
+// scheduleConfig loads and schedules the instances of a config, they're
+// persisted in the managed conf.d when the `persist` parameter is true
+func scheduleConfig(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !autoConfigEnabled(w) {
+		return
+	}
+	persist := r.URL.Query().Get("persist") == "true"
+	if persist && !persistenceEnabled() {
+		writeError(w, errPersistenceDisabled, 400)
+		return
+	}
+
+	payload := configPayload{}
+	if err := readBody(w, r, &payload); err != nil {
+		writeError(w, err, 400)
+		return
+	}
+	if err := validateCheckName(payload.Name); err != nil {
+		writeError(w, err, 400)
+		return
+	}
+	config := check.Config{Name: payload.Name, Provider: apiProvider}
+	// marshal the config like the file provider so the IDs don't change
+	// once it's persisted
+	config.InitConfig, _ = yaml.Marshal(payload.InitConfig)
+	for _, instance := range payload.Instances {
+		data, _ := yaml.Marshal(instance)
+		config.Instances = append(config.Instances, data)
+	}
+
+	ids, err := common.AC.ScheduleConfig(config)
+	if err != nil {
+		writeError(w, err, 400)
+		return
+	}
+	log.Infof("Scheduled %d instance(s) of %s through the API", len(ids), config.Name)
+
+	if persist {
+		for _, id := range ids {
+			instanceConfig, found := common.AC.GetInstanceConfig(id)
+			if !found {
+				continue
+			}
+			if err = persistConfig(instanceConfig, id); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			// don't run what won't survive a restart
+			for _, id := range ids {
+				common.AC.UnscheduleCheck(id)
+				removePersistedConfig(config.Name, id)
+			}
+			writeError(w, err, 500)
+			return
+		}
+	}
+
+	j, _ := json.Marshal(map[string][]check.ID{"ids": ids})
+	w.Write(j)
+}
+
+// patchInstance merges the settings sent in a check instance, the new
+// instance replaces the old one. Only the instances scheduled through the API
+// can be persisted.
+func patchInstance(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !autoConfigEnabled(w) {
+		return
+	}
+	id := check.ID(mux.Vars(r)["id"])
+	persist := r.URL.Query().Get("persist") == "true"
+	if persist && !persistenceEnabled() {
+		writeError(w, errPersistenceDisabled, 400)
+		return
+	}
+
+	patch := check.ConfigRawMap{}
+	if err := readBody(w, r, &patch); err != nil {
+		writeError(w, err, 400)
+		return
+	}
+	if persist {
+		// the persisted copy of an instance found by another provider would
+		// run along with the original after a restart
+		current, found := common.AC.GetInstanceConfig(id)
+		if found && current.Provider != apiProvider && !isPersisted(current.Name, id) {
+			writeError(w, fmt.Errorf("check %s wasn't scheduled through the API, it can't be persisted", id), 400)
+			return
+		}
+	}
+
+	config, newID, err := common.AC.PatchInstance(id, patch)
+	if err != nil {
+		writeError(w, err, errorStatus(err))
+		return
+	}
+	log.Infof("Patched check %s through the API, its new ID is %s", id, newID)
+
+	if newID != "" && (persist || isPersisted(config.Name, id)) {
+		if err := persistConfig(config, newID); err != nil {
+			writeError(w, err, 500)
+			return
+		}
+		if newID != id {
+			if err := removePersistedConfig(config.Name, id); err != nil {
+				log.Warnf("Unable to remove the persisted config of check %s: %s", id, err)
+			}
+		}
+	}
+
+	j, _ := json.Marshal(map[string]check.ID{"id": newID})
+	w.Write(j)
+}
+
+// unscheduleCheck stops a check instance, its persisted config is removed
+func unscheduleCheck(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !autoConfigEnabled(w) {
+		return
+	}
+	id := check.ID(mux.Vars(r)["id"])
+
+	config, found := common.AC.GetInstanceConfig(id)
+	if err := common.AC.UnscheduleCheck(id); err != nil {
+		writeError(w, err, errorStatus(err))
+		return
+	}
+	log.Infof("Unscheduled check %s through the API", id)
+
+	if found {
+		if err := removePersistedConfig(config.Name, id); err != nil {
+			writeError(w, err, 500)
+			return
+		}
+	}
+
+	j, _ := json.Marshal(map[string]check.ID{"id": id})
+	w.Write(j)
+}
+
+// autoConfigEnabled writes an error when AutoConfig isn't running
+func autoConfigEnabled(w http.ResponseWriter) bool {
+	if common.AC == nil {
+		writeError(w, fmt.Errorf("autoconfig is disabled"), 503)
+		return false
+	}
+	return true
+}
+
+// readBody unmarshals the JSON or YAML body of a request
+func readBody(w http.ResponseWriter, r *http.Request, out interface{}) error {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid body: %s", err)
+	}
+	return nil
+}
+
+func errorStatus(err error) int {
+	if _, notFound := err.(*autodiscovery.NotFoundError); notFound {
+		return 404
+	}
+	return 400
+}
+
+func writeError(w http.ResponseWriter, err error, code int) {
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	http.Error(w, string(body), code)
+}
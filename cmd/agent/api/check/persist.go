@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package check
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+var (
+	errPersistenceDisabled = errors.New("managed_confd_path is not set, configs can't be persisted")
+
+	// names of the checks as they can be found in a conf.d
+	checkNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	// characters of the check IDs that don't go in file names
+	unsafeIDCharsRe = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+)
+
+// configFile is the content of a config file of the managed conf.d
+type configFile struct {
+	InitConfig interface{}          `yaml:"init_config"`
+	Instances  []check.ConfigRawMap `yaml:"instances"`
+}
+
+func persistenceEnabled() bool {
+	return config.Datadog.GetString("managed_confd_path") != ""
+}
+
+func validateCheckName(name string) error {
+	if !checkNameRe.MatchString(name) || name[0] == '.' {
+		return fmt.Errorf("invalid check name %q", name)
+	}
+	return nil
+}
+
+// persistedConfigPath returns the path of the file persisting a check
+// instance, the file provider loads it from the `<name>.d` folder
+func persistedConfigPath(name string, id check.ID) string {
+	return filepath.Join(
+		config.Datadog.GetString("managed_confd_path"),
+		name+".d",
+		unsafeIDCharsRe.ReplaceAllString(string(id), "_")+".yaml",
+	)
+}
+
+// persistConfig writes the single instance config of a check in the managed
+// conf.d so it's scheduled again when the agent restarts
+func persistConfig(c check.Config, id check.ID) error {
+	if !persistenceEnabled() {
+		return errPersistenceDisabled
+	}
+	if err := validateCheckName(c.Name); err != nil {
+		return err
+	}
+
+	file := configFile{Instances: make([]check.ConfigRawMap, len(c.Instances))}
+	if err := yaml.Unmarshal(c.InitConfig, &file.InitConfig); err != nil {
+		return err
+	}
+	for i, instance := range c.Instances {
+		if err := yaml.Unmarshal(instance, &file.Instances[i]); err != nil {
+			return err
+		}
+	}
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	path := persistedConfigPath(c.Name, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// write the file atomically so a restart never sees half of it, the
+	// temporary file isn't in the folder of the check so it's never loaded
+	tmp := filepath.Join(config.Datadog.GetString("managed_confd_path"), fmt.Sprintf(".%s-%s.tmp", c.Name, filepath.Base(path)))
+	if err := ioutil.WriteFile(tmp, data, 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// removePersistedConfig removes the persisted config of a check instance, if
+// there's one
+func removePersistedConfig(name string, id check.ID) error {
+	if !persistenceEnabled() || validateCheckName(name) != nil {
+		return nil
+	}
+	err := os.Remove(persistedConfigPath(name, id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// isPersisted returns whether the config of a check instance is persisted
+func isPersisted(name string, id check.ID) bool {
+	if !persistenceEnabled() || validateCheckName(name) != nil {
+		return false
+	}
+	_, err := os.Stat(persistedConfigPath(name, id))
+	return err == nil
+}
@@ -8,11 +8,16 @@ package app
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/listeners"
+	"github.com/DataDog/datadog-agent/pkg/collector/providers"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/status"
@@ -21,10 +26,19 @@ import (
 )
 
 var (
-	checkRate  bool
-	checkName  string
-	checkDelay int
-	logLevel   string
+	checkRate       bool
+	checkName       string
+	checkDelay      int
+	checkJSON       bool
+	checkConfigPath string
+	checkInstance   int
+	checkSet        []string
+	checkTimes      int
+	checkInterval   time.Duration
+	checkADHost     string
+	checkADPorts    []int
+	checkADLabels   []string
+	logLevel        string
 )
 
 // Make the check cmd aggregator never flush by setting a very high interval
@@ -36,13 +50,34 @@ func init() {
 	checkCmd.Flags().BoolVarP(&checkRate, "check-rate", "r", false, "check rates by running the check twice")
 	checkCmd.Flags().StringVarP(&logLevel, "log-level", "l", "", "set the log level (default 'off')")
 	checkCmd.Flags().IntVarP(&checkDelay, "delay", "d", 100, "delay between running the check and grabbing the metrics in miliseconds")
+	checkCmd.Flags().BoolVarP(&checkJSON, "json", "", false, "print what every run of every instance submitted as JSON, without waiting for the aggregator")
+	checkCmd.Flags().StringVarP(&checkConfigPath, "config", "", "", "load the check configuration from this file instead of the configuration providers")
+	checkCmd.Flags().IntVarP(&checkInstance, "instance", "", 0, "only run the instance at this position in the configuration, starting at 1 (default all)")
+	checkCmd.Flags().StringArrayVarP(&checkSet, "set", "", []string{}, "override a setting of the instances, as key=value with a YAML value, nested keys are separated by dots")
+	checkCmd.Flags().IntVarP(&checkTimes, "times", "", 1, "number of times to run each instance")
+	checkCmd.Flags().DurationVarP(&checkInterval, "interval", "", 0, "time to wait between two runs of an instance")
+	checkCmd.Flags().StringVarP(&checkADHost, "ad-host", "", "127.0.0.1", "value of %%host%% in autodiscovery templates")
+	checkCmd.Flags().IntSliceVarP(&checkADPorts, "ad-port", "", []int{80}, "values of %%port%% in autodiscovery templates, the highest is used unless %%port_<index>%% is")
+	checkCmd.Flags().StringSliceVarP(&checkADLabels, "ad-label", "", []string{}, "labels resolving %%label_<key>%% in autodiscovery templates, as key=value")
 	checkCmd.SetArgs([]string{"checkName"})
 }
 
 var checkCmd = &cobra.Command{
 	Use:   "check <check_name>",
 	Short: "Run the specified check",
-	Long:  `Use this to run a specific check with a specific rate`,
+	Long: `Use this to run a specific check with a specific rate.
+
+The configuration is looked up with the configuration providers unless --config
+points to a configuration file. --instance and --set select and override the
+instances to run. The template variables of autodiscovery templates are resolved
+against a fake service, see --ad-host, --ad-port and --ad-label. %%env_<VAR>%%
+and %%hostname%% are resolved with the environment and the hostname of the
+command.
+
+With --json, the metric samples, service checks and events submitted by every run
+of every instance are printed as a single JSON document, along with the warnings
+and the error of the run. They're sorted so the output only changes when the check
+submits something different.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Global Agent configuration
 		err := common.SetupConfig(confFilePath)
@@ -79,15 +114,30 @@ var checkCmd = &cobra.Command{
 			return err
 		}
 
-		s := &serializer.Serializer{Forwarder: common.Forwarder}
+		if checkTimes < 1 {
+			return fmt.Errorf("--times must be at least 1")
+		}
+		if checkRate && checkTimes == 1 {
+			checkTimes = 2
+		}
+
+		s := serializer.NewSerializer(common.Forwarder)
 		agg := aggregator.InitAggregatorWithFlushInterval(s, hostname, checkCmdFlushInterval)
 		common.SetupAutoConfig(config.Datadog.GetString("confd_path"))
-		cs := common.AC.GetChecksByName(checkName)
+		cs, err := getChecks(checkName)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
 		if len(cs) == 0 {
 			fmt.Println("no check found")
 			return fmt.Errorf("no check found")
 		}
 
+		if checkJSON {
+			return printCheckRuns(cs)
+		}
+
 		if len(cs) > 1 {
 			fmt.Println("Multiple check instances found, running each of them")
 		}
@@ -102,31 +152,209 @@ var checkCmd = &cobra.Command{
 
 			checkStatus, _ := status.GetCheckStatus(c, s)
 			fmt.Println(string(checkStatus))
+			c.Stop()
 		}
 
 		return nil
 	},
 }
 
+// getChecks loads the checks to run, from the configuration providers or
+// the --config file, applying --instance and --set
+func getChecks(name string) ([]check.Check, error) {
+	if checkConfigPath == "" && checkInstance == 0 && len(checkSet) == 0 {
+		return common.AC.GetChecksByName(name), nil
+	}
+
+	var configs []check.Config
+	if checkConfigPath != "" {
+		c, err := providers.GetCheckConfigFromFile(name, checkConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("can't load %s: %s", checkConfigPath, err)
+		}
+		configs = []check.Config{c}
+	} else {
+		configs = common.AC.GetConfigsByName(name)
+	}
+
+	checks := []check.Check{}
+	for _, c := range configs {
+		prepared, err := prepareConfig(c)
+		if err != nil {
+			return nil, err
+		}
+		cs, err := common.AC.GetChecks(prepared)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, cs...)
+	}
+	return checks, nil
+}
+
+// prepareConfig resolves the template variables of the config and applies
+// --instance and --set to it
+func prepareConfig(c check.Config) (check.Config, error) {
+	if checkInstance != 0 {
+		if checkInstance < 0 || checkInstance > len(c.Instances) {
+			return c, fmt.Errorf("invalid instance %d, the configuration of %s has %d instance(s)", checkInstance, c.Name, len(c.Instances))
+		}
+		c.Instances = []check.ConfigData{c.Instances[checkInstance-1]}
+	}
+
+	if c.IsTemplate() {
+		resolved, err := autodiscovery.ResolveTemplateForService(c, &templateService{adIdentifiers: c.ADIdentifiers})
+		if err != nil {
+			return c, fmt.Errorf("can't resolve the template %s: %s", c.Name, err)
+		}
+		c = resolved
+	} else {
+		c.Instances = append([]check.ConfigData{}, c.Instances...)
+	}
+
+	for _, setting := range checkSet {
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 {
+			return c, fmt.Errorf("invalid setting %q, it must be key=value", setting)
+		}
+		for i := range c.Instances {
+			if err := c.Instances[i].SetValue(parts[0], parts[1]); err != nil {
+				return c, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// templateService is the fake service the templates are resolved against,
+// its host, ports and labels are set with --ad-host, --ad-port and --ad-label
+type templateService struct {
+	adIdentifiers []string
+}
+
+func (s *templateService) GetID() listeners.ID {
+	return listeners.ID("check-command")
+}
+
+func (s *templateService) GetADIdentifiers() ([]string, error) {
+	return s.adIdentifiers, nil
+}
+
+func (s *templateService) GetHosts() (map[string]string, error) {
+	return map[string]string{"bridge": checkADHost}, nil
+}
+
+func (s *templateService) GetPorts() ([]int, error) {
+	return checkADPorts, nil
+}
+
+func (s *templateService) GetTags() ([]string, error) {
+	return []string{}, nil
+}
+
+func (s *templateService) GetPid() (int, error) {
+	return os.Getpid(), nil
+}
+
+func (s *templateService) GetHostname() (string, error) {
+	return os.Hostname()
+}
+
+func (s *templateService) GetEnv() (map[string]string, error) {
+	env := make(map[string]string)
+	for _, e := range os.Environ() {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env, nil
+}
+
+func (s *templateService) GetLabels() (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, label := range checkADLabels {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid label %q, it must be key=value", label)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
+func (s *templateService) GetNamedPorts() (map[string]int, error) {
+	return nil, fmt.Errorf("the ports have no name, use %%%%port_<index>%%%% with --ad-port")
+}
+
 func runCheck(c check.Check, agg *aggregator.BufferedAggregator) *check.Stats {
 	s := check.NewStats(c)
-	i := 0
-	times := 1
-	if checkRate {
-		times = 2
-	}
-	for i < times {
+	for i := 0; i < checkTimes; i++ {
+		if i > 0 {
+			time.Sleep(checkInterval)
+		}
 		t0 := time.Now()
 		err := c.Run()
 		warnings := c.GetWarnings()
 		mStats, _ := c.GetMetricStats()
 		s.Add(time.Since(t0), err, warnings, mStats)
-		i++
 	}
 
 	return s
 }
 
+// checkInstanceRuns is the JSON output of an instance for `check --json`
+type checkInstanceRuns struct {
+	Check string     `json:"check"`
+	ID    check.ID   `json:"id"`
+	Runs  []checkRun `json:"runs"`
+}
+
+// checkRun is what a check run submitted, along with its warnings and error
+type checkRun struct {
+	aggregator.Recording
+	Warnings []string `json:"warnings"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// printCheckRuns runs the checks with a recording sender and prints what
+// every run submitted as JSON
+func printCheckRuns(cs []check.Check) error {
+	instances := []checkInstanceRuns{}
+	for _, c := range cs {
+		recorder := aggregator.NewRecordingSender()
+		if err := aggregator.SetSender(recorder, c.ID()); err != nil {
+			return err
+		}
+
+		instance := checkInstanceRuns{Check: c.String(), ID: c.ID(), Runs: []checkRun{}}
+		for i := 0; i < checkTimes; i++ {
+			if i > 0 {
+				time.Sleep(checkInterval)
+			}
+			run := checkRun{Warnings: []string{}}
+			if err := c.Run(); err != nil {
+				run.Error = err.Error()
+			}
+			for _, w := range c.GetWarnings() {
+				run.Warnings = append(run.Warnings, w.Error())
+			}
+			run.Recording = recorder.Flush()
+			instance.Runs = append(instance.Runs, run)
+		}
+		c.Stop()
+		aggregator.DestroySender(c.ID())
+		instances = append(instances, instance)
+	}
+
+	j, err := json.MarshalIndent(instances, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(j))
+	return nil
+}
+
 func getMetrics(agg *aggregator.BufferedAggregator) {
 	series := agg.GetSeries()
 	if len(series) != 0 {
@@ -0,0 +1,194 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/collector/autodiscovery"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configCheckVerbose bool
+	configCheckJSON    bool
+)
+
+func init() {
+	AgentCmd.AddCommand(configCheckCommand)
+	configCheckCommand.Flags().BoolVarP(&configCheckVerbose, "verbose", "v", false, "print the raw YAML of the configs and the templates")
+	configCheckCommand.Flags().BoolVarP(&configCheckJSON, "json", "j", false, "print out raw json")
+}
+
+var configCheckCommand = &cobra.Command{
+	Use:   "configcheck",
+	Short: "Print what autodiscovery knows about the check configurations",
+	Long: `Query the running agent for the configs and the templates collected by every
+configuration provider, the services reported by the listeners and the templates
+matching their AD identifiers, the configs resolved from the templates, the checks
+scheduled for every config and the errors resolving templates, loading and
+scheduling checks.
+
+Secrets (ENC[<handle>] values) are never decrypted in the output.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := common.SetupConfig(confFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+		return doConfigCheck(os.Stdout)
+	},
+}
+
+func doConfigCheck(w io.Writer) error {
+	c := common.GetClient(false) // FIX: get certificates right then make this true
+	urlstr := fmt.Sprintf("https://localhost:%v/agent/config-check?verbose=%t", config.Datadog.GetInt("cmd_port"), configCheckVerbose)
+
+	// Set session token
+	util.SetAuthToken()
+
+	r, err := common.DoGet(c, urlstr)
+	if err != nil {
+		errMap := make(map[string]string)
+		json.Unmarshal(r, &errMap)
+		// If the error has been marshalled into a json object, check it and return it properly
+		if e, found := errMap["error"]; found {
+			err = errors.New(e)
+		}
+		fmt.Fprintf(w, "Could not reach agent: %v \nMake sure the agent is running before requesting the config check and contact support if you continue having issues. \n", err)
+		return err
+	}
+
+	if configCheckJSON {
+		var prettyJSON bytes.Buffer
+		json.Indent(&prettyJSON, r, "", "  ")
+		fmt.Fprintln(w, prettyJSON.String())
+		return nil
+	}
+
+	res := autodiscovery.ConfigCheckResponse{}
+	if err := json.Unmarshal(r, &res); err != nil {
+		return fmt.Errorf("unable to decode the response of the agent: %s", err)
+	}
+	printConfigCheck(w, res)
+	return nil
+}
+
+// printConfigCheck renders the config check response for humans
+func printConfigCheck(w io.Writer, res autodiscovery.ConfigCheckResponse) {
+	fmt.Fprintln(w, "=== Configuration providers ===")
+	for _, p := range res.Providers {
+		mode := "collected once"
+		if p.Watched {
+			mode = "watched"
+		} else if p.Polled {
+			mode = "polled"
+		}
+		fmt.Fprintf(w, "\n%s (%s)\n", p.Name, mode)
+		fmt.Fprintf(w, "  Configs: %d\n", len(p.Configs))
+		for _, c := range p.Configs {
+			printConfigInfo(w, c, "    ")
+		}
+		fmt.Fprintf(w, "  Templates: %d\n", len(p.Templates))
+		for _, c := range p.Templates {
+			printConfigInfo(w, c, "    ")
+		}
+	}
+
+	fmt.Fprintln(w, "\n=== Services ===")
+	if len(res.Services) == 0 {
+		fmt.Fprintln(w, "\nNo service reported by the listeners")
+	}
+	for _, s := range res.Services {
+		fmt.Fprintf(w, "\n%s\n", s.ID)
+		fmt.Fprintf(w, "  AD identifiers: %s\n", strings.Join(s.ADIdentifiers, ", "))
+		if len(s.Matches) == 0 {
+			fmt.Fprintln(w, "  No template matches its AD identifiers")
+		}
+		for _, m := range s.Matches {
+			fmt.Fprintf(w, "  Matches template %s (%s) on %s\n", m.Template, m.Digest, m.ADIdentifier)
+		}
+		printCheckIDs(w, s.Checks, "  ")
+	}
+
+	fmt.Fprintln(w, "\n=== Resolved configs ===")
+	if len(res.ResolvedConfigs) == 0 {
+		fmt.Fprintln(w, "\nNo config resolved from a template")
+	}
+	for _, c := range res.ResolvedConfigs {
+		fmt.Fprintln(w)
+		printConfigInfo(w, c, "")
+	}
+
+	// the resolve errors are printed with their template
+	fmt.Fprintln(w, "\n=== Errors ===")
+	if len(res.LoaderErrors)+len(res.RunErrors) == 0 {
+		fmt.Fprintln(w, "\nNo error loading or scheduling checks")
+	}
+	names := make([]string, 0, len(res.LoaderErrors))
+	for name := range res.LoaderErrors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "\nLoading %s:\n", name)
+		for loader, err := range res.LoaderErrors[name] {
+			fmt.Fprintf(w, "  %s: %s\n", loader, err)
+		}
+	}
+	ids := make([]string, 0, len(res.RunErrors))
+	for id := range res.RunErrors {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(w, "\nScheduling %s: %s\n", id, res.RunErrors[check.ID(id)])
+	}
+}
+
+func printConfigInfo(w io.Writer, c autodiscovery.ConfigInfo, indent string) {
+	fmt.Fprintf(w, "%s- %s (digest %s, from %s)\n", indent, c.Name, c.Digest, c.Provider)
+	if len(c.ADIdentifiers) > 0 {
+		fmt.Fprintf(w, "%s  AD identifiers: %s\n", indent, strings.Join(c.ADIdentifiers, ", "))
+	}
+	printCheckIDs(w, c.Checks, indent+"  ")
+	for svc, err := range c.ResolveErrors {
+		fmt.Fprintf(w, "%s  Can't be resolved against %s: %s\n", indent, svc, err)
+	}
+	if c.InitConfig != "" {
+		fmt.Fprintf(w, "%s  init_config:\n%s\n", indent, indentLines(c.InitConfig, indent+"    "))
+	}
+	for i, instance := range c.Instances {
+		fmt.Fprintf(w, "%s  instance %d:\n%s\n", indent, i, indentLines(instance, indent+"    "))
+	}
+}
+
+func printCheckIDs(w io.Writer, ids []check.ID, indent string) {
+	if len(ids) == 0 {
+		fmt.Fprintf(w, "%sNo check scheduled\n", indent)
+		return
+	}
+	strIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		strIDs = append(strIDs, string(id))
+	}
+	fmt.Fprintf(w, "%sChecks: %s\n", indent, strings.Join(strIDs, ", "))
+}
+
+func indentLines(s, indent string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	return indent + strings.Join(lines, "\n"+indent)
+}
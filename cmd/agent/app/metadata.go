@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	metadataCmd.AddCommand(metadataSendCmd)
+	AgentCmd.AddCommand(metadataCmd)
+}
+
+var metadataCmd = &cobra.Command{
+	Use:   "metadata",
+	Short: "Interact with the metadata collectors of the running Agent",
+	Long:  ``,
+}
+
+var metadataSendCmd = &cobra.Command{
+	Use:   "send <collector>",
+	Short: "Force the running Agent to collect and send the given metadata now",
+	Long: `Force the running Agent to run the given metadata collector (e.g. host,
+agent_checks) immediately. The collector keeps its regular schedule.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("a single metadata collector name is required")
+		}
+		err := common.SetupConfig(confFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+		return requestMetadataSend(args[0])
+	},
+}
+
+func requestMetadataSend(collector string) error {
+	c := common.GetClient(false) // FIX: get certificates right then make this true
+	urlstr := fmt.Sprintf("https://localhost:%v/agent/metadata/%s/send", config.Datadog.GetInt("cmd_port"), url.PathEscape(collector))
+
+	// Set session token
+	util.SetAuthToken()
+
+	r, e := common.DoPost(c, urlstr, "application/json", bytes.NewBuffer([]byte{}))
+	if e != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(r, &errMap)
+		// If the error has been marshalled into a json object, check it and return it properly
+		if err, found := errMap["error"]; found {
+			e = fmt.Errorf(err)
+		}
+
+		fmt.Printf("Could not send '%s' metadata: %v\n", collector, e)
+		return e
+	}
+
+	fmt.Printf("'%s' metadata sent.\n", collector)
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/metadata"
+	"github.com/DataDog/datadog-agent/pkg/openmetrics"
 	"github.com/DataDog/datadog-agent/pkg/pidfile"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -36,8 +37,12 @@ import (
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/containers"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/embed"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/network"
+	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/prometheus"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system"
 
+	// register the plugin check loader
+	_ "github.com/DataDog/datadog-agent/pkg/collector/plugin"
+
 	// register metadata providers
 	_ "github.com/DataDog/datadog-agent/pkg/collector/metadata"
 	_ "github.com/DataDog/datadog-agent/pkg/metadata"
@@ -176,10 +181,20 @@ func StartAgent() error {
 	log.Debugf("Forwarder started")
 
 	// setup the aggregator
-	s := &serializer.Serializer{Forwarder: common.Forwarder}
+	s := serializer.NewSerializer(common.Forwarder)
 	agg := aggregator.InitAggregator(s, hostname)
 	agg.AddAgentStartupEvent(version.AgentVersion)
 
+	// expose the aggregated metrics in the Prometheus/OpenMetrics formats
+	if config.Datadog.GetBool("metrics_endpoint_enabled") {
+		store := openmetrics.NewStore(time.Duration(config.Datadog.GetInt("metrics_endpoint_expiry_seconds")) * time.Second)
+		agg.AddSeriesFlushHook(store.Update)
+		common.MetricsEndpoint, err = openmetrics.NewServer(store)
+		if err != nil {
+			log.Errorf("Could not start the metrics endpoint: %s", err)
+		}
+	}
+
 	// start dogstatsd
 	if config.Datadog.GetBool("use_dogstatsd") {
 		var err error
@@ -199,7 +214,13 @@ func StartAgent() error {
 	if config.Datadog.GetBool("enable_metadata_collection") {
 		common.MetadataScheduler = metadata.NewScheduler(s, hostname)
 		var C []config.MetadataProviders
-		err = config.Datadog.UnmarshalKey("metadata_providers", &C)
+		// `metadata_collectors` is the key of datadog.yaml, `metadata_providers`
+		// is still read when it's the only one set
+		key := "metadata_collectors"
+		if !config.Datadog.IsSet(key) && config.Datadog.IsSet("metadata_providers") {
+			key = "metadata_providers"
+		}
+		err = config.Datadog.UnmarshalKey(key, &C)
 		if err == nil {
 			log.Debugf("Adding configured providers to the metadata collector")
 			for _, c := range C {
@@ -215,7 +236,7 @@ func StartAgent() error {
 				}
 			}
 		} else {
-			log.Errorf("Unable to parse metadata_providers config: %v", err)
+			log.Errorf("Unable to parse %s config: %v", key, err)
 		}
 		// Should be always true, except in some edge cases (multiple agents per host)
 		err = common.MetadataScheduler.AddCollector("host", hostMetadataCollectorInterval*time.Second)
@@ -245,6 +266,9 @@ func StopAgent() {
 	if common.MetadataScheduler != nil {
 		common.MetadataScheduler.Stop()
 	}
+	if common.MetricsEndpoint != nil {
+		common.MetricsEndpoint.Stop()
+	}
 	api.StopServer()
 	if common.Forwarder != nil {
 		common.Forwarder.Stop()
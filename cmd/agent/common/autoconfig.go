@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package common
+
+import (
+	"path/filepath"
+
+	"github.com/DataDog/datadog-agent/pkg/collector"
+	"github.com/DataDog/datadog-agent/pkg/collector/autodiscovery"
+	"github.com/DataDog/datadog-agent/pkg/collector/listeners"
+	"github.com/DataDog/datadog-agent/pkg/collector/loaders"
+	"github.com/DataDog/datadog-agent/pkg/collector/providers"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	log "github.com/cihub/seelog"
+)
+
+// SetupAutoConfig configures the global AutoConfig:
+//   1. add the configuration providers
+//   2. add the check loaders
+//   3. add the listeners, the container ones need the docker build
+func SetupAutoConfig(confdPath string) {
+	setupContainers()
+
+	// create the Collector instance and start all the components
+	// NOTICE: this will also setup the Python environment
+	coll := collector.NewCollector(GetPythonPaths()...)
+
+	// create the Autoconfig instance
+	AC = autodiscovery.NewAutoConfig(coll)
+
+	// add the check loaders
+	for _, loader := range loaders.LoaderCatalog() {
+		AC.AddLoader(loader)
+		log.Debugf("Added %s to AutoConfig", loader)
+	}
+
+	// Add the configuration providers
+	// File Provider is hardocded and always enabled
+	confSearchPaths := []string{
+		confdPath,
+		filepath.Join(GetDistPath(), "conf.d"),
+	}
+	fileProvider := providers.NewFileConfigProvider(confSearchPaths)
+	// the file provider is watched when it's polled, the instances of a plugin
+	// check are restarted when its executable changes
+	checksPaths := []string{}
+	for _, key := range []string{"additional_checksd", "plugin_checks_path"} {
+		if path := config.Datadog.GetString(key); path != "" {
+			checksPaths = append(checksPaths, path)
+		}
+	}
+	fileProvider.SetChecksPaths(checksPaths)
+	AC.AddProvider(fileProvider, config.Datadog.GetBool("confd_watch"))
+
+	// the configs persisted through the API are only loaded at startup, at
+	// runtime the API schedules their checks itself
+	if managedPath := config.Datadog.GetString("managed_confd_path"); managedPath != "" {
+		AC.AddProvider(providers.NewFileConfigProvider([]string{managedPath}), false)
+	}
+
+	// Register additional configuration providers
+	var CP []config.ConfigurationProviders
+	err := config.Datadog.UnmarshalKey("config_providers", &CP)
+	if err == nil {
+		for _, cp := range CP {
+			factory, found := providers.ProviderCatalog[cp.Name]
+			if found {
+				configProvider, err := factory(cp)
+				if err == nil {
+					AC.AddProvider(configProvider, cp.Polling)
+					log.Infof("Registering %s config provider", cp.Name)
+				} else {
+					log.Errorf("Error while adding config provider %v: %v", cp.Name, err)
+				}
+			} else {
+				log.Errorf("Unable to find this provider in the catalog: %v", cp.Name)
+			}
+		}
+	} else {
+		log.Errorf("Error while reading 'config_providers' settings: %v", err)
+	}
+
+	// Autodiscovery listeners
+	// for now, no need to implement a registry of available listeners since we
+	// have only docker, the kubelet and the processes
+	var Listeners []config.Listeners
+	if err = config.Datadog.UnmarshalKey("listeners", &Listeners); err == nil {
+		for _, l := range Listeners {
+			switch l.Name {
+			case "process":
+				process, err := listeners.NewProcessListener()
+				if err != nil {
+					log.Errorf("Failed to create a process listener: %s", err)
+				} else {
+					AC.AddListener(process)
+				}
+			default:
+				addContainerListener(l.Name)
+			}
+		}
+	}
+}
+
+// StartAutoConfig starts the autoconfig:
+//   1. start polling the providers
+//   2. load all the configurations available at startup
+//   3. run all the Checks for each configuration found
+func StartAutoConfig() {
+	AC.StartPolling()
+	AC.LoadAndRun()
+}
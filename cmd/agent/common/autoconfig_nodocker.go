@@ -11,11 +11,16 @@ import (
 	log "github.com/cihub/seelog"
 )
 
-// SetupAutoConfig placeholder if docker is disabled
-func SetupAutoConfig(confdPath string) {
-	log.Debugf("AutoDiscovery is only supported with docker, disabling")
+// setupContainers placeholder if docker is disabled
+func setupContainers() {
+	log.Debugf("Container support is disabled, only the non-container listeners are available")
 }
 
-// StartAutoConfig placeholder if docker is disabled
-func StartAutoConfig() {
+// addContainerListener placeholder if docker is disabled, the container
+// listeners are ignored
+func addContainerListener(name string) {
+	switch name {
+	case "docker", "kubelet":
+		log.Warnf("The %s listener is only supported with docker, ignoring it", name)
+	}
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/metadata"
+	"github.com/DataDog/datadog-agent/pkg/openmetrics"
 	"github.com/kardianos/osext"
 )
 
@@ -31,6 +32,10 @@ var (
 	// Forwarder is the global forwarder instance
 	Forwarder forwarder.Forwarder
 
+	// MetricsEndpoint is the server exposing the aggregated metrics in the
+	// Prometheus/OpenMetrics formats, nil when disabled
+	MetricsEndpoint *openmetrics.Server
+
 	// utility variables
 	_here, _ = osext.ExecutableFolder()
 )
@@ -116,7 +116,7 @@ func start(cmd *cobra.Command, args []string) error {
 	}
 	f := forwarder.NewDefaultForwarder(keysPerDomain)
 	f.Start()
-	s := &serializer.Serializer{Forwarder: f}
+	s := serializer.NewSerializer(f)
 
 	hname, err := util.GetHostname()
 	if err != nil {
@@ -128,6 +128,7 @@ type BufferedAggregator struct {
 	mu                 sync.Mutex // to protect the checkSamplers field
 	serializer         *serializer.Serializer
 	hostname           string
+	seriesFlushHooks   []func(metrics.Series)
 	hostnameUpdate     chan string
 	hostnameUpdateDone chan struct{}    // signals that the hostname update is finished
 	TickerChan         <-chan time.Time // For test/benchmark purposes: it allows the flush to be controlled from the outside
@@ -266,6 +267,15 @@ func (agg *BufferedAggregator) addSample(metricSample *metrics.MetricSample, tim
 	}
 }
 
+// AddSeriesFlushHook registers a function called with the series of every
+// flush, before they're serialized. Hooks are called from the aggregator
+// goroutine: they must return quickly and must not modify the series.
+func (agg *BufferedAggregator) AddSeriesFlushHook(hook func(metrics.Series)) {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	agg.seriesFlushHooks = append(agg.seriesFlushHooks, hook)
+}
+
 // GetSeries grabs all the series from the queue and clears the queue
 func (agg *BufferedAggregator) GetSeries() metrics.Series {
 	series := agg.sampler.flush(timeNowNano())
@@ -282,6 +292,13 @@ func (agg *BufferedAggregator) flushSeries() {
 	series := agg.GetSeries()
 	addFlushCount("Series", int64(len(series)))
 
+	agg.mu.Lock()
+	hooks := agg.seriesFlushHooks
+	agg.mu.Unlock()
+	for _, hook := range hooks {
+		hook(series)
+	}
+
 	if len(series) == 0 {
 		return
 	}
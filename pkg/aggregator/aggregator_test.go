@@ -135,3 +135,16 @@ func TestSetHostname(t *testing.T) {
 	agg.SetHostname("different-hostname")
 	assert.Equal(t, "different-hostname", agg.hostname)
 }
+
+func TestSeriesFlushHook(t *testing.T) {
+	agg := NewBufferedAggregator(nil, "hostname", DefaultFlushInterval)
+
+	calls := 0
+	agg.AddSeriesFlushHook(func(series metrics.Series) {
+		calls++
+		assert.Len(t, series, 0)
+	})
+	agg.flushSeries()
+	agg.flushSeries()
+	assert.Equal(t, 2, calls)
+}
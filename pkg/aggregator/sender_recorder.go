@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package aggregator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// RecordedMetric is a metric sample submitted to a RecordingSender
+type RecordedMetric struct {
+	Name  string   `json:"name"`
+	Type  string   `json:"type"`
+	Value float64  `json:"value"`
+	Host  string   `json:"host"`
+	Tags  []string `json:"tags"`
+}
+
+// RecordedServiceCheck is a service check submitted to a RecordingSender
+type RecordedServiceCheck struct {
+	Name    string                     `json:"name"`
+	Status  metrics.ServiceCheckStatus `json:"status"`
+	Host    string                     `json:"host"`
+	Tags    []string                   `json:"tags"`
+	Message string                     `json:"message"`
+}
+
+// Recording holds what was submitted to a RecordingSender, sorted so two
+// identical runs produce the same recording
+type Recording struct {
+	Metrics       []RecordedMetric       `json:"metrics"`
+	ServiceChecks []RecordedServiceCheck `json:"service_checks"`
+	Events        []metrics.Event        `json:"events"`
+}
+
+// RecordingSender implements Sender by keeping what's submitted in memory
+// instead of sending it to the aggregator, everything is recorded as soon as
+// it's submitted. It's used to dry run checks.
+type RecordingSender struct {
+	recording Recording
+	stats     map[string]int64
+	m         sync.Mutex
+}
+
+// NewRecordingSender returns an empty RecordingSender
+func NewRecordingSender() *RecordingSender {
+	r := &RecordingSender{}
+	r.reset()
+	return r
+}
+
+func (r *RecordingSender) reset() {
+	r.recording = Recording{
+		Metrics:       []RecordedMetric{},
+		ServiceChecks: []RecordedServiceCheck{},
+		Events:        []metrics.Event{},
+	}
+	r.stats = map[string]int64{"Metrics": 0, "Events": 0, "ServiceChecks": 0}
+}
+
+// Flush returns what was submitted since the last flush and forgets it
+func (r *RecordingSender) Flush() Recording {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	rec := r.recording
+	sort.SliceStable(rec.Metrics, func(i, j int) bool {
+		a, b := rec.Metrics[i], rec.Metrics[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		return strings.Join(a.Tags, ",") < strings.Join(b.Tags, ",")
+	})
+	sort.SliceStable(rec.ServiceChecks, func(i, j int) bool {
+		a, b := rec.ServiceChecks[i], rec.ServiceChecks[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		return strings.Join(a.Tags, ",") < strings.Join(b.Tags, ",")
+	})
+	sort.SliceStable(rec.Events, func(i, j int) bool {
+		return rec.Events[i].Title < rec.Events[j].Title
+	})
+
+	r.reset()
+	return rec
+}
+
+// sortedTags returns a sorted copy of tags, never nil
+func sortedTags(tags []string) []string {
+	sorted := append([]string{}, tags...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func (r *RecordingSender) metric(metricType metrics.MetricType, metric string, value float64, hostname string, tags []string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.recording.Metrics = append(r.recording.Metrics, RecordedMetric{
+		Name:  metric,
+		Type:  metricType.String(),
+		Value: value,
+		Host:  hostname,
+		Tags:  sortedTags(tags),
+	})
+	r.stats["Metrics"]++
+}
+
+// Commit does nothing, samples are recorded as soon as they're submitted
+func (r *RecordingSender) Commit() {}
+
+// Gauge records a gauge sample
+func (r *RecordingSender) Gauge(metric string, value float64, hostname string, tags []string) {
+	r.metric(metrics.GaugeType, metric, value, hostname, tags)
+}
+
+// Rate records a rate sample
+func (r *RecordingSender) Rate(metric string, value float64, hostname string, tags []string) {
+	r.metric(metrics.RateType, metric, value, hostname, tags)
+}
+
+// Count records a count sample
+func (r *RecordingSender) Count(metric string, value float64, hostname string, tags []string) {
+	r.metric(metrics.CountType, metric, value, hostname, tags)
+}
+
+// MonotonicCount records a monotonic count sample
+func (r *RecordingSender) MonotonicCount(metric string, value float64, hostname string, tags []string) {
+	r.metric(metrics.MonotonicCountType, metric, value, hostname, tags)
+}
+
+// Counter records a counter sample
+func (r *RecordingSender) Counter(metric string, value float64, hostname string, tags []string) {
+	r.metric(metrics.CounterType, metric, value, hostname, tags)
+}
+
+// Histogram records a histogram sample
+func (r *RecordingSender) Histogram(metric string, value float64, hostname string, tags []string) {
+	r.metric(metrics.HistogramType, metric, value, hostname, tags)
+}
+
+// Historate records a historate sample
+func (r *RecordingSender) Historate(metric string, value float64, hostname string, tags []string) {
+	r.metric(metrics.HistorateType, metric, value, hostname, tags)
+}
+
+// ServiceCheck records a service check
+func (r *RecordingSender) ServiceCheck(checkName string, status metrics.ServiceCheckStatus, hostname string, tags []string, message string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.recording.ServiceChecks = append(r.recording.ServiceChecks, RecordedServiceCheck{
+		Name:    checkName,
+		Status:  status,
+		Host:    hostname,
+		Tags:    sortedTags(tags),
+		Message: message,
+	})
+	r.stats["ServiceChecks"]++
+}
+
+// Event records an event
+func (r *RecordingSender) Event(e metrics.Event) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	e.Tags = sortedTags(e.Tags)
+	r.recording.Events = append(r.recording.Events, e)
+	r.stats["Events"]++
+}
+
+// GetMetricStats returns the number of samples recorded since the last flush
+func (r *RecordingSender) GetMetricStats() map[string]int64 {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	stats := make(map[string]int64, len(r.stats))
+	for k, v := range r.stats {
+		stats[k] = v
+	}
+	return stats
+}
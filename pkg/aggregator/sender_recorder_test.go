@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package aggregator
+
+import (
+	// stdlib
+	"testing"
+
+	// 3p
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestRecordingSender(t *testing.T) {
+	r := NewRecordingSender()
+
+	r.Rate("b.metric", 2, "", []string{"z:1", "a:1"})
+	r.Gauge("b.metric", 1, "myhost", nil)
+	r.Gauge("a.metric", 3, "", []string{"foo"})
+	r.ServiceCheck("b.can_connect", metrics.ServiceCheckOK, "", nil, "")
+	r.ServiceCheck("a.can_connect", metrics.ServiceCheckCritical, "", []string{"foo"}, "down")
+	r.Event(metrics.Event{Title: "b", Tags: []string{"z", "a"}})
+	r.Event(metrics.Event{Title: "a"})
+	r.Commit()
+
+	assert.Equal(t, map[string]int64{"Metrics": 3, "Events": 2, "ServiceChecks": 2}, r.GetMetricStats())
+
+	rec := r.Flush()
+	assert.Equal(t, []RecordedMetric{
+		{Name: "a.metric", Type: "Gauge", Value: 3, Tags: []string{"foo"}},
+		{Name: "b.metric", Type: "Gauge", Value: 1, Host: "myhost", Tags: []string{}},
+		{Name: "b.metric", Type: "Rate", Value: 2, Tags: []string{"a:1", "z:1"}},
+	}, rec.Metrics)
+	assert.Equal(t, []RecordedServiceCheck{
+		{Name: "a.can_connect", Status: metrics.ServiceCheckCritical, Tags: []string{"foo"}, Message: "down"},
+		{Name: "b.can_connect", Status: metrics.ServiceCheckOK, Tags: []string{}},
+	}, rec.ServiceChecks)
+	assert.Equal(t, []metrics.Event{
+		{Title: "a", Tags: []string{}},
+		{Title: "b", Tags: []string{"a", "z"}},
+	}, rec.Events)
+
+	// flushing forgets what was recorded
+	assert.Equal(t, map[string]int64{"Metrics": 0, "Events": 0, "ServiceChecks": 0}, r.GetMetricStats())
+	rec = r.Flush()
+	assert.Empty(t, rec.Metrics)
+	assert.Empty(t, rec.ServiceChecks)
+	assert.Empty(t, rec.Events)
+}
@@ -6,24 +6,29 @@
 package autodiscovery
 
 import (
+	"bytes"
 	"expvar"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/collector"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/collector/listeners"
 	"github.com/DataDog/datadog-agent/pkg/collector/providers"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	log "github.com/cihub/seelog"
 )
 
 var (
 	configsPollIntl = 10 * time.Second
+	watchRetryIntl  = 30 * time.Second
 	configPipeBuf   = 100
 	acErrors        *expvar.Map
 	errorStats      = newAcErrorStats()
+	inventory       = newAcInventory()
 )
 
 func init() {
@@ -34,14 +39,48 @@ func init() {
 	acErrors.Set("RunErrors", expvar.Func(func() interface{} {
 		return errorStats.getRunErrors()
 	}))
+	acErrors.Set("ResolveErrors", expvar.Func(func() interface{} {
+		return errorStats.getResolveErrors()
+	}))
+	acErrors.Set("ConfigErrors", expvar.Func(func() interface{} {
+		return errorStats.getConfigErrors()
+	}))
 }
 
 // providerDescriptor keeps track of the configurations loaded by a certain
 // `providers.ConfigProvider` and whether it should be polled or not.
+// Watchable providers that should be polled are watched instead, they're only
+// polled while the watch fails.
 type providerDescriptor struct {
 	provider providers.ConfigProvider
 	configs  []check.Config
 	poll     bool
+	watching int32 // set atomically
+}
+
+func (pd *providerDescriptor) isWatching() bool {
+	return atomic.LoadInt32(&pd.watching) == 1
+}
+
+func (pd *providerDescriptor) setWatching(watching bool) {
+	var v int32
+	if watching {
+		v = 1
+	}
+	atomic.StoreInt32(&pd.watching, v)
+}
+
+// configErrorsReporter is implemented by the providers reporting the
+// configurations they found but couldn't parse, by location
+type configErrorsReporter interface {
+	GetConfigErrors() map[string]string
+}
+
+// checksReloader is implemented by the providers knowing when the code of a
+// check changed, ChecksToReload returns the names of the checks whose
+// instances must be restarted since the last call
+type checksReloader interface {
+	ChecksToReload() []string
 }
 
 // AutoConfig is responsible to collect checks configurations from
@@ -63,8 +102,10 @@ type AutoConfig struct {
 	listeners         []listeners.ServiceListener
 	configResolver    *ConfigResolver
 	configsPollTicker *time.Ticker
-	config2checks     map[string][]check.ID // cache the ID of checks we load for each config
+	config2checks     map[string][]check.ID     // cache the ID of checks we load for each config
+	liveConfigs       map[check.ID]check.Config // single instance configs of the checks scheduled with ScheduleConfig
 	stop              chan bool
+	watchStop         chan struct{}
 	m                 sync.RWMutex
 }
 
@@ -76,15 +117,27 @@ func NewAutoConfig(collector *collector.Collector) *AutoConfig {
 		loaders:       make([]check.Loader, 0, 5),
 		templateCache: NewTemplateCache(),
 		config2checks: make(map[string][]check.ID),
+		liveConfigs:   make(map[check.ID]check.Config),
 		stop:          make(chan bool),
+		watchStop:     make(chan struct{}),
 	}
 	ac.configResolver = newConfigResolver(collector, ac, ac.templateCache)
 
 	return ac
 }
 
-// StartPolling starts the goroutine responsible for polling the providers
+// StartPolling starts the goroutine responsible for polling the providers,
+// and one goroutine per provider that can be watched
 func (ac *AutoConfig) StartPolling() {
+	ac.m.RLock()
+	for _, pd := range ac.providers {
+		if wp, ok := pd.provider.(providers.WatchableConfigProvider); ok && pd.poll {
+			pd.setWatching(true)
+			go ac.watchProvider(pd, wp)
+		}
+	}
+	ac.m.RUnlock()
+
 	ac.configsPollTicker = time.NewTicker(configsPollIntl)
 	ac.pollConfigs()
 }
@@ -93,8 +146,9 @@ func (ac *AutoConfig) StartPolling() {
 // AutoConfig is not supposed to be restarted, so this is expected
 // to be called only once at program exit.
 func (ac *AutoConfig) Stop() {
-	// stop the poller
+	// stop the poller and the watchers
 	ac.stop <- true
+	close(ac.watchStop)
 
 	// stop the collector
 	if ac.collector != nil {
@@ -138,19 +192,21 @@ func (ac *AutoConfig) AddProvider(provider providers.ConfigProvider, shouldPoll
 		poll:     shouldPoll,
 	}
 	ac.providers = append(ac.providers, pd)
+	inventory.addProvider(fmt.Sprintf("%v", provider))
 }
 
 // LoadAndRun loads all of the configs it can find and schedules the corresponding
 // Check instances. Should always be run once so providers that don't need
 // polling will be queried at least once
 func (ac *AutoConfig) LoadAndRun() {
-	for _, check := range ac.getAllChecks() {
-		_, err := ac.collector.RunCheck(check)
-		if err != nil {
-			log.Errorf("Unable to run Check %s: %v", check, err)
-		}
-	}
+	ac.m.Lock()
+	defer ac.m.Unlock()
 
+	// keep track of the checks of every config so that they can be
+	// unscheduled when their config changes
+	for _, pd := range ac.providers {
+		ac.refreshProvider(pd)
+	}
 }
 
 // GetChecksByName returns any Check instance we can load for the given
@@ -163,12 +219,28 @@ func (ac *AutoConfig) GetChecksByName(checkName string) []check.Check {
 	for _, check := range ac.getAllChecks() {
 		if checkName == check.String() || titleCheck == check.String() {
 			checks = append(checks, check)
+		} else {
+			// loading a check might have started a plugin process
+			ac.discardCheck(check)
 		}
 	}
 
 	return checks
 }
 
+// GetConfigsByName returns the configurations found by the providers for the
+// given check name
+func (ac *AutoConfig) GetConfigsByName(checkName string) []check.Config {
+	configs := []check.Config{}
+	for _, config := range ac.getAllConfigs() {
+		if config.Name == checkName {
+			configs = append(configs, config)
+		}
+	}
+
+	return configs
+}
+
 // getAllConfigs queries all the providers and returns all the check
 // configurations found.
 func (ac *AutoConfig) getAllConfigs() []check.Config {
@@ -210,6 +282,7 @@ func (ac *AutoConfig) AddListener(listener listeners.ServiceListener) {
 	}
 
 	ac.listeners = append(ac.listeners, listener)
+	inventory.addListener(fmt.Sprintf("%v", listener))
 	listener.Listen(ac.configResolver.newService, ac.configResolver.delService)
 }
 
@@ -237,119 +310,314 @@ func (ac *AutoConfig) pollConfigs() {
 				}
 				return
 			case <-ac.configsPollTicker.C:
-				ac.m.RLock()
+				ac.m.Lock()
 				// invoke Collect on the known providers
 				for _, pd := range ac.providers {
-					// skip providers that don't want to be polled
-					if !pd.poll {
+					// skip providers that don't want to be polled, or that
+					// are watched
+					if !pd.poll || pd.isWatching() {
 						continue
 					}
-
-					// retrieve the list of newly added configurations as well
-					// as removed configurations
-					newConfigs, removedConfigs := ac.collect(pd)
-					for _, config := range newConfigs {
-						// store the checks we schedule for this config locally
-						configDigest := config.Digest()
-						ac.config2checks[configDigest] = []check.ID{}
-
-						if config.IsTemplate() {
-							// store the template in the cache in any case
-							if err := ac.templateCache.Set(config); err != nil {
-								log.Errorf("Unable to store Check configuration in the cache: %s", err)
-							}
-
-							// try to resolve the template
-							resolvedConfigs := ac.configResolver.ResolveTemplate(config)
-							if len(resolvedConfigs) == 0 {
-								log.Infof("Can't resolve the template for %s at this moment.", config.Name)
-								continue
-							}
-
-							// If success, get the checks for each config resolved
-							// and schedule for running, each template can resolve
-							// to multiple configs
-							for _, config := range resolvedConfigs {
-								// each config could resolve to multiple checks
-								checks, err := ac.GetChecks(config)
-								if err != nil {
-									log.Errorf("Unable to load check from template: %s", err)
-									continue
-								}
-								// ask the Collector to schedule the checks
-								for _, check := range checks {
-									_, err := ac.collector.RunCheck(check)
-									if err != nil {
-										log.Errorf("Unable to schedule check for running: %s", err)
-										errorStats.setRunError(check.ID(), err.Error())
-										continue
-									}
-									ac.config2checks[configDigest] = append(ac.config2checks[configDigest], check.ID())
-								}
-							}
-						} else {
-							// the config is not a template, just schedule the checks for running
-							checks, err := ac.GetChecks(config)
-							if err != nil {
-								log.Errorf("Unable to load check from template: %s", err)
-							}
-							// ask the Collector to schedule the checks
-							for _, check := range checks {
-								_, err := ac.collector.RunCheck(check)
-								if err != nil {
-									log.Errorf("Unable to schedule check for running: %s", err)
-									errorStats.setRunError(check.ID(), err.Error())
-									continue
-								}
-								ac.config2checks[configDigest] = append(ac.config2checks[configDigest], check.ID())
-							}
-						}
-					}
-
-					for _, config := range removedConfigs {
-						// unschedule all the checks corresponding to this config
-						digest := config.Digest()
-						ids := ac.config2checks[digest]
-						stopped := map[check.ID]struct{}{}
-						for _, id := range ids {
-							// `StopCheck` might time out so we don't risk to block
-							// the polling loop forever
-							err := ac.collector.StopCheck(id)
-							if err != nil {
-								log.Errorf("Error stopping check %s: %s", id, err)
-								errorStats.setRunError(id, err.Error())
-							} else {
-								stopped[id] = struct{}{}
-							}
-						}
-
-						// remove the entry from `config2checks`
-						if len(stopped) == len(ac.config2checks[digest]) {
-							// we managed to stop all the checks for this config
-							delete(ac.config2checks, digest)
-						} else {
-							// keep the checks we failed to stop in `config2checks`
-							dangling := []check.ID{}
-							for _, id := range ac.config2checks[digest] {
-								if _, found := stopped[id]; !found {
-									dangling = append(dangling, id)
-								}
-							}
-							ac.config2checks[digest] = dangling
-						}
-
-						// if the config is a template, remove it from the cache
-						if config.IsTemplate() {
-							ac.templateCache.Del(config)
-						}
-					}
+					ac.refreshProvider(pd)
 				}
-				ac.m.RUnlock()
+				ac.m.Unlock()
 			}
 		}
 	}()
 }
 
+// watchProvider collects the configurations of a provider as soon as it
+// reports a change. When the provider can't be watched, it's polled until the
+// watch works again.
+func (ac *AutoConfig) watchProvider(pd *providerDescriptor, wp providers.WatchableConfigProvider) {
+	for {
+		err := wp.Watch(ac.watchStop)
+		select {
+		case <-ac.watchStop:
+			return
+		default:
+		}
+
+		if err != nil {
+			if pd.isWatching() {
+				log.Warnf("Unable to watch %s, polling it until the watch works again: %s", wp, err)
+			}
+			pd.setWatching(false)
+			select {
+			case <-ac.watchStop:
+				return
+			case <-time.After(watchRetryIntl):
+			}
+			continue
+		}
+
+		if !pd.isWatching() {
+			log.Infof("Watching %s again", wp)
+		}
+		pd.setWatching(true)
+		ac.m.Lock()
+		ac.refreshProvider(pd)
+		ac.m.Unlock()
+	}
+}
+
+// refreshProvider collects the configurations of a provider, schedules the
+// checks of the new ones and unschedules the checks of the ones that are
+// gone, ac.m must be held
+func (ac *AutoConfig) refreshProvider(pd *providerDescriptor) {
+	// retrieve the list of newly added configurations as well
+	// as removed configurations
+	newConfigs, removedConfigs := ac.collect(pd)
+	// only restart the instances that changed in the configs that were
+	// edited, the other configs are scheduled and unscheduled as a whole
+	newConfigs, removedConfigs = ac.updateConfigs(newConfigs, removedConfigs)
+	for _, config := range newConfigs {
+		// store the checks we schedule for this config locally
+		configDigest := config.Digest()
+		ac.config2checks[configDigest] = []check.ID{}
+
+		if config.IsTemplate() {
+			// store the template in the cache in any case
+			if err := ac.templateCache.Set(config); err != nil {
+				log.Errorf("Unable to store Check configuration in the cache: %s", err)
+			}
+
+			// try to resolve the template
+			resolvedConfigs := ac.configResolver.ResolveTemplate(config)
+			if len(resolvedConfigs) == 0 {
+				log.Infof("Can't resolve the template for %s at this moment, `agent configcheck` shows the services matching its AD identifiers.", config.Name)
+				continue
+			}
+
+			// If success, get the checks for each config resolved
+			// and schedule for running, each template can resolve
+			// to multiple configs
+			for _, config := range resolvedConfigs {
+				ac.runConfig(config, configDigest)
+			}
+		} else {
+			// the config is not a template, just schedule the checks for running
+			ac.runConfig(config, configDigest)
+		}
+	}
+
+	for _, config := range removedConfigs {
+		// unschedule all the checks corresponding to this config
+		digest := config.Digest()
+		if ac.stopConfigChecks(digest) {
+			// we managed to stop all the checks for this config
+			delete(ac.config2checks, digest)
+		}
+
+		// if the config is a template, remove it from the cache
+		if config.IsTemplate() {
+			ac.templateCache.Del(config)
+			errorStats.removeTemplateResolveErrors(digest)
+		}
+	}
+
+	if cp, ok := pd.provider.(checksReloader); ok {
+		ac.reloadChecks(pd, cp.ChecksToReload(), newConfigs)
+	}
+}
+
+// updateConfigs pairs the removed configs with the new ones of the same check
+// and init_config, and updates the instances of each pair. It returns the
+// configs it couldn't pair or update. ac.m must be held.
+func (ac *AutoConfig) updateConfigs(newConfigs, removedConfigs []check.Config) (new, removed []check.Config) {
+	new = []check.Config{}
+	removed = []check.Config{}
+	paired := make(map[int]struct{}, len(newConfigs))
+
+	for _, old := range removedConfigs {
+		updated := false
+		for i, config := range newConfigs {
+			if _, found := paired[i]; found || config.IsTemplate() || old.IsTemplate() {
+				continue
+			}
+			if config.Name != old.Name || !bytes.Equal(config.InitConfig, old.InitConfig) {
+				continue
+			}
+			if updated = ac.updateInstances(old, config); updated {
+				paired[i] = struct{}{}
+				break
+			}
+		}
+		if !updated {
+			removed = append(removed, old)
+		}
+	}
+
+	for i, config := range newConfigs {
+		if _, found := paired[i]; !found {
+			new = append(new, config)
+		}
+	}
+	return new, removed
+}
+
+// updateInstances replaces the `old` config with the `new` one of the same
+// check by comparing the IDs of their instances: the checks of the instances
+// that are gone are stopped, the ones of the instances that were added are
+// scheduled and the others keep running. It returns false, without touching
+// anything, when the IDs of the running checks aren't the ones of the
+// instances, e.g. when a loader grouped them. ac.m must be held.
+func (ac *AutoConfig) updateInstances(old, new check.Config) bool {
+	oldDigest, newDigest := old.Digest(), new.Digest()
+	running := ac.config2checks[oldDigest]
+	if len(running) == 0 {
+		return false
+	}
+	oldIDs := make(map[check.ID]struct{}, len(old.Instances))
+	for _, instance := range old.Instances {
+		oldIDs[check.BuildID(old.Name, instance, old.InitConfig)] = struct{}{}
+	}
+	for _, id := range running {
+		if _, found := oldIDs[id]; !found {
+			return false
+		}
+	}
+
+	newIDs := make(map[check.ID]struct{}, len(new.Instances))
+	for _, instance := range new.Instances {
+		newIDs[check.BuildID(new.Name, instance, new.InitConfig)] = struct{}{}
+	}
+
+	// stop the checks of the instances that are gone
+	kept := []check.ID{}
+	keptIDs := make(map[check.ID]struct{}, len(running))
+	dangling := []check.ID{}
+	for _, id := range running {
+		if _, found := newIDs[id]; found {
+			kept = append(kept, id)
+			keptIDs[id] = struct{}{}
+			continue
+		}
+		if err := ac.collector.StopCheck(id); err != nil {
+			log.Errorf("Error stopping check %s: %s", id, err)
+			errorStats.setRunError(id, err.Error())
+			dangling = append(dangling, id)
+		} else {
+			inventory.removeCheck(id)
+		}
+	}
+	if len(dangling) == 0 {
+		delete(ac.config2checks, oldDigest)
+	} else {
+		ac.config2checks[oldDigest] = dangling
+	}
+	ac.config2checks[newDigest] = kept
+
+	// schedule the ones of the instances that were added
+	added := new
+	added.Instances = []check.ConfigData{}
+	for _, instance := range new.Instances {
+		id := check.BuildID(new.Name, instance, new.InitConfig)
+		if _, found := keptIDs[id]; !found {
+			keptIDs[id] = struct{}{}
+			added.Instances = append(added.Instances, instance)
+		}
+	}
+	if len(added.Instances) > 0 {
+		ac.runConfig(added, newDigest)
+	}
+	inventory.moveChecks(new, ac.config2checks[newDigest])
+
+	log.Infof("The configuration of %s changed: %d instance(s) stopped, %d scheduled, %d unchanged",
+		new.Name, len(running)-len(kept), len(ac.config2checks[newDigest])-len(kept), len(kept))
+	return true
+}
+
+// runConfig loads the checks of a config and schedules them, their IDs are
+// stored under `digest` in `config2checks`. ac.m must be held.
+func (ac *AutoConfig) runConfig(config check.Config, digest string) {
+	checks, err := ac.GetChecks(config)
+	if err != nil {
+		log.Errorf("Unable to load check from config: %s", err)
+	}
+	// ask the Collector to schedule the checks
+	for _, check := range checks {
+		_, err := ac.collector.RunCheck(check)
+		if err != nil {
+			log.Errorf("Unable to schedule check for running: %s", err)
+			errorStats.setRunError(check.ID(), err.Error())
+			ac.discardCheck(check)
+			continue
+		}
+		ac.config2checks[digest] = append(ac.config2checks[digest], check.ID())
+	}
+}
+
+// discardCheck stops a loaded check that won't be scheduled, loading it
+// might have started a plugin process. Some loaders return the same check
+// for several configs, the instance the collector runs is left alone.
+func (ac *AutoConfig) discardCheck(c check.Check) {
+	if ac.collector != nil {
+		if running, found := ac.collector.GetCheck(c.ID()); found {
+			if running != c {
+				c.Stop()
+			}
+			return
+		}
+	}
+	c.Stop()
+	inventory.removeCheck(c.ID())
+}
+
+// stopConfigChecks unschedules the checks stored under `digest` in
+// `config2checks`, only the ones it failed to stop are kept there. It returns
+// whether all of them were stopped. ac.m must be held.
+func (ac *AutoConfig) stopConfigChecks(digest string) bool {
+	dangling := []check.ID{}
+	for _, id := range ac.config2checks[digest] {
+		// `StopCheck` might time out so we don't risk to block
+		// the polling loop forever
+		err := ac.collector.StopCheck(id)
+		if err != nil {
+			log.Errorf("Error stopping check %s: %s", id, err)
+			errorStats.setRunError(id, err.Error())
+			dangling = append(dangling, id)
+		} else {
+			inventory.removeCheck(id)
+		}
+	}
+	ac.config2checks[digest] = dangling
+	return len(dangling) == 0
+}
+
+// reloadChecks restarts the checks of the configs of a provider named after
+// one of `names`, the new configs were just loaded so they're skipped. The
+// configs whose checks couldn't be loaded are loaded again. ac.m must be held.
+func (ac *AutoConfig) reloadChecks(pd *providerDescriptor, names []string, newConfigs []check.Config) {
+	if len(names) == 0 {
+		return
+	}
+	reload := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		reload[name] = struct{}{}
+	}
+	isNew := make(map[string]struct{}, len(newConfigs))
+	for _, config := range newConfigs {
+		isNew[config.Digest()] = struct{}{}
+	}
+
+	for _, config := range pd.configs {
+		digest := config.Digest()
+		if _, found := reload[config.Name]; !found || config.IsTemplate() {
+			continue
+		}
+		if _, found := isNew[digest]; found {
+			continue
+		}
+		log.Infof("The code of check %s changed, restarting its instances", config.Name)
+		if !ac.stopConfigChecks(digest) {
+			// don't run the same instance twice
+			continue
+		}
+		ac.runConfig(config, digest)
+	}
+}
+
 // collect is just a convenient wrapper to fetch configurations from a provider and
 // see what changed from the last time we called Collect().
 func (ac *AutoConfig) collect(pd *providerDescriptor) (new, removed []check.Config) {
@@ -357,11 +625,20 @@ func (ac *AutoConfig) collect(pd *providerDescriptor) (new, removed []check.Conf
 	removed = []check.Config{}
 
 	fetched, err := pd.provider.Collect()
+	if cp, ok := pd.provider.(configErrorsReporter); ok {
+		// several providers of the same kind can be added, e.g. file ones
+		errorStats.setConfigErrors(fmt.Sprintf("%v (%p)", pd.provider, pd.provider), cp.GetConfigErrors())
+	}
 	if err != nil {
 		log.Errorf("Unable to collect configurations from provider %s: %s", pd.provider, err)
 		return
 	}
 
+	// keep track of where the configs come from
+	for i := range fetched {
+		fetched[i].Provider = fmt.Sprintf("%v", pd.provider)
+	}
+
 	for _, c := range fetched {
 		if !pd.contains(&c) {
 			new = append(new, c)
@@ -385,21 +662,53 @@ func (ac *AutoConfig) collect(pd *providerDescriptor) (new, removed []check.Conf
 // GetChecks takes a check configuration and returns a slice of Check instances
 // along with any error it might happen during the process
 func (ac *AutoConfig) GetChecks(config check.Config) ([]check.Check, error) {
+	// the loaders get the secrets, the config keeps its `ENC[<handle>]`
+	// values for the digests, the inventory and the logs
+	decrypted, err := decryptConfig(config)
+	if err != nil {
+		errorStats.setLoaderError(config.Name, "Secrets", err.Error())
+		return []check.Check{}, fmt.Errorf("unable to decrypt the secrets of config '%s': %s", config.Name, err)
+	}
+
 	for _, loader := range ac.loaders {
-		res, err := loader.Load(config)
+		res, err := loader.Load(decrypted)
 		if err == nil {
 			log.Infof("%v: successfully loaded check '%s'", loader, config.Name)
 			errorStats.removeLoaderErrors(config.Name)
+			inventory.setChecks(config, loader, res)
 			return res, nil
 		}
 
-		errorStats.setLoaderError(config.Name, fmt.Sprintf("%v", loader), err.Error())
-		log.Debugf("%v: unable to load the check '%s': %s", loader, config.Name, err)
+		// the loaders may quote the decrypted config
+		errMsg := secrets.Scrub(err.Error())
+		errorStats.setLoaderError(config.Name, fmt.Sprintf("%v", loader), errMsg)
+		log.Debugf("%v: unable to load the check '%s': %s", loader, config.Name, errMsg)
 	}
 
 	return []check.Check{}, fmt.Errorf("unable to load any check from config '%s'", config.Name)
 }
 
+// decryptConfig returns a copy of the config with the secrets of its init
+// config and instances decrypted
+func decryptConfig(config check.Config) (check.Config, error) {
+	initConfig, err := secrets.Decrypt(config.InitConfig)
+	if err != nil {
+		return config, err
+	}
+	instances := make([]check.ConfigData, 0, len(config.Instances))
+	for _, instance := range config.Instances {
+		decrypted, err := secrets.Decrypt(instance)
+		if err != nil {
+			return config, err
+		}
+		instances = append(instances, decrypted)
+	}
+
+	config.InitConfig = initConfig
+	config.Instances = instances
+	return config, nil
+}
+
 // check if the descriptor contains the Config passed
 func (pd *providerDescriptor) contains(c *check.Config) bool {
 	for _, config := range pd.configs {
@@ -6,10 +6,14 @@
 package autodiscovery
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/collector"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/collector/listeners"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -27,10 +31,48 @@ type MockProvider2 struct {
 	MockProvider
 }
 
+type MockWatchableProvider struct {
+	MockProvider
+	configs chan []check.Config
+	watch   chan error
+	current []check.Config
+}
+
+func (p *MockWatchableProvider) Collect() ([]check.Config, error) {
+	p.collectCounter++
+	select {
+	case p.current = <-p.configs:
+	default:
+	}
+	return p.current, nil
+}
+
+func (p *MockWatchableProvider) Watch(stop <-chan struct{}) error {
+	select {
+	case err := <-p.watch:
+		return err
+	case <-stop:
+		return nil
+	}
+}
+
+func (p *MockWatchableProvider) String() string {
+	return "Mock Watchable Provider"
+}
+
 type MockLoader struct{}
 
 func (l *MockLoader) Load(config check.Config) ([]check.Check, error) { return []check.Check{}, nil }
 
+type RecordingLoader struct {
+	loaded []check.Config
+}
+
+func (l *RecordingLoader) Load(config check.Config) ([]check.Check, error) {
+	l.loaded = append(l.loaded, config)
+	return []check.Check{}, nil
+}
+
 type MockListener struct {
 	ListenCount  int
 	stopReceived bool
@@ -92,3 +134,263 @@ func TestStop(t *testing.T) {
 	assert.True(t, ml.stopReceived)
 	assert.True(t, ml.stopReceived)
 }
+
+func TestWatchProvider(t *testing.T) {
+	ac := NewAutoConfig(nil)
+	mp := &MockWatchableProvider{
+		configs: make(chan []check.Config, 1),
+		watch:   make(chan error),
+	}
+	ac.AddProvider(mp, true)
+	pd := ac.providers[0]
+	ac.StartPolling()
+	defer ac.Stop()
+	require.True(t, pd.isWatching())
+
+	getConfigs := func() []check.Config {
+		ac.m.RLock()
+		defer ac.m.RUnlock()
+		return pd.configs
+	}
+
+	// the configs are collected as soon as the provider reports a change
+	template := check.Config{Name: "redis", ADIdentifiers: []string{"redis"}, Instances: []check.ConfigData{check.ConfigData("host: %%host%%")}}
+	mp.configs <- []check.Config{template}
+	mp.watch <- nil
+	for i := 0; i < 100 && len(getConfigs()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, getConfigs(), 1)
+	assert.Equal(t, "redis", getConfigs()[0].Name)
+	assert.Equal(t, "Mock Watchable Provider", getConfigs()[0].Provider)
+
+	// the provider is polled when the watch fails
+	mp.watch <- errors.New("connection refused")
+	for i := 0; i < 100 && pd.isWatching(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, pd.isWatching())
+}
+
+func TestGetChecksSecrets(t *testing.T) {
+	ac := NewAutoConfig(nil)
+	loader := &RecordingLoader{}
+	ac.AddLoader(loader)
+	config.Datadog.Set("secret_backend_command", "")
+
+	// configs without secret are loaded as-is
+	plain := check.Config{
+		Name:       "redisdb",
+		InitConfig: check.ConfigData("{}"),
+		Instances:  []check.ConfigData{check.ConfigData("host: localhost\npassword: foo  # not a secret")},
+	}
+	_, err := ac.GetChecks(plain)
+	require.Nil(t, err)
+	require.Len(t, loader.loaded, 1)
+	assert.Equal(t, plain, loader.loaded[0])
+
+	// the secrets can't be decrypted, the loaders aren't called
+	secret := check.Config{
+		Name:      "postgres",
+		Instances: []check.ConfigData{check.ConfigData("password: ENC[db_pass]")},
+	}
+	_, err = ac.GetChecks(secret)
+	assert.NotNil(t, err)
+	assert.Len(t, loader.loaded, 1)
+	assert.Contains(t, GetLoaderErrors()["postgres"], "Secrets")
+}
+
+// FileLikeProvider reports config errors and the checks to reload, like the
+// file provider
+type FileLikeProvider struct {
+	configs []check.Config
+	errors  map[string]string
+	reload  []string
+}
+
+func (p *FileLikeProvider) Collect() ([]check.Config, error) { return p.configs, nil }
+func (p *FileLikeProvider) String() string                   { return "File Like Provider" }
+func (p *FileLikeProvider) GetConfigErrors() map[string]string {
+	return p.errors
+}
+func (p *FileLikeProvider) ChecksToReload() []string {
+	names := p.reload
+	p.reload = nil
+	return names
+}
+
+// InstanceLoader loads a check per instance
+type InstanceLoader struct{}
+
+func (l *InstanceLoader) Load(config check.Config) ([]check.Check, error) {
+	checks := []check.Check{}
+	for _, instance := range config.Instances {
+		checks = append(checks, &inventoryTestCheck{id: config.Name + ":" + string(instance)})
+	}
+	return checks, nil
+}
+
+func TestRefreshOnlyRestartsChangedChecks(t *testing.T) {
+	coll := collector.NewCollector()
+	defer coll.Stop()
+	ac := NewAutoConfig(coll)
+	ac.AddLoader(&InstanceLoader{})
+	foo := check.Config{Name: "foo", Instances: []check.ConfigData{check.ConfigData("a")}}
+	bar := check.Config{Name: "bar", Instances: []check.ConfigData{check.ConfigData("b")}}
+	provider := &FileLikeProvider{
+		configs: []check.Config{foo, bar},
+		errors:  map[string]string{"conf.d/baz.yaml": "invalid YAML"},
+	}
+	ac.AddProvider(provider, true)
+
+	ac.LoadAndRun()
+	_, found := coll.GetCheck("foo:a")
+	require.True(t, found)
+	barCheck, found := coll.GetCheck("bar:b")
+	require.True(t, found)
+	assert.Equal(t, ConfigErrors{"conf.d/baz.yaml": "invalid YAML"}, errorStats.getConfigErrors())
+
+	// only the checks of the config that changed are restarted
+	foo.Instances = []check.ConfigData{check.ConfigData("c")}
+	provider.configs = []check.Config{foo, bar}
+	provider.errors = nil
+	ac.m.Lock()
+	ac.refreshProvider(ac.providers[0])
+	ac.m.Unlock()
+	_, found = coll.GetCheck("foo:a")
+	assert.False(t, found)
+	_, found = coll.GetCheck("foo:c")
+	assert.True(t, found)
+	same, found := coll.GetCheck("bar:b")
+	require.True(t, found)
+	assert.True(t, same == barCheck)
+	assert.Empty(t, errorStats.getConfigErrors())
+
+	// the checks whose code changed are restarted
+	provider.reload = []string{"bar"}
+	ac.m.Lock()
+	ac.refreshProvider(ac.providers[0])
+	ac.m.Unlock()
+	restarted, found := coll.GetCheck("bar:b")
+	require.True(t, found)
+	assert.False(t, restarted == barCheck)
+	assert.Equal(t, []check.ID{"bar:b"}, ac.config2checks[bar.Digest()])
+
+	for _, id := range []check.ID{"foo:c", "bar:b"} {
+		inventory.removeCheck(id)
+	}
+}
+
+// recordingLoader loads a check per instance and keeps them
+type recordingLoader struct {
+	InstanceLoader
+	loaded []*inventoryTestCheck
+}
+
+func (l *recordingLoader) Load(config check.Config) ([]check.Check, error) {
+	checks, _ := l.InstanceLoader.Load(config)
+	for _, c := range checks {
+		l.loaded = append(l.loaded, c.(*inventoryTestCheck))
+	}
+	return checks, nil
+}
+
+func TestRunConfigStopsUnscheduledChecks(t *testing.T) {
+	coll := collector.NewCollector()
+	defer coll.Stop()
+	ac := NewAutoConfig(coll)
+	loader := &recordingLoader{}
+	ac.AddLoader(loader)
+	foo := check.Config{Name: "foo", Instances: []check.ConfigData{check.ConfigData("a")}}
+
+	ac.m.Lock()
+	ac.runConfig(foo, foo.Digest())
+	// already running, the new instance is stopped
+	ac.runConfig(foo, foo.Digest())
+	ac.m.Unlock()
+	require.Len(t, loader.loaded, 2)
+	running, found := coll.GetCheck("foo:a")
+	require.True(t, found)
+	assert.True(t, running == loader.loaded[0])
+	assert.False(t, loader.loaded[0].stopped)
+	assert.True(t, loader.loaded[1].stopped)
+
+	// only the checks returned are left running
+	loader.loaded = nil
+	ac.AddProvider(&FileLikeProvider{configs: []check.Config{foo}}, false)
+	assert.Empty(t, ac.GetChecksByName("bar"))
+	require.Len(t, loader.loaded, 1)
+	assert.True(t, loader.loaded[0].stopped)
+	assert.False(t, running.(*inventoryTestCheck).stopped)
+
+	inventory.removeCheck("foo:a")
+	errorStats.removeRunError("foo:a")
+}
+
+// IDLoader loads a check per instance, with the IDs of check.BuildID
+type IDLoader struct{}
+
+func (l *IDLoader) Load(config check.Config) ([]check.Check, error) {
+	checks := []check.Check{}
+	for _, instance := range config.Instances {
+		id := check.BuildID(config.Name, instance, config.InitConfig)
+		checks = append(checks, &inventoryTestCheck{id: string(id)})
+	}
+	return checks, nil
+}
+
+func TestRefreshOnlyRestartsChangedInstances(t *testing.T) {
+	coll := collector.NewCollector()
+	defer coll.Stop()
+	ac := NewAutoConfig(coll)
+	ac.AddLoader(&IDLoader{})
+	a, b, c := check.ConfigData("a"), check.ConfigData("b"), check.ConfigData("c")
+	idA, idB, idC := check.BuildID("foo", a, nil), check.BuildID("foo", b, nil), check.BuildID("foo", c, nil)
+	foo := check.Config{Name: "foo", Instances: []check.ConfigData{a, b}}
+	provider := &FileLikeProvider{configs: []check.Config{foo}}
+	ac.AddProvider(provider, true)
+
+	ac.LoadAndRun()
+	checkA, found := coll.GetCheck(idA)
+	require.True(t, found)
+	_, found = coll.GetCheck(idB)
+	require.True(t, found)
+
+	// b is replaced by c, a keeps running
+	edited := foo
+	edited.Instances = []check.ConfigData{a, c}
+	provider.configs = []check.Config{edited}
+	ac.m.Lock()
+	ac.refreshProvider(ac.providers[0])
+	ac.m.Unlock()
+	same, found := coll.GetCheck(idA)
+	require.True(t, found)
+	assert.True(t, same == checkA)
+	_, found = coll.GetCheck(idB)
+	assert.False(t, found)
+	_, found = coll.GetCheck(idC)
+	assert.True(t, found)
+	assert.Equal(t, []check.ID{idA, idC}, ac.config2checks[edited.Digest()])
+	_, found = ac.config2checks[foo.Digest()]
+	assert.False(t, found)
+	for _, info := range inventory.get().Checks {
+		if info.ID == idA || info.ID == idC {
+			assert.Equal(t, edited.Digest(), info.ConfigDigest)
+		}
+	}
+
+	// the init_config changes the IDs of all the instances
+	reinit := edited
+	reinit.InitConfig = check.ConfigData("{}")
+	provider.configs = []check.Config{reinit}
+	ac.m.Lock()
+	ac.refreshProvider(ac.providers[0])
+	ac.m.Unlock()
+	_, found = coll.GetCheck(idA)
+	assert.False(t, found)
+	assert.Len(t, ac.config2checks[reinit.Digest()], 2)
+
+	for _, id := range ac.config2checks[reinit.Digest()] {
+		inventory.removeCheck(id)
+	}
+}
@@ -0,0 +1,187 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package autodiscovery
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/listeners"
+)
+
+// ConfigInfo describes a config or a template known by AutoConfig, the raw
+// YAML is only set in verbose mode
+type ConfigInfo struct {
+	Name          string            `json:"name"`
+	Digest        string            `json:"digest"`
+	Provider      string            `json:"provider"`
+	ADIdentifiers []string          `json:"ad_identifiers,omitempty"`
+	Checks        []check.ID        `json:"checks"`
+	ResolveErrors map[string]string `json:"resolve_errors,omitempty"`
+	InitConfig    string            `json:"init_config,omitempty"`
+	Instances     []string          `json:"instances,omitempty"`
+}
+
+// ProviderConfigs lists what a config provider collected, the templates are
+// apart from the configs
+type ProviderConfigs struct {
+	Name      string       `json:"name"`
+	Polled    bool         `json:"polled"`
+	Watched   bool         `json:"watched"`
+	Configs   []ConfigInfo `json:"configs"`
+	Templates []ConfigInfo `json:"templates"`
+}
+
+// TemplateMatch is a template that matched a service on an AD identifier
+type TemplateMatch struct {
+	ADIdentifier string `json:"ad_identifier"`
+	Template     string `json:"template"`
+	Digest       string `json:"digest"`
+}
+
+// ServiceInfo describes a service reported by the listeners, along with the
+// templates matching its AD identifiers and the checks it runs
+type ServiceInfo struct {
+	ID            listeners.ID    `json:"id"`
+	ADIdentifiers []string        `json:"ad_identifiers"`
+	Matches       []TemplateMatch `json:"matches"`
+	Checks        []check.ID      `json:"checks"`
+}
+
+// ConfigCheckResponse is a snapshot of what AutoConfig knows about the
+// configs, the templates and the services, to troubleshoot autodiscovery
+type ConfigCheckResponse struct {
+	Providers       []ProviderConfigs        `json:"providers"`
+	Services        []ServiceInfo            `json:"services"`
+	ResolvedConfigs []ConfigInfo             `json:"resolved_configs"`
+	LoaderErrors    map[string]LoaderErrors  `json:"loader_errors"`
+	ResolveErrors   map[string]ResolveErrors `json:"resolve_errors"`
+	RunErrors       map[check.ID]string      `json:"run_errors"`
+}
+
+// GetConfigCheck returns the configs and the templates collected by every
+// provider, the services and the templates they match, the configs the
+// checks were loaded from and the errors of AutoConfig. With verbose, the raw
+// YAML of the configs is part of the response. The secrets of the configs are
+// never decrypted.
+func (ac *AutoConfig) GetConfigCheck(verbose bool) ConfigCheckResponse {
+	resolveErrors := errorStats.getResolveErrors()
+	loaded := inventory.getConfigs()
+	checksByDigest := map[string][]check.ID{}
+	for _, info := range inventory.get().Checks {
+		checksByDigest[info.ConfigDigest] = append(checksByDigest[info.ConfigDigest], info.ID)
+	}
+
+	response := ConfigCheckResponse{
+		Providers:       []ProviderConfigs{},
+		Services:        []ServiceInfo{},
+		ResolvedConfigs: []ConfigInfo{},
+		LoaderErrors:    errorStats.getLoaderErrors(),
+		ResolveErrors:   resolveErrors,
+		RunErrors:       errorStats.getRunErrors(),
+	}
+
+	ac.m.RLock()
+	for _, pd := range ac.providers {
+		pc := ProviderConfigs{
+			Name:      fmt.Sprintf("%v", pd.provider),
+			Polled:    pd.poll && !pd.isWatching(),
+			Watched:   pd.isWatching(),
+			Configs:   []ConfigInfo{},
+			Templates: []ConfigInfo{},
+		}
+		for _, config := range pd.configs {
+			info := newConfigInfo(config, verbose)
+			if config.IsTemplate() {
+				// the checks scheduled when the template was collected, the
+				// ones of the services started later are listed with them
+				info.Checks = append(info.Checks, ac.config2checks[info.Digest]...)
+				info.ResolveErrors = resolveErrors[info.Digest]
+				pc.Templates = append(pc.Templates, info)
+			} else {
+				info.Checks = append(info.Checks, checksByDigest[info.Digest]...)
+				pc.Configs = append(pc.Configs, info)
+			}
+		}
+		response.Providers = append(response.Providers, pc)
+	}
+	ac.m.RUnlock()
+
+	for digest, config := range loaded {
+		// only the configs resolved from templates have AD identifiers
+		if len(config.ADIdentifiers) == 0 {
+			continue
+		}
+		info := newConfigInfo(config, verbose)
+		info.Checks = append(info.Checks, checksByDigest[digest]...)
+		response.ResolvedConfigs = append(response.ResolvedConfigs, info)
+	}
+	sort.Slice(response.ResolvedConfigs, func(i, j int) bool {
+		return response.ResolvedConfigs[i].Digest < response.ResolvedConfigs[j].Digest
+	})
+
+	response.Services = ac.configResolver.getServices(ac.templateCache)
+
+	return response
+}
+
+// newConfigInfo describes a config, its raw YAML is only set with verbose
+func newConfigInfo(config check.Config, verbose bool) ConfigInfo {
+	info := ConfigInfo{
+		Name:          config.Name,
+		Digest:        config.Digest(),
+		Provider:      config.Provider,
+		ADIdentifiers: config.ADIdentifiers,
+		Checks:        []check.ID{},
+	}
+	if verbose {
+		info.InitConfig = string(config.InitConfig)
+		info.Instances = make([]string, 0, len(config.Instances))
+		for _, instance := range config.Instances {
+			info.Instances = append(info.Instances, string(instance))
+		}
+	}
+	return info
+}
+
+// getServices describes the services known by the resolver along with the
+// templates of the cache matching their AD identifiers, sorted by ID
+func (cr *ConfigResolver) getServices(tc *TemplateCache) []ServiceInfo {
+	cr.m.Lock()
+	defer cr.m.Unlock()
+
+	services := make([]ServiceInfo, 0, len(cr.services))
+	for id, svc := range cr.services {
+		info := ServiceInfo{
+			ID:            id,
+			ADIdentifiers: []string{},
+			Matches:       []TemplateMatch{},
+			Checks:        append([]check.ID{}, cr.serviceToChecks[id]...),
+		}
+		// a service without identifiers matches no template
+		if adIDs, err := svc.GetADIdentifiers(); err == nil {
+			info.ADIdentifiers = append(info.ADIdentifiers, adIDs...)
+		}
+		for _, adID := range info.ADIdentifiers {
+			templates, err := tc.Get(adID)
+			if err != nil {
+				continue
+			}
+			for _, tpl := range templates {
+				info.Matches = append(info.Matches, TemplateMatch{
+					ADIdentifier: adID,
+					Template:     tpl.Name,
+					Digest:       tpl.Digest(),
+				})
+			}
+		}
+		services = append(services, info)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].ID < services[j].ID })
+
+	return services
+}
@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package autodiscovery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/listeners"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticProvider struct {
+	configs []check.Config
+}
+
+func (p *staticProvider) Collect() ([]check.Config, error) { return p.configs, nil }
+func (p *staticProvider) String() string                   { return "static provider" }
+
+// envService is a service with no environment variable
+type envService struct {
+	id    listeners.ID
+	adIDs []string
+}
+
+func (s *envService) GetID() listeners.ID                    { return s.id }
+func (s *envService) GetADIdentifiers() ([]string, error)    { return s.adIDs, nil }
+func (s *envService) GetHosts() (map[string]string, error)   { return nil, nil }
+func (s *envService) GetPorts() ([]int, error)               { return nil, nil }
+func (s *envService) GetTags() ([]string, error)             { return []string{}, nil }
+func (s *envService) GetPid() (int, error)                   { return -1, errors.New("no pid") }
+func (s *envService) GetHostname() (string, error)           { return "", nil }
+func (s *envService) GetEnv() (map[string]string, error)     { return map[string]string{}, nil }
+func (s *envService) GetLabels() (map[string]string, error)  { return map[string]string{}, nil }
+func (s *envService) GetNamedPorts() (map[string]int, error) { return map[string]int{}, nil }
+
+func TestGetConfigCheck(t *testing.T) {
+	plain := check.Config{
+		Name:       "cpu",
+		InitConfig: check.ConfigData("{}"),
+		Instances:  []check.ConfigData{check.ConfigData("{}")},
+	}
+	template := check.Config{
+		Name:          "redisdb",
+		ADIdentifiers: []string{"redis"},
+		Instances:     []check.ConfigData{check.ConfigData("host: %%host%%\npassword: %%env_REDIS_PASSWORD%%")},
+	}
+	ac := NewAutoConfig(nil)
+	ac.AddLoader(&MockLoader{})
+	ac.AddProvider(&staticProvider{configs: []check.Config{plain, template}}, false)
+	configs, _ := ac.collect(ac.providers[0])
+	require.Len(t, configs, 2)
+	require.Nil(t, ac.templateCache.Set(configs[1]))
+
+	// the template can't be resolved against the service
+	ac.configResolver.processNewService(&envService{id: "docker://abcd", adIDs: []string{"docker://abcd", "redis"}})
+
+	inventory.setChecks(configs[0], &MockLoader{}, []check.Check{&inventoryTestCheck{id: "cpu:1"}})
+	defer inventory.removeCheck("cpu:1")
+
+	res := ac.GetConfigCheck(false)
+	require.Len(t, res.Providers, 1)
+	provider := res.Providers[0]
+	assert.Equal(t, "static provider", provider.Name)
+	assert.False(t, provider.Polled)
+	require.Len(t, provider.Configs, 1)
+	assert.Equal(t, "cpu", provider.Configs[0].Name)
+	assert.Equal(t, configs[0].Digest(), provider.Configs[0].Digest)
+	assert.Equal(t, []check.ID{"cpu:1"}, provider.Configs[0].Checks)
+	assert.Empty(t, provider.Configs[0].Instances)
+
+	require.Len(t, provider.Templates, 1)
+	tplDigest := configs[1].Digest()
+	assert.Equal(t, tplDigest, provider.Templates[0].Digest)
+	require.Contains(t, provider.Templates[0].ResolveErrors, "docker://abcd")
+	assert.Contains(t, provider.Templates[0].ResolveErrors["docker://abcd"], "%%env_REDIS_PASSWORD%%")
+	assert.Equal(t, provider.Templates[0].ResolveErrors, map[string]string(res.ResolveErrors[tplDigest]))
+
+	require.Len(t, res.Services, 1)
+	assert.Equal(t, listeners.ID("docker://abcd"), res.Services[0].ID)
+	assert.Equal(t, []TemplateMatch{{ADIdentifier: "redis", Template: "redisdb", Digest: tplDigest}}, res.Services[0].Matches)
+	assert.Empty(t, res.Services[0].Checks)
+
+	// the raw YAML is only there in verbose mode
+	res = ac.GetConfigCheck(true)
+	assert.Equal(t, "{}", res.Providers[0].Configs[0].InitConfig)
+	assert.Equal(t, []string{"host: %%host%%\npassword: %%env_REDIS_PASSWORD%%"}, res.Providers[0].Templates[0].Instances)
+
+	// the errors go away with the service
+	ac.configResolver.processDelService(&envService{id: "docker://abcd"})
+	res = ac.GetConfigCheck(false)
+	assert.Empty(t, res.Services)
+	assert.Empty(t, res.ResolveErrors)
+}
@@ -7,7 +7,9 @@ package autodiscovery
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
 	"unicode"
@@ -18,7 +20,7 @@ import (
 	log "github.com/cihub/seelog"
 )
 
-type variableGetter func(key []byte, svc listeners.Service) []byte
+type variableGetter func(key []byte, svc listeners.Service) ([]byte, error)
 
 var (
 	templateVariables = map[string]variableGetter{
@@ -26,6 +28,21 @@ var (
 		"pid":            getPid,
 		"port":           getPort,
 		"container-name": getContainerName,
+		"env":            getEnvvar,
+		"label":          getLabel,
+		"hostname":       getHostname,
+	}
+
+	// the templates tried out of autodiscovery, e.g. by the `check` command,
+	// resolve %%host%% and %%port%% with the addresses of the service
+	serviceTemplateVariables = map[string]variableGetter{
+		"host":           getServiceHost,
+		"pid":            getPid,
+		"port":           getServicePort,
+		"container-name": getContainerName,
+		"env":            getEnvvar,
+		"label":          getLabel,
+		"hostname":       getHostname,
 	}
 )
 
@@ -35,6 +52,7 @@ var (
 type ConfigResolver struct {
 	ac              *AutoConfig
 	collector       *collector.Collector
+	variables       map[string]variableGetter
 	templates       *TemplateCache
 	services        map[listeners.ID]listeners.Service // Service.ID --> []Service
 	serviceToChecks map[listeners.ID][]check.ID        // Service.ID --> []CheckID
@@ -50,6 +68,7 @@ func newConfigResolver(coll *collector.Collector, ac *AutoConfig, tc *TemplateCa
 	cr := &ConfigResolver{
 		ac:              ac,
 		collector:       coll,
+		variables:       templateVariables,
 		templates:       tc,
 		services:        make(map[listeners.ID]listeners.Service),
 		serviceToChecks: make(map[listeners.ID][]check.ID, 0),
@@ -101,6 +120,7 @@ func (cr *ConfigResolver) Stop() {
 func (cr *ConfigResolver) ResolveTemplate(tpl check.Config) []check.Config {
 	// use a map to dedupe configurations
 	resolvedSet := map[string]check.Config{}
+	tplDigest := tpl.Digest()
 
 	// go through the AD identifiers provided by the template
 	for _, id := range tpl.ADIdentifiers {
@@ -115,9 +135,11 @@ func (cr *ConfigResolver) ResolveTemplate(tpl check.Config) []check.Config {
 			config, err := cr.resolve(tpl, cr.services[serviceID])
 			if err == nil {
 				resolvedSet[config.Digest()] = config
+				errorStats.removeResolveError(tplDigest, string(serviceID))
 			} else {
 				log.Debugf("Error resolving template %s for service %s: %v",
-					config.Name, serviceID, err)
+					tpl.Name, serviceID, err)
+				errorStats.setResolveError(tplDigest, string(serviceID), err.Error())
 			}
 		}
 	}
@@ -131,9 +153,21 @@ func (cr *ConfigResolver) ResolveTemplate(tpl check.Config) []check.Config {
 	return resolved
 }
 
+// ResolveTemplateForService resolves the template variables of `tpl` with the
+// data of `svc`, it's used to try templates out of autodiscovery. Unlike
+// autodiscovery, %%host%% and %%port%% resolve to the address of the service
+// when it has one.
+func ResolveTemplateForService(tpl check.Config, svc listeners.Service) (check.Config, error) {
+	cr := &ConfigResolver{variables: serviceTemplateVariables}
+	return cr.resolve(tpl, svc)
+}
+
 // resolve takes a template and a service and generates a config with
 // valid connection info and relevant tags.
 func (cr *ConfigResolver) resolve(tpl check.Config, svc listeners.Service) (check.Config, error) {
+	// the instances are shared with the cached template, don't modify them
+	tpl.Instances = append([]check.ConfigData{}, tpl.Instances...)
+
 	tags, err := svc.GetTags()
 	if err != nil {
 		return tpl, err
@@ -142,13 +176,14 @@ func (cr *ConfigResolver) resolve(tpl check.Config, svc listeners.Service) (chec
 		vars := tpl.GetTemplateVariablesForInstance(i)
 		for _, v := range vars {
 			name, key := parseTemplateVar(v)
-			if f, ok := templateVariables[string(name)]; ok {
-				resolvedVar := f(key, svc)
-				if resolvedVar != nil {
-					// init config vars are replaced by the first found
-					tpl.InitConfig = bytes.Replace(tpl.InitConfig, v, resolvedVar, -1)
-					tpl.Instances[i] = bytes.Replace(tpl.Instances[i], v, resolvedVar, -1)
+			if f, ok := cr.variables[string(name)]; ok {
+				resolvedVar, err := f(key, svc)
+				if err != nil {
+					return check.Config{}, fmt.Errorf("can't resolve %s: %s", v, err)
 				}
+				// init config vars are replaced by the first found
+				tpl.InitConfig = bytes.Replace(tpl.InitConfig, v, resolvedVar, -1)
+				tpl.Instances[i] = bytes.Replace(tpl.Instances[i], v, resolvedVar, -1)
 			} else {
 				return check.Config{}, fmt.Errorf("template variable %s does not exist", name)
 			}
@@ -193,9 +228,11 @@ func (cr *ConfigResolver) processNewService(svc listeners.Service) {
 		// resolve the template
 		config, err := cr.resolve(template, svc)
 		if err != nil {
-			log.Errorf("Unable to resolve configuration template: %v", err)
+			log.Errorf("Unable to resolve configuration template %s for service %s: %v", template.Name, svc.GetID(), err)
+			errorStats.setResolveError(template.Digest(), string(svc.GetID()), err.Error())
 			continue
 		}
+		errorStats.removeResolveError(template.Digest(), string(svc.GetID()))
 
 		// load the checks for this config using Autoconfig
 		checks, err := cr.ac.GetChecks(config)
@@ -209,6 +246,7 @@ func (cr *ConfigResolver) processNewService(svc listeners.Service) {
 			id, err := cr.collector.RunCheck(check)
 			if err != nil {
 				log.Errorf("Unable to schedule the check: %v", err)
+				cr.ac.discardCheck(check)
 				continue
 			}
 			// add the check to the list of checks running against the service
@@ -224,6 +262,22 @@ func (cr *ConfigResolver) processDelService(svc listeners.Service) {
 	cr.m.Lock()
 	defer cr.m.Unlock()
 
+	errorStats.removeServiceResolveErrors(string(svc.GetID()))
+
+	// forget the service so templates aren't resolved against it anymore
+	delete(cr.services, svc.GetID())
+	for adID, serviceIDs := range cr.adIDToServices {
+		for i, id := range serviceIDs {
+			if id == svc.GetID() {
+				cr.adIDToServices[adID] = append(serviceIDs[:i], serviceIDs[i+1:]...)
+				break
+			}
+		}
+		if len(cr.adIDToServices[adID]) == 0 {
+			delete(cr.adIDToServices, adID)
+		}
+	}
+
 	if checks, ok := cr.serviceToChecks[svc.GetID()]; ok {
 		stopped := map[check.ID]struct{}{}
 		for _, id := range checks {
@@ -232,6 +286,7 @@ func (cr *ConfigResolver) processDelService(svc listeners.Service) {
 				log.Errorf("Failed to stop check '%s': %s", id, err)
 			}
 			stopped[id] = struct{}{}
+			inventory.removeCheck(id)
 		}
 
 		// remove the entry from `serviceToChecks`
@@ -252,32 +307,138 @@ func (cr *ConfigResolver) processDelService(svc listeners.Service) {
 }
 
 // TODO (use svc.Hosts)
-func getHost(tplVar []byte, svc listeners.Service) []byte {
-	return []byte("127.0.0.1")
+func getHost(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	return []byte("127.0.0.1"), nil
+}
+
+// getPort returns the port of the service named by the key, e.g.
+// `%%port_http%%`, or at the index given as key, e.g. `%%port_0%%`, in
+// ascending order. It falls back to 80 otherwise.
+func getPort(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	key := string(tplVar)
+	idx, err := strconv.Atoi(key)
+	if key != "" && err != nil {
+		namedPorts, err := svc.GetNamedPorts()
+		if err != nil {
+			return nil, fmt.Errorf("no port named %s: %s", key, err)
+		}
+		port, found := namedPorts[key]
+		if !found {
+			return nil, fmt.Errorf("no port named %s for service %s", key, svc.GetID())
+		}
+		return []byte(strconv.Itoa(port)), nil
+	}
+
+	if key != "" {
+		if sorted := sortedPorts(svc); idx >= 0 && idx < len(sorted) {
+			return []byte(strconv.Itoa(sorted[idx])), nil
+		}
+	}
+	return []byte("80"), nil
+}
+
+// getServiceHost returns the IP address of the service on the network given
+// as key, on the `bridge` network or on the first network by name otherwise.
+// It falls back to 127.0.0.1 when the service has no known address.
+func getServiceHost(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	hosts, err := svc.GetHosts()
+	if err != nil || len(hosts) == 0 {
+		return []byte("127.0.0.1"), nil
+	}
+	if ip, ok := hosts[string(tplVar)]; ok {
+		return []byte(ip), nil
+	}
+	if ip, ok := hosts["bridge"]; ok {
+		return []byte(ip), nil
+	}
+	networks := make([]string, 0, len(hosts))
+	for network := range hosts {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	return []byte(hosts[networks[0]]), nil
 }
 
-// TODO (use svc.Ports)
-func getPort(tplVar []byte, svc listeners.Service) []byte {
-	return []byte("80")
+// getServicePort works like getPort, but returns the highest port of the
+// service when no key is given
+func getServicePort(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if sorted := sortedPorts(svc); len(tplVar) == 0 && len(sorted) > 0 {
+		return []byte(strconv.Itoa(sorted[len(sorted)-1])), nil
+	}
+	return getPort(tplVar, svc)
+}
+
+// sortedPorts returns the ports of the service in ascending order
+func sortedPorts(svc listeners.Service) []int {
+	ports, err := svc.GetPorts()
+	if err != nil {
+		return nil
+	}
+	sorted := append([]int{}, ports...)
+	sort.Ints(sorted)
+	return sorted
 }
 
 // getPid returns the process identifier of the service
-func getPid(tplVar []byte, svc listeners.Service) []byte {
+func getPid(tplVar []byte, svc listeners.Service) ([]byte, error) {
 	pid, err := svc.GetPid()
 	if err != nil {
-		log.Errorf("Failed to get pid for service %s, skipping config - %s", svc.GetID(), err)
-		return nil
+		return nil, fmt.Errorf("failed to get the pid of service %s: %s", svc.GetID(), err)
 	}
-	return []byte(strconv.Itoa(pid))
+	return []byte(strconv.Itoa(pid)), nil
 }
 
 // TODO
-func getContainerName(tplVar []byte, svc listeners.Service) []byte {
-	return []byte("test-container-name")
+func getContainerName(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	return []byte("test-container-name"), nil
+}
+
+// getEnvvar returns the value of the environment variable of the service
+// given as key, e.g. `%%env_REDIS_PASSWORD%%`
+func getEnvvar(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if len(tplVar) == 0 {
+		return nil, errors.New("the name of the environment variable is missing, use %%env_<VAR>%%")
+	}
+	env, err := svc.GetEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the environment of service %s: %s", svc.GetID(), err)
+	}
+	value, found := env[string(tplVar)]
+	if !found {
+		return nil, fmt.Errorf("environment variable %s is not set for service %s", tplVar, svc.GetID())
+	}
+	return []byte(value), nil
+}
+
+// getLabel returns the value of the label of the service given as key, e.g.
+// `%%label_com.example.port%%`
+func getLabel(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if len(tplVar) == 0 {
+		return nil, errors.New("the name of the label is missing, use %%label_<key>%%")
+	}
+	labels, err := svc.GetLabels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the labels of service %s: %s", svc.GetID(), err)
+	}
+	value, found := labels[string(tplVar)]
+	if !found {
+		return nil, fmt.Errorf("label %s is not set for service %s", tplVar, svc.GetID())
+	}
+	return []byte(value), nil
+}
+
+// getHostname returns the hostname of the service
+func getHostname(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	hostname, err := svc.GetHostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the hostname of service %s: %s", svc.GetID(), err)
+	}
+	return []byte(hostname), nil
 }
 
-// parseTemplateVar extracts the name of the var
-// and the key (or index if it can be cast to an int)
+// parseTemplateVar extracts the name of the var and the key (or index if it
+// can be cast to an int), everything after the first underscore is the key so
+// it can contain underscores, e.g. `%%env_REDIS_PASSWORD%%`
 func parseTemplateVar(v []byte) (name, key []byte) {
 	stripped := bytes.Map(func(r rune) rune {
 		if unicode.IsSpace(r) || r == '%' {
@@ -285,7 +446,7 @@ func parseTemplateVar(v []byte) (name, key []byte) {
 		}
 		return r
 	}, v)
-	split := bytes.Split(stripped, []byte("_"))
+	split := bytes.SplitN(stripped, []byte("_"), 2)
 	name = split[0]
 	if len(split) == 2 {
 		key = split[1]
@@ -58,9 +58,9 @@ func TestParseTemplateVar(t *testing.T) {
 	assert.Equal(t, "host0", string(name))
 	assert.Equal(t, "", string(key))
 
-	name, key = parseTemplateVar([]byte("%%host_0_1%%"))
-	assert.Equal(t, "host", string(name))
-	assert.Equal(t, "", string(key))
+	name, key = parseTemplateVar([]byte("%%env_REDIS_PASSWORD%%"))
+	assert.Equal(t, "env", string(name))
+	assert.Equal(t, "REDIS_PASSWORD", string(key))
 }
 
 func TestResolve(t *testing.T) {
@@ -86,8 +86,67 @@ func TestResolve(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "pid: 1337\ntags:\n- foo\n", string(config.Instances[0]))
 
+	// autodiscovery keeps the default host and port, only indexed ports are
+	// looked up
+	service.Hosts = map[string]string{"bridge": "172.17.0.2", "custom": "10.0.0.2"}
+	service.Ports = []int{6379, 80}
+	tpl.Instances = []check.ConfigData{check.ConfigData("host: %%host%%\nport: %%port%%\nother_port: %%port_1%%")}
+	config, err = cr.resolve(tpl, &service)
+	assert.Nil(t, err)
+	assert.Equal(t, "host: 127.0.0.1\nport: 80\nother_port: 6379", string(config.Instances[0]))
+
+	// the templates tried out of autodiscovery use the service's host and port
+	config, err = ResolveTemplateForService(tpl, &service)
+	assert.Nil(t, err)
+	assert.Equal(t, "host: 172.17.0.2\nport: 6379\nother_port: 6379", string(config.Instances[0]))
+	tpl.Instances = []check.ConfigData{check.ConfigData("host: %%host_custom%%\nport: %%port_0%%")}
+	config, err = ResolveTemplateForService(tpl, &service)
+	assert.Nil(t, err)
+	assert.Equal(t, "host: 10.0.0.2\nport: 80", string(config.Instances[0]))
+	assert.Equal(t, "host: %%host_custom%%\nport: %%port_0%%", string(tpl.Instances[0]))
+	service.Hosts = nil
+	service.Ports = nil
+	tpl.Instances = []check.ConfigData{check.ConfigData("host: %%host%%\nport: %%port%%")}
+	config, err = ResolveTemplateForService(tpl, &service)
+	assert.Nil(t, err)
+	assert.Equal(t, "host: 127.0.0.1\nport: 80", string(config.Instances[0]))
+
 	// template variable doesn't exist
 	tpl.Instances = []check.ConfigData{check.ConfigData("host: %%FOO%%")}
 	config, err = cr.resolve(tpl, &service)
 	assert.NotNil(t, err)
+
+	service.Hostname = "redis-1"
+	service.Env = map[string]string{"REDIS_PASSWORD": "s3cr3t"}
+	service.Labels = map[string]string{"com.example.db": "2"}
+	tpl.Instances = []check.ConfigData{check.ConfigData("host: %%hostname%%\npassword: %%env_REDIS_PASSWORD%%\ndb: %%label_com.example.db%%")}
+	config, err = cr.resolve(tpl, &service)
+	assert.Nil(t, err)
+	assert.Equal(t, "host: redis-1\npassword: s3cr3t\ndb: 2", string(config.Instances[0]))
+
+	// the variables that can't be resolved are reported
+	for _, v := range []string{"%%env_REDIS_USER%%", "%%env%%", "%%label_com.example.user%%", "%%port_redis%%"} {
+		tpl.Instances = []check.ConfigData{check.ConfigData("foo: " + v)}
+		_, err = cr.resolve(tpl, &service)
+		if assert.NotNil(t, err, v) {
+			assert.Contains(t, err.Error(), v)
+		}
+	}
+}
+
+func TestGetPortByName(t *testing.T) {
+	service := &listeners.KubeContainerService{
+		ID:         "docker://a5901276aed1",
+		Ports:      []int{9090, 8080},
+		NamedPorts: map[string]int{"http": 8080, "metrics": 9090},
+	}
+
+	port, err := getPort([]byte("metrics"), service)
+	assert.Nil(t, err)
+	assert.Equal(t, "9090", string(port))
+	port, err = getPort([]byte("0"), service)
+	assert.Nil(t, err)
+	assert.Equal(t, "8080", string(port))
+	_, err = getPort([]byte("grpc"), service)
+	assert.EqualError(t, err, "no port named grpc for service docker://a5901276aed1")
 }
@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package autodiscovery
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+// loaderKinds maps the known loaders to the short name used in the inventory
+var loaderKinds = map[string]string{
+	"Python Check Loader": "py",
+	"Core Check Loader":   "core",
+	"JMX Check Loader":    "jmx",
+}
+
+// CheckInfo describes where a check instance comes from
+type CheckInfo struct {
+	ID           check.ID `json:"id"`
+	Name         string   `json:"name"`
+	Loader       string   `json:"loader"`
+	ConfigDigest string   `json:"config_digest"`
+	Provider     string   `json:"provider"`
+}
+
+// Inventory is a snapshot of what AutoConfig is currently using
+type Inventory struct {
+	Providers []string    `json:"config_providers"`
+	Listeners []string    `json:"listeners"`
+	Checks    []CheckInfo `json:"checks"`
+}
+
+// acInventory keeps track of the providers and listeners AutoConfig uses and
+// of the check instances it loaded
+type acInventory struct {
+	providers []string
+	listeners []string
+	checks    map[check.ID]CheckInfo
+	configs   map[string]check.Config // config digest --> config the checks were loaded from
+	m         sync.RWMutex
+}
+
+func newAcInventory() *acInventory {
+	return &acInventory{
+		checks:  make(map[check.ID]CheckInfo),
+		configs: make(map[string]check.Config),
+	}
+}
+
+func (i *acInventory) addProvider(name string) {
+	i.m.Lock()
+	defer i.m.Unlock()
+	i.providers = append(i.providers, name)
+}
+
+func (i *acInventory) addListener(name string) {
+	i.m.Lock()
+	defer i.m.Unlock()
+	i.listeners = append(i.listeners, name)
+}
+
+// setChecks records the check instances a loader created from a config
+func (i *acInventory) setChecks(config check.Config, loader check.Loader, checks []check.Check) {
+	loaderName := fmt.Sprintf("%v", loader)
+	if kind, found := loaderKinds[loaderName]; found {
+		loaderName = kind
+	}
+	digest := config.Digest()
+
+	i.m.Lock()
+	defer i.m.Unlock()
+	if len(checks) > 0 {
+		i.configs[digest] = config
+	}
+	for _, c := range checks {
+		i.checks[c.ID()] = CheckInfo{
+			ID:           c.ID(),
+			Name:         config.Name,
+			Loader:       loaderName,
+			ConfigDigest: digest,
+			Provider:     config.Provider,
+		}
+	}
+}
+
+// moveChecks records that the check instances now come from `config`, the
+// configs they came from are forgotten once none of their checks is left
+func (i *acInventory) moveChecks(config check.Config, ids []check.ID) {
+	digest := config.Digest()
+
+	i.m.Lock()
+	defer i.m.Unlock()
+	previous := make(map[string]struct{})
+	for _, id := range ids {
+		info, found := i.checks[id]
+		if !found {
+			continue
+		}
+		previous[info.ConfigDigest] = struct{}{}
+		info.ConfigDigest = digest
+		info.Provider = config.Provider
+		i.checks[id] = info
+		i.configs[digest] = config
+	}
+
+	for _, info := range i.checks {
+		delete(previous, info.ConfigDigest)
+	}
+	for prev := range previous {
+		delete(i.configs, prev)
+	}
+}
+
+func (i *acInventory) removeCheck(id check.ID) {
+	i.m.Lock()
+	defer i.m.Unlock()
+	info, found := i.checks[id]
+	if !found {
+		return
+	}
+	delete(i.checks, id)
+
+	// forget the config once none of its checks is left
+	for _, other := range i.checks {
+		if other.ConfigDigest == info.ConfigDigest {
+			return
+		}
+	}
+	delete(i.configs, info.ConfigDigest)
+}
+
+// getConfigs returns a copy of the configs the checks were loaded from, by
+// digest
+func (i *acInventory) getConfigs() map[string]check.Config {
+	i.m.RLock()
+	defer i.m.RUnlock()
+
+	configs := make(map[string]check.Config, len(i.configs))
+	for digest, config := range i.configs {
+		configs[digest] = config
+	}
+	return configs
+}
+
+// get returns a copy of the inventory, checks are sorted by ID
+func (i *acInventory) get() Inventory {
+	i.m.RLock()
+	defer i.m.RUnlock()
+
+	inv := Inventory{
+		Providers: append([]string{}, i.providers...),
+		Listeners: append([]string{}, i.listeners...),
+		Checks:    make([]CheckInfo, 0, len(i.checks)),
+	}
+	for _, info := range i.checks {
+		inv.Checks = append(inv.Checks, info)
+	}
+	sort.Slice(inv.Checks, func(a, b int) bool { return inv.Checks[a].ID < inv.Checks[b].ID })
+
+	return inv
+}
+
+// GetInventory returns the providers and listeners in use along with the
+// origin of every check instance loaded by AutoConfig
+func GetInventory() Inventory {
+	return inventory.get()
+}
+
+// GetRunErrors returns the errors that occurred while scheduling check instances
+func GetRunErrors() map[check.ID]string {
+	return errorStats.getRunErrors()
+}
@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package autodiscovery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type inventoryTestCheck struct {
+	id      string
+	stopped bool
+}
+
+func (c *inventoryTestCheck) String() string                            { return "inventoryTestCheck" }
+func (c *inventoryTestCheck) Stop()                                     { c.stopped = true }
+func (c *inventoryTestCheck) Configure(a, b check.ConfigData) error     { return nil }
+func (c *inventoryTestCheck) Interval() time.Duration                   { return 1 * time.Minute }
+func (c *inventoryTestCheck) Run() error                                { return nil }
+func (c *inventoryTestCheck) ID() check.ID                              { return check.ID(c.id) }
+func (c *inventoryTestCheck) GetWarnings() []error                      { return []error{} }
+func (c *inventoryTestCheck) GetMetricStats() (map[string]int64, error) { return nil, nil }
+
+type pyLoader struct{}
+
+func (l *pyLoader) Load(config check.Config) ([]check.Check, error) { return nil, nil }
+func (l *pyLoader) String() string                                  { return "Python Check Loader" }
+
+type customLoader struct{}
+
+func (l *customLoader) Load(config check.Config) ([]check.Check, error) { return nil, nil }
+func (l *customLoader) String() string                                  { return "Custom Loader" }
+
+func TestInventory(t *testing.T) {
+	i := newAcInventory()
+	i.addProvider("File Configuration Provider")
+	i.addListener("Docker Listener")
+
+	config := check.Config{
+		Name:      "foo",
+		Instances: []check.ConfigData{check.ConfigData("foo: bar")},
+		Provider:  "File Configuration Provider",
+	}
+	i.setChecks(config, &pyLoader{}, []check.Check{
+		&inventoryTestCheck{id: "foo:2"},
+		&inventoryTestCheck{id: "foo:1"},
+	})
+	i.setChecks(check.Config{Name: "bar"}, &customLoader{}, []check.Check{&inventoryTestCheck{id: "bar:1"}})
+
+	inv := i.get()
+	assert.Equal(t, []string{"File Configuration Provider"}, inv.Providers)
+	assert.Equal(t, []string{"Docker Listener"}, inv.Listeners)
+	require.Len(t, inv.Checks, 3)
+
+	assert.Equal(t, check.ID("bar:1"), inv.Checks[0].ID)
+	assert.Equal(t, "", inv.Checks[0].Provider)
+	assert.Equal(t, "Custom Loader", inv.Checks[0].Loader)
+
+	assert.Equal(t, check.ID("foo:1"), inv.Checks[1].ID)
+	assert.Equal(t, CheckInfo{
+		ID:           "foo:2",
+		Name:         "foo",
+		Loader:       "py",
+		ConfigDigest: config.Digest(),
+		Provider:     "File Configuration Provider",
+	}, inv.Checks[2])
+
+	i.removeCheck("foo:1")
+	assert.Len(t, i.get().Checks, 2)
+
+	// the config is kept until all its checks are removed
+	assert.Equal(t, config, i.getConfigs()[config.Digest()])
+	i.removeCheck("foo:2")
+	assert.Len(t, i.getConfigs(), 1)
+	_, found := i.getConfigs()[config.Digest()]
+	assert.False(t, found)
+}
+
+func TestCollectSetsProvider(t *testing.T) {
+	pd := &providerDescriptor{provider: &fooProvider{}}
+	ac := NewAutoConfig(nil)
+	configs, _ := ac.collect(pd)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "foo provider", configs[0].Provider)
+}
+
+type fooProvider struct{}
+
+func (p *fooProvider) Collect() ([]check.Config, error) {
+	return []check.Config{{Name: "foo"}}, nil
+}
+func (p *fooProvider) String() string { return "foo provider" }
@@ -0,0 +1,272 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package autodiscovery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	log "github.com/cihub/seelog"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// NotFoundError is returned when the check instance isn't scheduled
+type NotFoundError struct {
+	ID check.ID
+}
+
+// Error returns the error message
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("cannot find a check with ID %s", e.ID)
+}
+
+// ScheduleConfig loads the checks of a config and schedules them. The
+// instances are loaded one by one, the error of the first one that can't be
+// loaded or scheduled is returned and nothing is scheduled in this case.
+func (ac *AutoConfig) ScheduleConfig(config check.Config) ([]check.ID, error) {
+	if config.Name == "" {
+		return nil, errors.New("the config has no check name")
+	}
+	if len(config.Instances) == 0 {
+		return nil, fmt.Errorf("the config of %s has no instances", config.Name)
+	}
+	if config.IsTemplate() {
+		return nil, fmt.Errorf("the config of %s is a template, it can't be scheduled directly", config.Name)
+	}
+
+	ac.m.Lock()
+	defer ac.m.Unlock()
+	return ac.schedule(config)
+}
+
+// UnscheduleCheck stops a check instance and forgets it
+func (ac *AutoConfig) UnscheduleCheck(id check.ID) error {
+	ac.m.Lock()
+	defer ac.m.Unlock()
+
+	if !ac.isScheduled(id) {
+		return &NotFoundError{ID: id}
+	}
+	return ac.unschedule(id)
+}
+
+// PatchInstance replaces a check instance with a copy of its configuration
+// where `patch` is merged: the settings it contains override the ones of the
+// instance, nested settings are merged the same way and the settings set to
+// null are removed. The new instance is scheduled in place of the old one,
+// which is kept if the new one can't be. It returns the config and the ID of
+// the new instance.
+func (ac *AutoConfig) PatchInstance(id check.ID, patch check.ConfigRawMap) (check.Config, check.ID, error) {
+	ac.m.Lock()
+	defer ac.m.Unlock()
+
+	if !ac.isScheduled(id) {
+		return check.Config{}, "", &NotFoundError{ID: id}
+	}
+	config, found := ac.getInstanceConfig(id)
+	if !found {
+		return check.Config{}, "", fmt.Errorf("the configuration of %s is unknown, it can't be patched", id)
+	}
+
+	instance := check.ConfigRawMap{}
+	if err := yaml.Unmarshal(config.Instances[0], &instance); err != nil {
+		return check.Config{}, "", err
+	}
+	mergePatch(instance, patch)
+	data, err := yaml.Marshal(instance)
+	if err != nil {
+		return check.Config{}, "", err
+	}
+	patched := config
+	patched.Instances = []check.ConfigData{data}
+	if patched.Digest() == config.Digest() {
+		// nothing changed
+		return config, id, nil
+	}
+
+	if err := ac.unschedule(id); err != nil {
+		return check.Config{}, "", err
+	}
+	ids, err := ac.schedule(patched)
+	if err != nil {
+		// put the old instance back
+		if _, e := ac.schedule(config); e != nil {
+			log.Errorf("Unable to schedule check %s again after a failed patch: %s", id, e)
+		}
+		return check.Config{}, "", err
+	}
+	if len(ids) != 1 {
+		// a loader grouped the instance with others, it can't be told apart
+		return patched, "", nil
+	}
+	return patched, ids[0], nil
+}
+
+// GetInstanceConfig returns the configuration of a scheduled check instance,
+// with its instance only
+func (ac *AutoConfig) GetInstanceConfig(id check.ID) (check.Config, bool) {
+	ac.m.RLock()
+	defer ac.m.RUnlock()
+	return ac.getInstanceConfig(id)
+}
+
+// getInstanceConfig looks for the config of a check instance in the ones
+// scheduled with ScheduleConfig then in the ones of the providers, ac.m must
+// be held
+func (ac *AutoConfig) getInstanceConfig(id check.ID) (check.Config, bool) {
+	if config, found := ac.liveConfigs[id]; found {
+		return config, true
+	}
+
+	// the ID is usually built from the name of the check, the instance and
+	// the init_config, see check.BuildID, some checks only have one instance
+	// and use their name
+	for _, pd := range ac.providers {
+		for _, config := range pd.configs {
+			if string(id) == config.Name && len(config.Instances) == 1 {
+				return config, true
+			}
+			if !strings.HasPrefix(string(id), config.Name+":") {
+				continue
+			}
+			for _, instance := range config.Instances {
+				if check.BuildID(config.Name, instance, config.InitConfig) == id {
+					config.Instances = []check.ConfigData{instance}
+					return config, true
+				}
+			}
+		}
+	}
+	return check.Config{}, false
+}
+
+// schedule loads and schedules the instances of a config one by one, ac.m
+// must be held
+func (ac *AutoConfig) schedule(config check.Config) ([]check.ID, error) {
+	if ac.collector == nil {
+		return nil, errors.New("the collector is not running")
+	}
+
+	scheduled := []check.ID{}
+	rollback := func() {
+		for _, id := range scheduled {
+			if err := ac.unschedule(id); err != nil {
+				log.Errorf("Unable to unschedule check %s: %s", id, err)
+			}
+		}
+	}
+
+	for i, instance := range config.Instances {
+		single := config
+		single.Instances = []check.ConfigData{instance}
+
+		checks, err := ac.GetChecks(single)
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("instance %d: %s", i+1, describeLoaderErrors(config.Name, err))
+		}
+		for j, c := range checks {
+			if _, err := ac.collector.RunCheck(c); err != nil {
+				// stop the checks that were loaded but won't run
+				for _, loaded := range checks[j:] {
+					ac.discardCheck(loaded)
+				}
+				rollback()
+				return nil, fmt.Errorf("instance %d: %s", i+1, err)
+			}
+			scheduled = append(scheduled, c.ID())
+			ac.liveConfigs[c.ID()] = single
+		}
+	}
+
+	digest := config.Digest()
+	ac.config2checks[digest] = append(ac.config2checks[digest], scheduled...)
+	return scheduled, nil
+}
+
+// isScheduled returns whether the collector runs the check instance
+func (ac *AutoConfig) isScheduled(id check.ID) bool {
+	if ac.collector == nil {
+		return false
+	}
+	_, found := ac.collector.GetCheck(id)
+	return found
+}
+
+// unschedule stops a check instance and forgets it, ac.m must be held
+func (ac *AutoConfig) unschedule(id check.ID) error {
+	if err := ac.collector.StopCheck(id); err != nil {
+		errorStats.setRunError(id, err.Error())
+		return err
+	}
+	inventory.removeCheck(id)
+	errorStats.removeRunError(id)
+	delete(ac.liveConfigs, id)
+
+	for digest, ids := range ac.config2checks {
+		kept := []check.ID{}
+		for _, i := range ids {
+			if i != id {
+				kept = append(kept, i)
+			}
+		}
+		if len(kept) == 0 {
+			delete(ac.config2checks, digest)
+		} else {
+			ac.config2checks[digest] = kept
+		}
+	}
+	return nil
+}
+
+// describeLoaderErrors returns the errors of the loaders that failed to load
+// the check, sorted by loader, or `err` if there's none
+func describeLoaderErrors(checkName string, err error) string {
+	loaderErrors := errorStats.getLoaderErrors()[checkName]
+	if len(loaderErrors) == 0 {
+		return err.Error()
+	}
+	msgs := make([]string, 0, len(loaderErrors))
+	for loader, e := range loaderErrors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", loader, e))
+	}
+	sort.Strings(msgs)
+	return strings.Join(msgs, "; ")
+}
+
+// mergePatch merges `patch` in `target` like a JSON merge patch (RFC 7386)
+func mergePatch(target, patch check.ConfigRawMap) {
+	for k, v := range patch {
+		if v == nil {
+			delete(target, k)
+			continue
+		}
+		p, isMap := asRawMap(v)
+		if !isMap {
+			target[k] = v
+			continue
+		}
+		t, isMap := asRawMap(target[k])
+		if !isMap {
+			t = check.ConfigRawMap{}
+		}
+		mergePatch(t, p)
+		target[k] = t
+	}
+}
+
+func asRawMap(v interface{}) (check.ConfigRawMap, bool) {
+	switch m := v.(type) {
+	case check.ConfigRawMap:
+		return m, true
+	case map[interface{}]interface{}:
+		return check.ConfigRawMap(m), true
+	}
+	return nil, false
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package autodiscovery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestScheduleConfigValidation(t *testing.T) {
+	ac := NewAutoConfig(nil)
+
+	_, err := ac.ScheduleConfig(check.Config{Instances: []check.ConfigData{check.ConfigData("foo: bar")}})
+	assert.EqualError(t, err, "the config has no check name")
+
+	_, err = ac.ScheduleConfig(check.Config{Name: "foo"})
+	assert.EqualError(t, err, "the config of foo has no instances")
+
+	_, err = ac.ScheduleConfig(check.Config{
+		Name:          "foo",
+		ADIdentifiers: []string{"redis"},
+		Instances:     []check.ConfigData{check.ConfigData("host: %%host%%")},
+	})
+	assert.EqualError(t, err, "the config of foo is a template, it can't be scheduled directly")
+
+	_, err = ac.ScheduleConfig(check.Config{Name: "foo", Instances: []check.ConfigData{check.ConfigData("foo: bar")}})
+	assert.EqualError(t, err, "the collector is not running")
+}
+
+func TestUnscheduledCheck(t *testing.T) {
+	ac := NewAutoConfig(nil)
+
+	err := ac.UnscheduleCheck("foo:1234")
+	assert.IsType(t, &NotFoundError{}, err)
+	_, _, err = ac.PatchInstance("foo:1234", check.ConfigRawMap{"foo": "bar"})
+	assert.IsType(t, &NotFoundError{}, err)
+}
+
+func TestGetInstanceConfig(t *testing.T) {
+	ac := NewAutoConfig(nil)
+	config := check.Config{
+		Name:       "foo",
+		InitConfig: check.ConfigData("timeout: 1"),
+		Instances:  []check.ConfigData{check.ConfigData("host: a"), check.ConfigData("host: b")},
+		Provider:   "File Configuration Provider",
+	}
+	ac.providers = append(ac.providers, &providerDescriptor{configs: []check.Config{config}})
+
+	id := check.BuildID("foo", config.Instances[1], config.InitConfig)
+	found, ok := ac.GetInstanceConfig(id)
+	require.True(t, ok)
+	assert.Equal(t, []check.ConfigData{check.ConfigData("host: b")}, found.Instances)
+	assert.Equal(t, config.InitConfig, found.InitConfig)
+	assert.Equal(t, config.Provider, found.Provider)
+
+	_, ok = ac.GetInstanceConfig(check.BuildID("bar", config.Instances[1], config.InitConfig))
+	assert.False(t, ok)
+	_, ok = ac.GetInstanceConfig("foo")
+	assert.False(t, ok)
+
+	// checks with a single instance can use their name as ID
+	ac.providers[0].configs = append(ac.providers[0].configs, check.Config{Name: "cpu", Instances: []check.ConfigData{check.ConfigData("{}")}})
+	found, ok = ac.GetInstanceConfig("cpu")
+	require.True(t, ok)
+	assert.Equal(t, "cpu", found.Name)
+}
+
+func TestMergePatch(t *testing.T) {
+	target := check.ConfigRawMap{}
+	require.Nil(t, yaml.Unmarshal([]byte("host: a\nport: 1\nauth:\n  user: foo\n  password: bar\ntags: [a, b]"), &target))
+	patch := check.ConfigRawMap{}
+	require.Nil(t, yaml.Unmarshal([]byte(`{"port": 2, "auth": {"password": null, "token": "t"}, "tags": ["c"], "ssl": {"verify": false}}`), &patch))
+
+	mergePatch(target, patch)
+
+	out, err := yaml.Marshal(target)
+	require.Nil(t, err)
+	assert.Equal(t, "auth:\n  token: t\n  user: foo\nhost: a\nport: 2\nssl:\n  verify: false\ntags:\n- c\n", string(out))
+}
+
+func TestDescribeLoaderErrors(t *testing.T) {
+	errorStats.setLoaderError("described", "Python Check Loader", "No module named described")
+	errorStats.setLoaderError("described", "Core Check Loader", "Check described not found in Catalog")
+	defer errorStats.removeLoaderErrors("described")
+
+	assert.Equal(t, "Core Check Loader: Check described not found in Catalog; Python Check Loader: No module named described",
+		describeLoaderErrors("described", errors.New("unable to load")))
+	assert.Equal(t, "unable to load", describeLoaderErrors("other", errors.New("unable to load")))
+}
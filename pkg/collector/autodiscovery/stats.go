@@ -9,23 +9,36 @@ import (
 	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 )
 
 // LoaderErrors is just an alias for a loader->error map
 type LoaderErrors map[string]string
 
+// ConfigErrors maps the location of a config, like the path of a file, to the
+// error parsing it
+type ConfigErrors map[string]string
+
+// ResolveErrors maps the ID of a service to the error resolving a template
+// against it
+type ResolveErrors map[string]string
+
 // loaderErrorStats holds the error objects
 type acErrorStats struct {
-	loader map[string]LoaderErrors // check name -> LoaderErrors
-	run    map[check.ID]string     // check ID -> error
-	m      sync.RWMutex
+	loader  map[string]LoaderErrors  // check name -> LoaderErrors
+	run     map[check.ID]string      // check ID -> error
+	resolve map[string]ResolveErrors // template digest -> ResolveErrors
+	config  map[string]ConfigErrors  // provider -> ConfigErrors
+	m       sync.RWMutex
 }
 
 // newAcErrorStats returns an instance holding autoconfig errors stats
 func newAcErrorStats() *acErrorStats {
 	return &acErrorStats{
-		loader: make(map[string]LoaderErrors),
-		run:    make(map[check.ID]string),
+		loader:  make(map[string]LoaderErrors),
+		run:     make(map[check.ID]string),
+		resolve: make(map[string]ResolveErrors),
+		config:  make(map[string]ConfigErrors),
 	}
 }
 
@@ -67,11 +80,13 @@ func (es *acErrorStats) getLoaderErrors() map[string]LoaderErrors {
 	return errorsCopy
 }
 
+// setRunError sets the error scheduling or stopping a check, the secrets of
+// its decrypted config are scrubbed
 func (es *acErrorStats) setRunError(checkID check.ID, err string) {
 	es.m.Lock()
 	defer es.m.Unlock()
 
-	es.run[checkID] = err
+	es.run[checkID] = secrets.Scrub(err)
 }
 
 func (es *acErrorStats) removeRunError(checkID check.ID) {
@@ -92,3 +107,93 @@ func (es *acErrorStats) getRunErrors() map[check.ID]string {
 
 	return runCopy
 }
+
+// setResolveError records the error resolving a template against a service
+func (es *acErrorStats) setResolveError(templateDigest string, serviceID string, err string) {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	if _, found := es.resolve[templateDigest]; !found {
+		es.resolve[templateDigest] = make(ResolveErrors)
+	}
+	es.resolve[templateDigest][serviceID] = err
+}
+
+// removeResolveError removes the error resolving a template against a
+// service, usually when it's resolved
+func (es *acErrorStats) removeResolveError(templateDigest string, serviceID string) {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	delete(es.resolve[templateDigest], serviceID)
+	if len(es.resolve[templateDigest]) == 0 {
+		delete(es.resolve, templateDigest)
+	}
+}
+
+// removeTemplateResolveErrors removes the errors of a template that's gone
+func (es *acErrorStats) removeTemplateResolveErrors(templateDigest string) {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	delete(es.resolve, templateDigest)
+}
+
+// removeServiceResolveErrors removes the errors of a service that's gone
+func (es *acErrorStats) removeServiceResolveErrors(serviceID string) {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	for digest, errors := range es.resolve {
+		delete(errors, serviceID)
+		if len(errors) == 0 {
+			delete(es.resolve, digest)
+		}
+	}
+}
+
+func (es *acErrorStats) getResolveErrors() map[string]ResolveErrors {
+	es.m.RLock()
+	defer es.m.RUnlock()
+
+	resolveCopy := make(map[string]ResolveErrors)
+	for digest, errors := range es.resolve {
+		resolveCopy[digest] = make(ResolveErrors)
+		for svc, err := range errors {
+			resolveCopy[digest][svc] = err
+		}
+	}
+
+	return resolveCopy
+}
+
+// setConfigErrors replaces the errors parsing the configs of a provider
+func (es *acErrorStats) setConfigErrors(provider string, errors map[string]string) {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	if len(errors) == 0 {
+		delete(es.config, provider)
+		return
+	}
+	es.config[provider] = make(ConfigErrors, len(errors))
+	for location, err := range errors {
+		es.config[provider][location] = err
+	}
+}
+
+// getConfigErrors returns the errors parsing the configs of all the
+// providers, by location
+func (es *acErrorStats) getConfigErrors() ConfigErrors {
+	es.m.RLock()
+	defer es.m.RUnlock()
+
+	configCopy := make(ConfigErrors)
+	for _, errors := range es.config {
+		for location, err := range errors {
+			configCopy[location] = err
+		}
+	}
+
+	return configCopy
+}
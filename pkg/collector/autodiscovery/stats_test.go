@@ -64,3 +64,14 @@ func TestGetRunError(t *testing.T) {
 
 	assert.Len(t, err, 1)
 }
+
+func TestConfigErrors(t *testing.T) {
+	s := newAcErrorStats()
+	s.setConfigErrors("files", map[string]string{"conf.d/foo.yaml": "anError"})
+	s.setConfigErrors("http", map[string]string{"https://example.com": "anotherError"})
+	assert.Equal(t, ConfigErrors{"conf.d/foo.yaml": "anError", "https://example.com": "anotherError"}, s.getConfigErrors())
+
+	// the errors of a provider are replaced
+	s.setConfigErrors("files", map[string]string{})
+	assert.Equal(t, ConfigErrors{"https://example.com": "anotherError"}, s.getConfigErrors())
+}
@@ -61,6 +61,7 @@ type Config struct {
 	Instances     []ConfigData // array of Yaml configurations
 	InitConfig    ConfigData   // the init_config in Yaml (python check only)
 	ADIdentifiers []string     // the list of AutoDiscovery identifiers (optional)
+	Provider      string       // the provider that issued the config (optional, not part of the Digest)
 }
 
 // Check is an interface for types capable to run checks
@@ -75,25 +76,46 @@ type Check interface {
 	GetMetricStats() (map[string]int64, error)     // get metric stats from the sender
 }
 
+// WithTimeout is an optional interface for checks defining their own run
+// timeout. A zero timeout means the `check_timeout` agent setting applies.
+type WithTimeout interface {
+	Timeout() time.Duration
+}
+
+// TimeoutError is returned when a check run doesn't complete in time
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+// Error returns the error message
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timeout: the check run didn't complete within %v", e.Timeout)
+}
+
 // Stats holds basic runtime statistics about check instances
 type Stats struct {
-	CheckName          string
-	CheckID            ID
-	TotalRuns          uint64
-	TotalErrors        uint64
-	TotalWarnings      uint64
-	Metrics            int64
-	Events             int64
-	ServiceChecks      int64
-	TotalMetrics       int64
-	TotalEvents        int64
-	TotalServiceChecks int64
-	ExecutionTimes     [32]int64 // circular buffer of recent run durations, most recent at [(TotalRuns+31) % 32]
-	LastExecutionTime  int64     // most recent run duration, provided for convenience
-	LastError          string    // error that occured in the last run, if any
-	LastWarnings       []string  // warnings that occured in the last run, if any
-	UpdateTimestamp    int64     // latest update to this instance, unix timestamp in seconds
-	m                  sync.Mutex
+	CheckName            string
+	CheckID              ID
+	TotalRuns            uint64
+	TotalErrors          uint64
+	TotalWarnings        uint64
+	TotalTimeouts        uint64
+	TotalRetries         uint64 // attempts made after a failed one, see RetryPolicy
+	ConsecutiveFailures  uint64
+	DisabledUntil        int64 // unix timestamp of the next attempt when the check is disabled, 0 otherwise
+	Metrics              int64
+	Events               int64
+	ServiceChecks        int64
+	TotalMetrics         int64
+	TotalEvents          int64
+	TotalServiceChecks   int64
+	ExecutionTimes       [32]int64 // circular buffer of recent run durations, most recent at [(TotalRuns+31) % 32]
+	LastExecutionTime    int64     // most recent run duration, provided for convenience
+	LongestExecutionTime int64     // longest run duration, timed out runs count for their timeout
+	LastError            string    // error that occured in the last run, if any
+	LastWarnings         []string  // warnings that occured in the last run, if any
+	UpdateTimestamp      int64     // latest update to this instance, unix timestamp in seconds
+	m                    sync.Mutex
 }
 
 // NewStats returns a new check stats instance
@@ -104,6 +126,20 @@ func NewStats(c Check) *Stats {
 	}
 }
 
+// AddRetries tracks the attempts made after failed ones during a run
+func (cs *Stats) AddRetries(n uint64) {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	cs.TotalRetries += n
+}
+
+// SetDisabledUntil records until when the check is disabled, 0 if it's not
+func (cs *Stats) SetDisabledUntil(ts int64) {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	cs.DisabledUntil = ts
+}
+
 // Add tracks a new execution time
 func (cs *Stats) Add(t time.Duration, err error, warnings []error, metricStats map[string]int64) {
 	cs.m.Lock()
@@ -114,11 +150,19 @@ func (cs *Stats) Add(t time.Duration, err error, warnings []error, metricStats m
 	cs.LastExecutionTime = tms
 	cs.ExecutionTimes[cs.TotalRuns] = tms
 	cs.TotalRuns = (cs.TotalRuns + 1) % 32
+	if tms > cs.LongestExecutionTime {
+		cs.LongestExecutionTime = tms
+	}
 	if err != nil {
 		cs.TotalErrors++
 		cs.LastError = err.Error()
+		if _, isTimeout := err.(*TimeoutError); isTimeout {
+			cs.TotalTimeouts++
+		}
+		cs.ConsecutiveFailures++
 	} else {
 		cs.LastError = ""
+		cs.ConsecutiveFailures = 0
 	}
 	cs.LastWarnings = []string{}
 	if len(warnings) != 0 {
@@ -259,6 +303,53 @@ func (c *ConfigData) MergeAdditionalTags(tags []string) error {
 	return nil
 }
 
+// SetValue sets the setting `key` of the config to `value`, parsed as YAML.
+// Nested settings are separated by dots, e.g. `auth.user`, the missing
+// parents are created.
+func (c *ConfigData) SetValue(key, value string) error {
+	rawConfig := ConfigRawMap{}
+	if err := yaml.Unmarshal(*c, &rawConfig); err != nil {
+		return err
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+		return fmt.Errorf("invalid value for %s: %s", key, err)
+	}
+
+	parent := rawConfig
+	path := strings.Split(key, ".")
+	for i, name := range path[:len(path)-1] {
+		if name == "" {
+			return fmt.Errorf("invalid setting name %q", key)
+		}
+		switch child := parent[name].(type) {
+		case ConfigRawMap:
+			parent = child
+		case map[interface{}]interface{}:
+			parent = ConfigRawMap(child)
+		case nil:
+			m := ConfigRawMap{}
+			parent[name] = m
+			parent = m
+		default:
+			return fmt.Errorf("can't set %s: %s is not a map", key, strings.Join(path[:i+1], "."))
+		}
+	}
+	name := path[len(path)-1]
+	if name == "" {
+		return fmt.Errorf("invalid setting name %q", key)
+	}
+	parent[name] = v
+
+	out, err := yaml.Marshal(&rawConfig)
+	if err != nil {
+		return err
+	}
+	*c = ConfigData(out)
+
+	return nil
+}
+
 // Digest returns an hash value representing the data stored in this configuration
 func (c *Config) Digest() string {
 	h := fnv.New64()
@@ -7,7 +7,9 @@ package check
 
 import (
 	"crypto/rand"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	yaml "gopkg.in/yaml.v2"
@@ -89,11 +91,46 @@ func TestMergeAdditionalTags(t *testing.T) {
 	assert.Contains(t, rawConfig["tags"], "bar")
 }
 
+func TestSetValue(t *testing.T) {
+	data := ConfigData("host: localhost\nauth:\n  user: foo\n")
+
+	assert.Nil(t, data.SetValue("port", "6379"))
+	assert.Nil(t, data.SetValue("auth.password", "bar"))
+	assert.Nil(t, data.SetValue("tags", "[\"env:dev\"]"))
+	assert.Nil(t, data.SetValue("ssl.verify", "false"))
+
+	rawConfig := ConfigRawMap{}
+	assert.Nil(t, yaml.Unmarshal(data, &rawConfig))
+	assert.Equal(t, "localhost", rawConfig["host"])
+	assert.Equal(t, 6379, rawConfig["port"])
+	assert.Equal(t, []interface{}{"env:dev"}, rawConfig["tags"])
+	assert.Equal(t, ConfigRawMap{"user": "foo", "password": "bar"}, rawConfig["auth"])
+	assert.Equal(t, ConfigRawMap{"verify": false}, rawConfig["ssl"])
+
+	assert.NotNil(t, data.SetValue("host.name", "foo"))
+	assert.NotNil(t, data.SetValue("auth.", "foo"))
+	assert.NotNil(t, data.SetValue("port", "[unclosed"))
+}
+
 func TestDigest(t *testing.T) {
 	config := &Config{}
 	assert.Equal(t, 16, len(config.Digest()))
 }
 
+func TestStatsTimeouts(t *testing.T) {
+	s := NewStats(&TestCheck{})
+	s.Add(20*time.Millisecond, nil, nil, nil)
+	s.Add(10*time.Millisecond, errors.New("boom"), nil, nil)
+	assert.Equal(t, uint64(0), s.TotalTimeouts)
+	assert.Equal(t, int64(20), s.LongestExecutionTime)
+
+	s.Add(time.Second, &TimeoutError{Timeout: time.Second}, nil, nil)
+	assert.Equal(t, uint64(1), s.TotalTimeouts)
+	assert.Equal(t, uint64(2), s.TotalErrors)
+	assert.Equal(t, int64(1000), s.LongestExecutionTime)
+	assert.Equal(t, "timeout: the check run didn't complete within 1s", s.LastError)
+}
+
 // this is here to prevent compiler optimization on the benchmarking code
 var result string
 
@@ -107,3 +144,17 @@ func BenchmarkID(b *testing.B) {
 	}
 	result = id
 }
+
+func TestStatsFailures(t *testing.T) {
+	s := NewStats(&TestCheck{})
+	s.Add(time.Millisecond, errors.New("boom"), nil, nil)
+	s.Add(time.Millisecond, errors.New("boom"), nil, nil)
+	assert.Equal(t, uint64(2), s.ConsecutiveFailures)
+	s.AddRetries(3)
+	s.SetDisabledUntil(42)
+	assert.Equal(t, uint64(3), s.TotalRetries)
+	assert.Equal(t, int64(42), s.DisabledUntil)
+
+	s.Add(time.Millisecond, nil, nil, nil)
+	assert.Equal(t, uint64(0), s.ConsecutiveFailures)
+}
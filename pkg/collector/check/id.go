@@ -15,11 +15,16 @@ type ID string
 
 // Identify returns the ID of the check
 func Identify(check Check, instance ConfigData, initConfig ConfigData) ID {
+	return BuildID(check.String(), instance, initConfig)
+}
+
+// BuildID returns the ID of an instance of the check `name`
+func BuildID(name string, instance ConfigData, initConfig ConfigData) ID {
 	h := fnv.New64()
 	h.Write([]byte(instance))
 	h.Write([]byte(initConfig))
 
-	id := check.String() + ":"
+	id := name + ":"
 	id += strconv.FormatUint(h.Sum64(), 16)
 	return ID(id)
 }
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package check
+
+import (
+	"fmt"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RetryPolicy tells how failing runs of a check are retried and when the check
+// is disabled because it keeps failing. Zero values are replaced by defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a run is attempted before reporting
+	// an error
+	MaxAttempts int
+	// Backoff is the wait before the second attempt, doubled at every attempt
+	Backoff time.Duration
+	// MinRuntime only applies to long running checks: a run lasting at least
+	// this long before failing resets the attempts
+	MinRuntime time.Duration
+	// CircuitBreakerThreshold is the number of consecutive failed runs after
+	// which the check is disabled, 0 means it's never disabled
+	CircuitBreakerThreshold int
+	// CircuitBreakerBackoff is how long the check is first disabled, doubled
+	// every time the run attempted when re-enabling it fails
+	CircuitBreakerBackoff time.Duration
+}
+
+// WithRetryPolicy is an optional interface for checks having their own retry
+// policy. A nil policy means the defaults apply.
+type WithRetryPolicy interface {
+	RetryPolicy() *RetryPolicy
+}
+
+// retryConfig is the `retry` section of an instance, durations are in seconds
+type retryConfig struct {
+	Retry *struct {
+		MaxAttempts             int     `yaml:"max_attempts"`
+		Backoff                 float64 `yaml:"backoff"`
+		MinRuntime              float64 `yaml:"min_runtime"`
+		CircuitBreakerThreshold int     `yaml:"circuit_breaker_threshold"`
+		CircuitBreakerBackoff   float64 `yaml:"circuit_breaker_backoff"`
+	} `yaml:"retry"`
+}
+
+// GetRetryPolicy returns the policy set in the `retry` section of an
+// instance, nil if there's none
+func GetRetryPolicy(instance ConfigData) (*RetryPolicy, error) {
+	c := retryConfig{}
+	if err := yaml.Unmarshal(instance, &c); err != nil {
+		return nil, fmt.Errorf("invalid retry settings: %s", err)
+	}
+	if c.Retry == nil {
+		return nil, nil
+	}
+
+	r := c.Retry
+	if r.MaxAttempts < 0 || r.Backoff < 0 || r.MinRuntime < 0 || r.CircuitBreakerThreshold < 0 || r.CircuitBreakerBackoff < 0 {
+		return nil, fmt.Errorf("invalid retry settings: values can't be negative")
+	}
+
+	seconds := func(s float64) time.Duration {
+		return time.Duration(s * float64(time.Second))
+	}
+	return &RetryPolicy{
+		MaxAttempts:             r.MaxAttempts,
+		Backoff:                 seconds(r.Backoff),
+		MinRuntime:              seconds(r.MinRuntime),
+		CircuitBreakerThreshold: r.CircuitBreakerThreshold,
+		CircuitBreakerBackoff:   seconds(r.CircuitBreakerBackoff),
+	}, nil
+}
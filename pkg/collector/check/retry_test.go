@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package check
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRetryPolicy(t *testing.T) {
+	p, err := GetRetryPolicy(ConfigData("foo: bar"))
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+
+	p, err = GetRetryPolicy(ConfigData(`
+retry:
+  max_attempts: 3
+  backoff: 0.5
+  min_runtime: 10
+  circuit_breaker_threshold: 5
+  circuit_breaker_backoff: 60
+`))
+	require.Nil(t, err)
+	assert.Equal(t, &RetryPolicy{
+		MaxAttempts:             3,
+		Backoff:                 500 * time.Millisecond,
+		MinRuntime:              10 * time.Second,
+		CircuitBreakerThreshold: 5,
+		CircuitBreakerBackoff:   time.Minute,
+	}, p)
+
+	// defaults are left to the runner
+	p, err = GetRetryPolicy(ConfigData("retry:\n  max_attempts: 2"))
+	require.Nil(t, err)
+	assert.Equal(t, &RetryPolicy{MaxAttempts: 2}, p)
+
+	_, err = GetRetryPolicy(ConfigData("retry:\n  max_attempts: -1"))
+	assert.NotNil(t, err)
+	_, err = GetRetryPolicy(ConfigData("retry: foo"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,258 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package check
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduleOnce is the `schedule` value of the checks that run only once
+const ScheduleOnce = "once"
+
+// Schedule tells when a check should run, as an alternative to a fixed interval
+type Schedule interface {
+	// Next returns the first run time strictly after t, the zero time if
+	// there's none
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// WithSchedule is an optional interface for checks that can be given a
+// schedule. A nil Schedule means the check runs at its Interval().
+type WithSchedule interface {
+	Schedule() Schedule
+}
+
+// OnceSchedule is the schedule of the checks that run once, as soon as
+// they're scheduled
+type OnceSchedule struct{}
+
+// Next always returns the zero time, the only run is the one at scheduling time
+func (OnceSchedule) Next(t time.Time) time.Time {
+	return time.Time{}
+}
+
+// String returns `once`
+func (OnceSchedule) String() string {
+	return ScheduleOnce
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// cronField describes one of the five fields of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	{name: "day of week", min: 0, max: 7, names: dayNames},
+}
+
+// CronSchedule is a schedule defined by a standard 5 fields cron expression
+// (minute, hour, day of month, month, day of week), evaluated in the time
+// zone of the time passed to Next
+type CronSchedule struct {
+	expr                     string
+	minute, hour, dom, month uint64 // bitsets of the allowed values
+	dow                      uint64
+	domWildcard, dowWildcard bool
+}
+
+// ParseSchedule parses the value of the `schedule` instance setting: either
+// `once` or a cron expression. The usual `@daily`-like shortcuts are supported.
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == ScheduleOnce {
+		return OnceSchedule{}, nil
+	}
+
+	spec := expr
+	if strings.HasPrefix(expr, "@") {
+		s, found := cronDescriptors[expr]
+		if !found {
+			return nil, fmt.Errorf("unknown schedule %s", expr)
+		}
+		spec = s
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule %q: expected %q or 5 cron fields, got %d fields", expr, ScheduleOnce, len(fields))
+	}
+
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := cronFields[i].parse(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %s", expr, err)
+		}
+		bits[i] = b
+	}
+
+	// 0 and 7 are both sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &CronSchedule{
+		expr:        expr,
+		minute:      bits[0],
+		hour:        bits[1],
+		dom:         bits[2],
+		month:       bits[3],
+		dow:         bits[4],
+		domWildcard: strings.HasPrefix(fields[2], "*"),
+		dowWildcard: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parse returns the bitset of the values matched by a field, made of a comma
+// separated list of `*`, `n`, `a-b` items, each optionally followed by a `/step`
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			s, err := strconv.Atoi(item[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %s", f.name, item)
+			}
+			rng, step = item[:i], s
+		}
+
+		var from, to int
+		switch {
+		case rng == "*":
+			from, to = f.min, f.max
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if from, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if to, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			from, to = v, v
+			if step > 1 {
+				// `n/step` means from n to the max
+				to = f.max
+			}
+		}
+
+		if from > to {
+			return 0, fmt.Errorf("invalid range in %s field: %s", f.name, item)
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, found := f.names[strings.ToLower(s)]; found {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field: %s", f.name, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s out of range [%d-%d]: %d", f.name, f.min, f.max, v)
+	}
+	return v, nil
+}
+
+// Next returns the first minute strictly after t matching the expression, the
+// zero time if there's none in the next five years (e.g. `0 0 30 2 *`)
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchDay follows the cron convention: when both the day of month and the
+// day of week are restricted, matching either of them is enough
+func (s *CronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if !s.domWildcard && !s.dowWildcard {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// String returns the cron expression
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// GetSchedule returns the schedule set in the `schedule` field of an
+// instance, nil if there's none
+func GetSchedule(instance ConfigRawMap) (Schedule, error) {
+	x, found := instance["schedule"]
+	if !found {
+		return nil, nil
+	}
+	expr, ok := x.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid schedule: %v is not a string", x)
+	}
+	return ParseSchedule(expr)
+}
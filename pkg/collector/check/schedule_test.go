@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package check
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScheduleOnce(t *testing.T) {
+	s, err := ParseSchedule(" once ")
+	require.Nil(t, err)
+	assert.Equal(t, OnceSchedule{}, s)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"twice",
+		"@fortnightly",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+	} {
+		_, err := ParseSchedule(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// a monday
+	now := time.Date(2017, 10, 2, 12, 34, 56, 0, time.UTC)
+
+	for _, tc := range []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2017, 10, 2, 12, 35, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2017, 10, 2, 12, 45, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2017, 10, 3, 2, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2017, 10, 3, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2017, 10, 2, 13, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2017, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * sat,sun", time.Date(2017, 10, 7, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2017, 10, 8, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * FEB *", time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"10-20/5 13-14 * * *", time.Date(2017, 10, 2, 13, 10, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2017, 10, 2, 12, 45, 0, 0, time.UTC)},
+		// day of month or day of week
+		{"0 0 15 * fri", time.Date(2017, 10, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+	} {
+		s, err := ParseSchedule(tc.expr)
+		require.Nil(t, err, tc.expr)
+		assert.Equal(t, tc.next, s.Next(now), tc.expr)
+		assert.Equal(t, tc.expr, s.String())
+	}
+
+	// never
+	s, err := ParseSchedule("0 0 30 2 *")
+	require.Nil(t, err)
+	assert.True(t, s.Next(now).IsZero())
+}
+
+func TestGetSchedule(t *testing.T) {
+	s, err := GetSchedule(ConfigRawMap{"foo": "bar"})
+	assert.Nil(t, err)
+	assert.Nil(t, s)
+
+	s, err = GetSchedule(ConfigRawMap{"schedule": "once"})
+	assert.Nil(t, err)
+	assert.Equal(t, OnceSchedule{}, s)
+
+	_, err = GetSchedule(ConfigRawMap{"schedule": 5})
+	assert.NotNil(t, err)
+}
@@ -35,6 +35,7 @@ type Collector struct {
 func NewCollector(paths ...string) *Collector {
 	run := runner.NewRunner(config.Datadog.GetInt("check_runners"))
 	sched := scheduler.NewScheduler(run.GetChan())
+	sched.SetQueueObserver(run)
 	sched.Run()
 
 	c := &Collector{
@@ -167,6 +168,15 @@ func (c *Collector) StopCheck(id check.ID) error {
 	return nil
 }
 
+// GetCheck returns the scheduled check instance with this ID
+func (c *Collector) GetCheck(id check.ID) (check.Check, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	ch, found := c.checks[id]
+	return ch, found
+}
+
 // check if the check is on the list
 func (c *Collector) find(id check.ID) bool {
 	c.m.RLock()
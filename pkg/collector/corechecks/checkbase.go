@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package corechecks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	log "github.com/cihub/seelog"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// CommonInstanceConfig holds the settings every instance of a Go check
+// accepts, they're parsed by CheckBase.CommonConfigure
+type CommonInstanceConfig struct {
+	MinCollectionInterval int      `yaml:"min_collection_interval"`
+	Tags                  []string `yaml:"tags"`
+}
+
+// CheckBase provides the parts of check.Check every Go check implements the
+// same way. Checks embed it, set it with NewCheckBase in their factory and
+// call CommonConfigure from their Configure method, they only have to
+// implement Run:
+//
+//	type FooCheck struct {
+//	    core.CheckBase
+//	}
+//
+//	func (c *FooCheck) Configure(data, initConfig check.ConfigData) error {
+//	    return c.CommonConfigure(data)
+//	}
+//
+//	func fooFactory() check.Check {
+//	    return &FooCheck{CheckBase: core.NewCheckBase("foo")}
+//	}
+type CheckBase struct {
+	checkName    string
+	checkID      check.ID
+	interval     time.Duration
+	tags         []string
+	schedule     check.Schedule
+	retryPolicy  *check.RetryPolicy
+	lastWarnings []error
+}
+
+// NewCheckBase returns a CheckBase for the check `name`
+func NewCheckBase(name string) CheckBase {
+	return CheckBase{
+		checkName: name,
+		checkID:   check.ID(name),
+		interval:  check.DefaultCheckInterval,
+	}
+}
+
+// CommonConfigure parses the settings shared by every instance: the
+// `min_collection_interval` (in seconds), the `tags`, the `schedule` and
+// the `retry` policy
+func (c *CheckBase) CommonConfigure(instance check.ConfigData) error {
+	conf := CommonInstanceConfig{}
+	if err := UnmarshalConfig(instance, &conf); err != nil {
+		return fmt.Errorf("%s: invalid instance configuration: %s", c.checkName, err)
+	}
+
+	if conf.MinCollectionInterval < 0 {
+		return fmt.Errorf("%s: min_collection_interval can't be negative", c.checkName)
+	}
+	c.interval = check.DefaultCheckInterval
+	if conf.MinCollectionInterval > 0 {
+		c.interval = time.Duration(conf.MinCollectionInterval) * time.Second
+	}
+
+	c.tags = nil
+	if len(conf.Tags) > 0 {
+		c.tags = conf.Tags
+	}
+
+	raw := check.ConfigRawMap{}
+	if err := yaml.Unmarshal(instance, &raw); err != nil {
+		return fmt.Errorf("%s: invalid instance configuration: %s", c.checkName, err)
+	}
+	schedule, err := check.GetSchedule(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %s", c.checkName, err)
+	}
+	retryPolicy, err := check.GetRetryPolicy(instance)
+	if err != nil {
+		return fmt.Errorf("%s: %s", c.checkName, err)
+	}
+	c.schedule = schedule
+	c.retryPolicy = retryPolicy
+
+	return nil
+}
+
+// BuildID gives an ID made of the name of the check and a hash of its
+// configuration, for checks that can run several instances
+func (c *CheckBase) BuildID(instance, initConfig check.ConfigData) {
+	c.checkID = check.BuildID(c.checkName, instance, initConfig)
+}
+
+// String returns the name of the check
+func (c *CheckBase) String() string {
+	return c.checkName
+}
+
+// ID returns the name of the check, unless BuildID was called, since there
+// should be only one instance running
+func (c *CheckBase) ID() check.ID {
+	return c.checkID
+}
+
+// Interval returns the scheduling time for the check, the
+// `min_collection_interval` of the instance if set
+func (c *CheckBase) Interval() time.Duration {
+	return c.interval
+}
+
+// Schedule returns the `schedule` of the instance, nil if there's none
+func (c *CheckBase) Schedule() check.Schedule {
+	return c.schedule
+}
+
+// RetryPolicy returns the `retry` policy of the instance, nil if there's none
+func (c *CheckBase) RetryPolicy() *check.RetryPolicy {
+	return c.retryPolicy
+}
+
+// Tags returns the `tags` of the instance, to be added to everything the
+// check sends
+func (c *CheckBase) Tags() []string {
+	return c.tags
+}
+
+// Stop does nothing
+func (c *CheckBase) Stop() {}
+
+// GetWarnings grabs the last warnings from the check
+func (c *CheckBase) GetWarnings() []error {
+	w := c.lastWarnings
+	c.lastWarnings = []error{}
+	return w
+}
+
+// Warn will log a warning and add it to the warnings
+func (c *CheckBase) Warn(v ...interface{}) error {
+	w := log.Warn(v...)
+	c.lastWarnings = append(c.lastWarnings, w)
+
+	return w
+}
+
+// Warnf will log a formatted warning and add it to the warnings
+func (c *CheckBase) Warnf(format string, params ...interface{}) error {
+	w := log.Warnf(format, params...)
+	c.lastWarnings = append(c.lastWarnings, w)
+
+	return w
+}
+
+// GetSender returns the sender of the check
+func (c *CheckBase) GetSender() (aggregator.Sender, error) {
+	return aggregator.GetSender(c.ID())
+}
+
+// GetMetricStats returns the stats from the last run of the check
+func (c *CheckBase) GetMetricStats() (map[string]int64, error) {
+	sender, err := c.GetSender()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve a Sender instance: %v", err)
+	}
+	return sender.GetMetricStats(), nil
+}
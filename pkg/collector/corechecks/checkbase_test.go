@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package corechecks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+func TestCheckBaseDefaults(t *testing.T) {
+	c := NewCheckBase("foo")
+	require.Nil(t, c.CommonConfigure(nil))
+
+	assert.Equal(t, "foo", c.String())
+	assert.Equal(t, check.ID("foo"), c.ID())
+	assert.Equal(t, check.DefaultCheckInterval, c.Interval())
+	assert.Nil(t, c.Tags())
+	assert.Nil(t, c.Schedule())
+	assert.Nil(t, c.RetryPolicy())
+}
+
+func TestCheckBaseCommonConfigure(t *testing.T) {
+	instance := check.ConfigData(`
+min_collection_interval: 60
+tags:
+  - env:prod
+  - role:db
+schedule: "@hourly"
+retry:
+  max_attempts: 2
+`)
+	c := NewCheckBase("foo")
+	require.Nil(t, c.CommonConfigure(instance))
+
+	assert.Equal(t, time.Minute, c.Interval())
+	assert.Equal(t, []string{"env:prod", "role:db"}, c.Tags())
+	require.NotNil(t, c.Schedule())
+	assert.Equal(t, "@hourly", c.Schedule().String())
+	require.NotNil(t, c.RetryPolicy())
+	assert.Equal(t, 2, c.RetryPolicy().MaxAttempts)
+
+	c.BuildID(instance, nil)
+	assert.Equal(t, check.BuildID("foo", instance, nil), c.ID())
+	assert.NotEqual(t, check.ID("foo"), c.ID())
+
+	err := c.CommonConfigure([]byte("min_collection_interval: -1"))
+	require.NotNil(t, err)
+	assert.Equal(t, "foo: min_collection_interval can't be negative", err.Error())
+
+	err = c.CommonConfigure([]byte("tags: env:prod"))
+	assert.NotNil(t, err)
+
+	err = c.CommonConfigure([]byte("schedule: never"))
+	assert.NotNil(t, err)
+}
+
+func TestCheckBaseWarnings(t *testing.T) {
+	c := NewCheckBase("foo")
+	c.Warn("first", " warning")
+	c.Warnf("warning #%d", 2)
+
+	warnings := c.GetWarnings()
+	require.Len(t, warnings, 2)
+	assert.Equal(t, "first warning", warnings[0].Error())
+	assert.Equal(t, "warning #2", warnings[1].Error())
+	assert.Len(t, c.GetWarnings(), 0)
+}
@@ -0,0 +1,171 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package corechecks
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// UnmarshalConfig parses the YAML `data` of an instance or an init_config
+// into `out`, a pointer to a struct. Once parsed, the fields missing from the
+// YAML are set to the value of their `default` struct tag and the fields with
+// a `required:"true"` struct tag must be set, for example:
+//
+//	type ntpConfig struct {
+//	    Host    string        `yaml:"host" required:"true"`
+//	    Port    string        `yaml:"port" default:"ntp"`
+//	    Timeout time.Duration `yaml:"timeout" default:"1s"`
+//	}
+//
+// An explicit zero value like `ssl: false` or `port: 0` is kept, a key set to
+// null is missing. Defaults are supported for strings, booleans, numbers,
+// durations (in the time.ParseDuration format) and string lists (comma
+// separated). Nested structs are handled the same way.
+func UnmarshalConfig(data check.ConfigData, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("can't unmarshal the configuration into %T, a pointer to a struct is expected", out)
+	}
+
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return err
+	}
+	// the keys set in the document, to tell them from the zero values
+	raw := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	return applySchema(v.Elem(), raw, "")
+}
+
+// applySchema sets the defaults and checks the required fields of a struct,
+// `raw` holds the keys of the struct in the YAML document and `prefix` is its
+// path
+func applySchema(v reflect.Value, raw map[interface{}]interface{}, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		value := v.Field(i)
+		key, inline := fieldName(field)
+		name := prefix + key
+		nestedRaw := raw
+		if inline {
+			name = strings.TrimSuffix(prefix, ".")
+		} else {
+			nestedRaw, _ = raw[key].(map[interface{}]interface{})
+		}
+
+		if !inline && raw[key] == nil {
+			if def, found := field.Tag.Lookup("default"); found {
+				if err := setValue(value, def); err != nil {
+					return fmt.Errorf("invalid default value %q for field %q: %s", def, name, err)
+				}
+			} else if field.Tag.Get("required") == "true" {
+				return fmt.Errorf("missing required field %q", name)
+			}
+		}
+
+		nested := prefix
+		if !inline {
+			nested = name + "."
+		}
+		switch {
+		case value.Kind() == reflect.Struct:
+			if err := applySchema(value, nestedRaw, nested); err != nil {
+				return err
+			}
+		case value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Struct:
+			if err := applySchema(value.Elem(), nestedRaw, nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fieldName returns the YAML key of a struct field and whether its fields
+// are inlined in the parent struct
+func fieldName(field reflect.StructField) (string, bool) {
+	opts := strings.Split(field.Tag.Get("yaml"), ",")
+	for _, opt := range opts[1:] {
+		if opt == "inline" {
+			return "", true
+		}
+	}
+	if opts[0] == "" {
+		// the yaml package default
+		return strings.ToLower(field.Name), false
+	}
+	return opts[0], false
+}
+
+// setValue parses `s` into `v` according to its type
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("defaults are not supported for %s", v.Type())
+		}
+		items := []string{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return fmt.Errorf("defaults are not supported for %s", v.Type())
+	}
+	return nil
+}
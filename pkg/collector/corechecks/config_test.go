@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package corechecks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAuthConfig struct {
+	User     string `yaml:"user" required:"true"`
+	Password string `yaml:"password"`
+}
+
+type testInstanceConfig struct {
+	CommonInstanceConfig `yaml:",inline"`
+	Host                 string          `yaml:"host" required:"true"`
+	Port                 int             `yaml:"port" default:"8080"`
+	Timeout              time.Duration   `yaml:"timeout" default:"5s"`
+	Ratio                float64         `yaml:"ratio" default:"0.5"`
+	SSL                  bool            `yaml:"ssl" default:"true"`
+	Excluded             []string        `yaml:"excluded" default:"foo, bar"`
+	Auth                 *testAuthConfig `yaml:"auth"`
+}
+
+func TestUnmarshalConfig(t *testing.T) {
+	conf := testInstanceConfig{}
+	err := UnmarshalConfig([]byte("host: localhost\nport: 9090\ntags: [\"foo:bar\"]\nmin_collection_interval: 30"), &conf)
+	require.Nil(t, err)
+	assert.Equal(t, "localhost", conf.Host)
+	assert.Equal(t, 9090, conf.Port)
+	assert.Equal(t, 5*time.Second, conf.Timeout)
+	assert.Equal(t, 0.5, conf.Ratio)
+	assert.True(t, conf.SSL)
+	assert.Equal(t, []string{"foo", "bar"}, conf.Excluded)
+	assert.Nil(t, conf.Auth)
+	assert.Equal(t, []string{"foo:bar"}, conf.Tags)
+	assert.Equal(t, 30, conf.MinCollectionInterval)
+
+	// values set in the YAML are kept
+	conf = testInstanceConfig{}
+	err = UnmarshalConfig([]byte("host: localhost\ntimeout: 1m\nexcluded: [baz]\nauth:\n  user: admin"), &conf)
+	require.Nil(t, err)
+	assert.Equal(t, time.Minute, conf.Timeout)
+	assert.Equal(t, []string{"baz"}, conf.Excluded)
+	require.NotNil(t, conf.Auth)
+	assert.Equal(t, "admin", conf.Auth.User)
+
+	// explicit zero values are kept, null ones get the default
+	conf = testInstanceConfig{}
+	err = UnmarshalConfig([]byte("host: localhost\nport: 0\nratio: 0\nssl: false\nexcluded: []\ntimeout:"), &conf)
+	require.Nil(t, err)
+	assert.Equal(t, 0, conf.Port)
+	assert.Equal(t, 0.0, conf.Ratio)
+	assert.False(t, conf.SSL)
+	assert.Empty(t, conf.Excluded)
+	assert.Equal(t, 5*time.Second, conf.Timeout)
+
+	// a required field set to a zero value is present
+	conf = testInstanceConfig{}
+	err = UnmarshalConfig([]byte("host: \"\"\nauth:\n  user: \"\""), &conf)
+	require.Nil(t, err)
+	assert.Equal(t, "", conf.Host)
+	assert.Equal(t, "", conf.Auth.User)
+}
+
+func TestUnmarshalConfigErrors(t *testing.T) {
+	conf := testInstanceConfig{}
+	err := UnmarshalConfig([]byte("port: 9090"), &conf)
+	require.NotNil(t, err)
+	assert.Equal(t, `missing required field "host"`, err.Error())
+
+	err = UnmarshalConfig([]byte("host: ~"), &conf)
+	require.NotNil(t, err)
+	assert.Equal(t, `missing required field "host"`, err.Error())
+
+	err = UnmarshalConfig([]byte("host: localhost\nauth:\n  password: secret"), &conf)
+	require.NotNil(t, err)
+	assert.Equal(t, `missing required field "auth.user"`, err.Error())
+
+	err = UnmarshalConfig([]byte("host: [localhost"), &conf)
+	assert.NotNil(t, err)
+
+	err = UnmarshalConfig([]byte("host: localhost"), conf)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "a pointer to a struct is expected")
+
+	invalid := struct {
+		Port int `yaml:"port" default:"http"`
+	}{}
+	err = UnmarshalConfig(nil, &invalid)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), `invalid default value "http" for field "port"`)
+}
@@ -7,9 +7,11 @@ package corechecks
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/collector/loaders"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	log "github.com/cihub/seelog"
 )
 
@@ -56,15 +58,21 @@ func (gl *GoCheckLoader) Load(config check.Config) ([]check.Check, error) {
 		return checks, fmt.Errorf(msg)
 	}
 
+	errs := []string{}
 	for _, instance := range config.Instances {
 		newCheck := factory()
 		if err := newCheck.Configure(instance, config.InitConfig); err != nil {
-			log.Errorf("core.loader: could not configure check %s: %s", newCheck, err)
+			// the instance was decrypted, don't log its secrets
+			log.Errorf("core.loader: could not configure check %s: %s", newCheck, secrets.Scrub(err.Error()))
+			errs = append(errs, err.Error())
 			continue
 		}
 		checks = append(checks, newCheck)
 	}
 
+	if len(checks) == 0 && len(errs) > 0 {
+		return checks, fmt.Errorf("could not configure any instance of %s: %s", config.Name, strings.Join(errs, "; "))
+	}
 	return checks, nil
 }
 
@@ -6,6 +6,7 @@
 package corechecks
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -24,6 +25,15 @@ func (c *TestCheck) ID() check.ID                                       { return
 func (c *TestCheck) GetWarnings() []error                               { return []error{} }
 func (c *TestCheck) GetMetricStats() (map[string]int64, error)          { return make(map[string]int64), nil }
 
+type failingCheck struct{ TestCheck }
+
+func (c *failingCheck) Configure(data check.ConfigData, initConfig check.ConfigData) error {
+	if string(data) == "fail: true" {
+		return errors.New("bad instance")
+	}
+	return nil
+}
+
 func TestNewGoCheckLoader(t *testing.T) {
 	if checkLoader, _ := NewGoCheckLoader(); checkLoader == nil {
 		t.Fatal("Expected loader instance, found: nil")
@@ -87,3 +97,28 @@ func TestLoad(t *testing.T) {
 		t.Fatalf("Expected 0 checks, found: %d", len(lst))
 	}
 }
+
+func TestLoadConfigureErrors(t *testing.T) {
+	RegisterCheck("failing", func() check.Check { return &failingCheck{} })
+	l, _ := NewGoCheckLoader()
+
+	// the instances that can be configured are loaded
+	cc := check.Config{Name: "failing", Instances: []check.ConfigData{
+		check.ConfigData("fail: true"),
+		check.ConfigData("fail: false"),
+	}}
+	lst, err := l.Load(cc)
+	if err != nil {
+		t.Fatalf("Expected nil error, found: %v", err)
+	}
+	if len(lst) != 1 {
+		t.Fatalf("Expected 1 check, found: %d", len(lst))
+	}
+
+	// none of them can be configured
+	cc.Instances = cc.Instances[:1]
+	_, err = l.Load(cc)
+	if err == nil || err.Error() != "could not configure any instance of failing: bad instance" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
@@ -31,20 +31,16 @@ import (
 	"time"
 	"unsafe"
 
-	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
 	"github.com/DataDog/datadog-agent/pkg/util"
 
 	log "github.com/cihub/seelog"
 	"github.com/k-sone/snmpgo"
-
-	"gopkg.in/yaml.v2"
 )
 
 const (
 	maxOIDLen      = 128
-	defaultPort    = 161
 	nonRepeaters   = 0
 	maxRepetitions = 10
 )
@@ -70,8 +66,8 @@ type metric struct {
 }
 
 type snmpInstanceCfg struct {
-	Host            string                  `yaml:"ip_address"`
-	Port            uint                    `yaml:"port"`
+	Host            string                  `yaml:"ip_address" required:"true"`
+	Port            uint                    `yaml:"port" default:"161"`
 	User            string                  `yaml:"user,omitempty"`
 	Community       string                  `yaml:"community_string,omitempty"`
 	Version         int                     `yaml:"snmp_version,omitempty"`
@@ -104,13 +100,8 @@ type snmpConfig struct {
 
 // SNMPCheck grabs SNMP metrics
 type SNMPCheck struct {
-	id           check.ID
-	lastWarnings []error
-	cfg          *snmpConfig
-}
-
-func (c *SNMPCheck) String() string {
-	return "snmp"
+	core.CheckBase
+	cfg *snmpConfig
 }
 
 func initCNetSnmpLib(cfg *snmpInitCfg) (err error) {
@@ -405,24 +396,16 @@ func (c *snmpConfig) Parse(data []byte, initData []byte) error {
 	var instance snmpInstanceCfg
 	var initConf snmpInitCfg
 
-	if err := yaml.Unmarshal(data, &instance); err != nil {
+	if err := core.UnmarshalConfig(data, &instance); err != nil {
 		return err
 	}
-	if err := yaml.Unmarshal(initData, &initConf); err != nil {
+	if err := core.UnmarshalConfig(initData, &initConf); err != nil {
 		return err
 	}
 
 	c.instance = instance
 	c.initConf = initConf
 
-	if c.instance.Port == 0 {
-		c.instance.Port = defaultPort
-	}
-
-	if c.instance.Host == "" {
-		return errors.New("error parsing configuration - invalid SNMP instance configuration")
-	}
-
 	//build instance tag
 	tagbuff.Reset()
 	tagbuff.WriteString("snmp_device:")
@@ -456,7 +439,7 @@ func (c *snmpConfig) Parse(data []byte, initData []byte) error {
 }
 
 func (c *SNMPCheck) submitSNMP(oids snmpgo.Oids, vbs snmpgo.VarBinds) error {
-	sender, err := aggregator.GetSender(c.id)
+	sender, err := c.GetSender()
 	if err != nil {
 		return err
 	}
@@ -648,6 +631,9 @@ func (c *SNMPCheck) getSNMP() error {
 
 // Configure the check from YAML data
 func (c *SNMPCheck) Configure(data check.ConfigData, initConfig check.ConfigData) error {
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
 
 	cfg := new(snmpConfig)
 	err := cfg.Parse(data, initConfig)
@@ -655,7 +641,7 @@ func (c *SNMPCheck) Configure(data check.ConfigData, initConfig check.ConfigData
 		log.Criticalf("Error parsing configuration file: %s ", err)
 		return err
 	}
-	c.id = check.Identify(c, data, initConfig)
+	c.BuildID(data, initConfig)
 	c.cfg = cfg
 
 	//init SNMP - will fail if missing snmp libs.
@@ -718,28 +704,6 @@ func (c *SNMPCheck) Configure(data check.ConfigData, initConfig check.ConfigData
 	return nil
 }
 
-// Interval returns the scheduling time for the check
-func (c *SNMPCheck) Interval() time.Duration {
-	return check.DefaultCheckInterval
-}
-
-// ID FIXME: this should return a real identifier
-func (c *SNMPCheck) ID() check.ID {
-	return c.id
-}
-
-// GetMetricStats returns the stats from the last run of the check
-func (c *SNMPCheck) GetMetricStats() (map[string]int64, error) {
-	sender, err := aggregator.GetSender(c.ID())
-	if err != nil {
-		return nil, fmt.Errorf("Failed to retrieve a Sender instance: %v", err)
-	}
-	return sender.GetMetricStats(), nil
-}
-
-// Stop does nothing
-func (c *SNMPCheck) Stop() {}
-
 // Run runs the check
 func (c *SNMPCheck) Run() error {
 
@@ -751,31 +715,10 @@ func (c *SNMPCheck) Run() error {
 	return nil
 }
 
-// GetWarnings grabs the last warnings from the sender
-func (c *SNMPCheck) GetWarnings() []error {
-	w := c.lastWarnings
-	c.lastWarnings = []error{}
-	return w
-}
-
-// Warn will log a warning and add it to the warnings
-func (c *SNMPCheck) warn(v ...interface{}) error {
-	w := log.Warn(v)
-	c.lastWarnings = append(c.lastWarnings, w)
-
-	return w
-}
-
-// Warnf will log a formatted warning and add it to the warnings
-func (c *SNMPCheck) warnf(format string, params ...interface{}) error {
-	w := log.Warnf(format, params)
-	c.lastWarnings = append(c.lastWarnings, w)
-
-	return w
-}
-
 func snmpFactory() check.Check {
-	return &SNMPCheck{}
+	return &SNMPCheck{
+		CheckBase: core.NewCheckBase("snmp"),
+	}
 }
 
 func init() {
@@ -191,7 +191,7 @@ func TestConfigureV3(t *testing.T) {
 }
 
 func TestSubmitSNMP(t *testing.T) {
-	snmpCheck := new(SNMPCheck)
+	snmpCheck := snmpFactory().(*SNMPCheck)
 	cfg := new(snmpConfig)
 
 	initCNetSnmpLib(nil)
@@ -0,0 +1,11 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+/*
+Package prometheus provides a core check scraping endpoints exposing metrics
+in the Prometheus text or OpenMetrics formats
+
+*/
+package prometheus
@@ -0,0 +1,217 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Metric family types, as declared in the `# TYPE` lines
+const (
+	counterType   = "counter"
+	gaugeType     = "gauge"
+	histogramType = "histogram"
+	summaryType   = "summary"
+	untypedType   = "untyped"
+)
+
+// sample is a single line of the exposition format
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// metricFamily groups the samples of a metric
+type metricFamily struct {
+	name    string
+	mType   string
+	samples []sample
+}
+
+// parser reads the Prometheus text format (version 0.0.4) and the
+// OpenMetrics text format into metric families
+type parser struct {
+	families []*metricFamily
+	byName   map[string]*metricFamily
+}
+
+// parse reads all the metric families from r, in order of appearance
+func parse(r io.Reader) ([]*metricFamily, error) {
+	p := &parser{byName: make(map[string]*metricFamily)}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line == "# EOF" {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			p.parseComment(line)
+			continue
+		}
+		if err := p.parseSample(line); err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return p.families, nil
+}
+
+// family returns the family called name, creating it if needed
+func (p *parser) family(name, mType string) *metricFamily {
+	if f, found := p.byName[name]; found {
+		return f
+	}
+	f := &metricFamily{name: name, mType: mType}
+	p.byName[name] = f
+	p.families = append(p.families, f)
+	return f
+}
+
+// parseComment handles the `# TYPE` lines, `# HELP`, `# UNIT` and free
+// comments are ignored
+func (p *parser) parseComment(line string) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[1] != "TYPE" {
+		return
+	}
+	mType := strings.ToLower(fields[3])
+	switch mType {
+	case counterType, gaugeType, histogramType, summaryType:
+	default:
+		mType = untypedType
+	}
+	f := p.family(fields[2], mType)
+	f.mType = mType
+}
+
+// familyForSample returns the family a sample belongs to, histograms and
+// summaries samples are suffixed with _bucket, _sum and _count, OpenMetrics
+// counters with _total and _created
+func (p *parser) familyForSample(name string) *metricFamily {
+	if f, found := p.byName[name]; found {
+		return f
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_total", "_created"} {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		if f, found := p.byName[strings.TrimSuffix(name, suffix)]; found && f.mType != untypedType && f.mType != gaugeType {
+			return f
+		}
+	}
+	return p.family(name, untypedType)
+}
+
+func (p *parser) parseSample(line string) error {
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd <= 0 {
+		return fmt.Errorf("invalid sample: %q", line)
+	}
+	s := sample{name: line[:nameEnd]}
+	rest := line[nameEnd:]
+
+	if rest[0] == '{' {
+		labels, remaining, err := parseLabels(rest[1:])
+		if err != nil {
+			return err
+		}
+		s.labels = labels
+		rest = remaining
+	}
+
+	// the value can be followed by a timestamp, which is ignored
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return fmt.Errorf("missing value for sample %s", s.name)
+	}
+	value, err := parseValue(fields[0])
+	if err != nil {
+		return fmt.Errorf("invalid value for sample %s: %s", s.name, err)
+	}
+	s.value = value
+
+	f := p.familyForSample(s.name)
+	// the creation timestamp of OpenMetrics counters isn't a metric
+	if strings.HasSuffix(s.name, "_created") && f.name != s.name {
+		return nil
+	}
+	f.samples = append(f.samples, s)
+	return nil
+}
+
+// parseLabels parses the label set following the opening brace, and returns
+// the rest of the line after the closing brace
+func parseLabels(s string) (map[string]string, string, error) {
+	labels := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return nil, "", fmt.Errorf("unterminated label set")
+		}
+		if s[0] == '}' {
+			return labels, s[1:], nil
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, "", fmt.Errorf("invalid label set")
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t")
+		if s == "" || s[0] != '"' {
+			return nil, "", fmt.Errorf("label %s: value must be quoted", name)
+		}
+
+		var value []byte
+		i := 1
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value = append(value, '\n')
+				default:
+					value = append(value, s[i])
+				}
+				continue
+			}
+			value = append(value, s[i])
+		}
+		if i >= len(s) {
+			return nil, "", fmt.Errorf("label %s: unterminated value", name)
+		}
+		labels[name] = string(value)
+		s = s[i+1:]
+	}
+}
+
+func parseValue(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN", "Nan":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package prometheus
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const promText = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"}    3 1395066363000
+
+# A free comment
+msdos_file_access_time_seconds{path="C:\\DIR\\FILE.TXT",error="Cannot find file:\n\"FILE.TXT\""} 1.458255915e9
+
+# TYPE go_goroutines gauge
+go_goroutines 42
+
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{le="0.05"} 24054
+http_request_duration_seconds_bucket{le="+Inf"} 144320
+http_request_duration_seconds_sum 53423
+http_request_duration_seconds_count 144320
+
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 4773
+rpc_duration_seconds{quantile="0.99"} NaN
+rpc_duration_seconds_sum 1.7560473e+07
+rpc_duration_seconds_count 2693
+`
+
+const openMetricsText = `# TYPE acme_http_router_request counter
+# HELP acme_http_router_request Total number of requests
+acme_http_router_request_total{path="/api/v1",method="GET"} 1366
+acme_http_router_request_created{path="/api/v1",method="GET"} 1.605281325e+09
+# TYPE temperature gauge
+# UNIT temperature celsius
+temperature 21.5
+# EOF
+ignored_after_eof 1
+`
+
+func TestParsePrometheus(t *testing.T) {
+	families, err := parse(strings.NewReader(promText))
+	require.NoError(t, err)
+	require.Len(t, families, 5)
+
+	assert.Equal(t, "http_requests_total", families[0].name)
+	assert.Equal(t, counterType, families[0].mType)
+	require.Len(t, families[0].samples, 2)
+	assert.Equal(t, 3.0, families[0].samples[1].value)
+	assert.Equal(t, map[string]string{"method": "post", "code": "400"}, families[0].samples[1].labels)
+
+	assert.Equal(t, "msdos_file_access_time_seconds", families[1].name)
+	assert.Equal(t, untypedType, families[1].mType)
+	assert.Equal(t, `C:\DIR\FILE.TXT`, families[1].samples[0].labels["path"])
+	assert.Equal(t, "Cannot find file:\n\"FILE.TXT\"", families[1].samples[0].labels["error"])
+	assert.Equal(t, 1.458255915e9, families[1].samples[0].value)
+
+	assert.Equal(t, gaugeType, families[2].mType)
+	assert.Nil(t, families[2].samples[0].labels)
+
+	assert.Equal(t, histogramType, families[3].mType)
+	require.Len(t, families[3].samples, 4)
+	assert.Equal(t, "+Inf", families[3].samples[1].labels["le"])
+	assert.Equal(t, "http_request_duration_seconds_count", families[3].samples[3].name)
+
+	assert.Equal(t, summaryType, families[4].mType)
+	require.Len(t, families[4].samples, 4)
+	assert.True(t, math.IsNaN(families[4].samples[1].value))
+}
+
+func TestParseOpenMetrics(t *testing.T) {
+	families, err := parse(strings.NewReader(openMetricsText))
+	require.NoError(t, err)
+	require.Len(t, families, 2)
+
+	assert.Equal(t, "acme_http_router_request", families[0].name)
+	assert.Equal(t, counterType, families[0].mType)
+	require.Len(t, families[0].samples, 1)
+	assert.Equal(t, "acme_http_router_request_total", families[0].samples[0].name)
+	assert.Equal(t, 1366.0, families[0].samples[0].value)
+
+	assert.Equal(t, "temperature", families[1].name)
+	assert.Equal(t, 21.5, families[1].samples[0].value)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		`metric{label="value"`,
+		`metric{label=value} 1`,
+		`metric{label="value} 1`,
+		`metric`,
+		`metric one`,
+	} {
+		_, err := parse(strings.NewReader(text))
+		assert.Error(t, err, text)
+	}
+}
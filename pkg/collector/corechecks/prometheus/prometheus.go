@@ -0,0 +1,308 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	checkName        = "prometheus"
+	serviceCheckName = "prometheus.can_connect"
+	acceptHeader     = "application/openmetrics-text; version=1.0.0,text/plain; version=0.0.4; q=0.5,*/*; q=0.1"
+)
+
+// Check scrapes a Prometheus/OpenMetrics endpoint
+type Check struct {
+	core.CheckBase
+	cfg    *promConfig
+	client *http.Client
+}
+
+type promInstanceConfig struct {
+	URL                   string            `yaml:"prometheus_url" required:"true"`
+	Namespace             string            `yaml:"namespace"`
+	Metrics               []interface{}     `yaml:"metrics"`
+	LabelsMapper          map[string]string `yaml:"labels_mapper"`
+	ExcludeLabels         []string          `yaml:"exclude_labels"`
+	TypeOverrides         map[string]string `yaml:"type_overrides"`
+	SendHistogramsBuckets bool              `yaml:"send_histograms_buckets" default:"true"`
+	Timeout               int               `yaml:"timeout" default:"10"`
+}
+
+type promInitConfig struct{}
+
+// metricMatcher selects the scraped metrics with a glob pattern, an
+// optional alias renames the matching metric
+type metricMatcher struct {
+	pattern string
+	alias   string
+}
+
+type promConfig struct {
+	instance       promInstanceConfig
+	initConf       promInitConfig
+	matchers       []metricMatcher
+	excludeLabels  map[string]bool
+	sendHistBucket bool
+}
+
+func (c *promConfig) Parse(data []byte, initData []byte) error {
+	var instance promInstanceConfig
+	var initConf promInitConfig
+
+	if err := core.UnmarshalConfig(data, &instance); err != nil {
+		return err
+	}
+
+	if err := core.UnmarshalConfig(initData, &initConf); err != nil {
+		return err
+	}
+
+	if len(instance.Metrics) == 0 {
+		return fmt.Errorf("missing metrics, use '*' to collect every metric")
+	}
+	if instance.Timeout <= 0 {
+		return fmt.Errorf("invalid timeout %d, it must be positive", instance.Timeout)
+	}
+
+	matchers, err := parseMatchers(instance.Metrics)
+	if err != nil {
+		return err
+	}
+
+	for name, mType := range instance.TypeOverrides {
+		switch mType {
+		case counterType, gaugeType, histogramType, summaryType, untypedType:
+		default:
+			return fmt.Errorf("invalid type override %q for metric %s", mType, name)
+		}
+	}
+
+	c.instance = instance
+	c.initConf = initConf
+	c.matchers = matchers
+	c.excludeLabels = make(map[string]bool, len(instance.ExcludeLabels))
+	for _, l := range instance.ExcludeLabels {
+		c.excludeLabels[l] = true
+	}
+	c.sendHistBucket = instance.SendHistogramsBuckets
+
+	return nil
+}
+
+// parseMatchers reads the `metrics` list, each entry is either a pattern
+// or a map of patterns to aliases
+func parseMatchers(entries []interface{}) ([]metricMatcher, error) {
+	matchers := []metricMatcher{}
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case string:
+			matchers = append(matchers, metricMatcher{pattern: e})
+		case map[interface{}]interface{}:
+			for pattern, alias := range e {
+				matchers = append(matchers, metricMatcher{pattern: fmt.Sprint(pattern), alias: fmt.Sprint(alias)})
+			}
+		default:
+			return nil, fmt.Errorf("invalid metrics entry: %v", entry)
+		}
+	}
+	for _, m := range matchers {
+		if _, err := path.Match(m.pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid metrics pattern %q: %s", m.pattern, err)
+		}
+	}
+	return matchers, nil
+}
+
+// metricName returns the name under which the family is submitted, and
+// false if the family isn't in the allowlist
+func (c *promConfig) metricName(family string) (string, bool) {
+	for _, m := range c.matchers {
+		if ok, _ := path.Match(m.pattern, family); !ok {
+			continue
+		}
+		name := family
+		if m.alias != "" {
+			name = m.alias
+		}
+		if c.instance.Namespace != "" {
+			name = c.instance.Namespace + "." + name
+		}
+		return name, true
+	}
+	return "", false
+}
+
+// tags returns the instance tags and the sample labels mapped to tags,
+// ignoring the labels given in skip
+func (c *promConfig) tags(instanceTags []string, labels map[string]string, skip string) []string {
+	tags := make([]string, 0, len(instanceTags)+len(labels))
+	tags = append(tags, instanceTags...)
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if name == skip || c.excludeLabels[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tagName := name
+		if mapped, found := c.instance.LabelsMapper[name]; found {
+			tagName = mapped
+		}
+		tags = append(tags, tagName+":"+labels[name])
+	}
+	return tags
+}
+
+// Configure configure the data from the yaml
+func (c *Check) Configure(data check.ConfigData, initConfig check.ConfigData) error {
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+
+	cfg := new(promConfig)
+	err := cfg.Parse(data, initConfig)
+	if err != nil {
+		log.Criticalf("Error parsing configuration file: %s", err)
+		return err
+	}
+
+	c.BuildID(data, initConfig)
+	c.cfg = cfg
+	c.client = &http.Client{Timeout: time.Duration(cfg.instance.Timeout) * time.Second}
+
+	return nil
+}
+
+// Run runs the check
+func (c *Check) Run() error {
+	sender, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	scTags := append([]string{"url:" + c.cfg.instance.URL}, c.Tags()...)
+
+	families, err := c.scrape()
+	if err != nil {
+		sender.ServiceCheck(serviceCheckName, metrics.ServiceCheckCritical, "", scTags, err.Error())
+		sender.Commit()
+		return err
+	}
+	sender.ServiceCheck(serviceCheckName, metrics.ServiceCheckOK, "", scTags, "")
+
+	for _, family := range families {
+		name, found := c.cfg.metricName(family.name)
+		if !found {
+			continue
+		}
+		mType := family.mType
+		if override, found := c.cfg.instance.TypeOverrides[family.name]; found {
+			mType = override
+		}
+		c.submitFamily(sender, name, mType, family)
+	}
+
+	sender.Commit()
+
+	return nil
+}
+
+func (c *Check) scrape() ([]*metricFamily, error) {
+	req, err := http.NewRequest("GET", c.cfg.instance.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", acceptHeader)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("can't scrape %s: %s", c.cfg.instance.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("can't scrape %s: unexpected status code %d", c.cfg.instance.URL, resp.StatusCode)
+	}
+	if strings.Contains(resp.Header.Get("Content-Type"), "application/vnd.google.protobuf") {
+		return nil, fmt.Errorf("can't scrape %s: the protobuf format is not supported", c.cfg.instance.URL)
+	}
+
+	families, err := parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse the response of %s: %s", c.cfg.instance.URL, err)
+	}
+	return families, nil
+}
+
+// submitFamily maps the samples of a family onto the sender: counters are
+// monotonic counts, gauges and untyped metrics are gauges, histograms and
+// summaries give a monotonic count and sum plus their buckets or quantiles
+func (c *Check) submitFamily(sender aggregator.Sender, name, mType string, family *metricFamily) {
+	for _, s := range family.samples {
+		if math.IsNaN(s.value) {
+			continue
+		}
+
+		switch mType {
+		case counterType:
+			sender.MonotonicCount(name, s.value, "", c.cfg.tags(c.Tags(), s.labels, ""))
+		case histogramType, summaryType:
+			c.submitDistributionSample(sender, name, mType, family.name, s)
+		default:
+			sender.Gauge(name, s.value, "", c.cfg.tags(c.Tags(), s.labels, ""))
+		}
+	}
+}
+
+func (c *Check) submitDistributionSample(sender aggregator.Sender, name, mType, family string, s sample) {
+	switch s.name {
+	case family + "_count":
+		sender.MonotonicCount(name+".count", s.value, "", c.cfg.tags(c.Tags(), s.labels, ""))
+	case family + "_sum":
+		sender.MonotonicCount(name+".sum", s.value, "", c.cfg.tags(c.Tags(), s.labels, ""))
+	case family + "_bucket":
+		if !c.cfg.sendHistBucket {
+			return
+		}
+		tags := append(c.cfg.tags(c.Tags(), s.labels, "le"), "upper_bound:"+s.labels["le"])
+		sender.MonotonicCount(name+".bucket", s.value, "", tags)
+	default:
+		if mType != summaryType {
+			return
+		}
+		tags := append(c.cfg.tags(c.Tags(), s.labels, "quantile"), "quantile:"+s.labels["quantile"])
+		sender.Gauge(name+".quantile", s.value, "", tags)
+	}
+}
+
+func prometheusFactory() check.Check {
+	return &Check{
+		CheckBase: core.NewCheckBase(checkName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(checkName, prometheusFactory)
+}
@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg := new(promConfig)
+	err := cfg.Parse([]byte("metrics: ['*']"), nil)
+	assert.Error(t, err)
+
+	err = cfg.Parse([]byte("prometheus_url: http://localhost:9090/metrics"), nil)
+	assert.Error(t, err)
+
+	err = cfg.Parse([]byte(`
+prometheus_url: http://localhost:9090/metrics
+metrics: ['*']
+type_overrides:
+  foo: bar
+`), nil)
+	assert.Error(t, err)
+
+	err = cfg.Parse([]byte(`
+prometheus_url: http://localhost:9090/metrics
+metrics: ['*']
+timeout: 0
+`), nil)
+	assert.Error(t, err)
+
+	err = cfg.Parse([]byte(`
+prometheus_url: http://localhost:9090/metrics
+namespace: myapp
+metrics:
+  - http_*
+  - go_goroutines: goroutines
+send_histograms_buckets: false
+`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.instance.Timeout)
+	assert.False(t, cfg.sendHistBucket)
+
+	name, found := cfg.metricName("http_requests_total")
+	assert.True(t, found)
+	assert.Equal(t, "myapp.http_requests_total", name)
+	name, found = cfg.metricName("go_goroutines")
+	assert.True(t, found)
+	assert.Equal(t, "myapp.goroutines", name)
+	_, found = cfg.metricName("process_open_fds")
+	assert.False(t, found)
+}
+
+func TestRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, promText)
+	}))
+	defer ts.Close()
+
+	promCheck := prometheusFactory().(*Check)
+	err := promCheck.Configure([]byte(fmt.Sprintf(`
+min_collection_interval: 30
+prometheus_url: %s
+namespace: test
+metrics:
+  - http_*
+  - rpc_duration_seconds
+  - go_goroutines
+labels_mapper:
+  code: status_code
+exclude_labels:
+  - method
+type_overrides:
+  go_goroutines: counter
+tags:
+  - foo:bar
+`, ts.URL)), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, promCheck.Interval())
+
+	mockSender := aggregator.NewMockSender(promCheck.ID())
+	scTags := []string{"url:" + ts.URL, "foo:bar"}
+	mockSender.On("ServiceCheck", serviceCheckName, metrics.ServiceCheckOK, "", scTags, "").Return().Times(1)
+	mockSender.On("MonotonicCount", "test.http_requests_total", 1027.0, "", []string{"foo:bar", "status_code:200"}).Return().Times(1)
+	mockSender.On("MonotonicCount", "test.http_requests_total", 3.0, "", []string{"foo:bar", "status_code:400"}).Return().Times(1)
+	mockSender.On("MonotonicCount", "test.go_goroutines", 42.0, "", []string{"foo:bar"}).Return().Times(1)
+	mockSender.On("MonotonicCount", "test.http_request_duration_seconds.bucket", 24054.0, "", []string{"foo:bar", "upper_bound:0.05"}).Return().Times(1)
+	mockSender.On("MonotonicCount", "test.http_request_duration_seconds.bucket", 144320.0, "", []string{"foo:bar", "upper_bound:+Inf"}).Return().Times(1)
+	mockSender.On("MonotonicCount", "test.http_request_duration_seconds.sum", 53423.0, "", []string{"foo:bar"}).Return().Times(1)
+	mockSender.On("MonotonicCount", "test.http_request_duration_seconds.count", 144320.0, "", []string{"foo:bar"}).Return().Times(1)
+	mockSender.On("Gauge", "test.rpc_duration_seconds.quantile", 4773.0, "", []string{"foo:bar", "quantile:0.5"}).Return().Times(1)
+	mockSender.On("MonotonicCount", "test.rpc_duration_seconds.sum", 1.7560473e+07, "", []string{"foo:bar"}).Return().Times(1)
+	mockSender.On("MonotonicCount", "test.rpc_duration_seconds.count", 2693.0, "", []string{"foo:bar"}).Return().Times(1)
+	mockSender.On("Commit").Return().Times(1)
+
+	err = promCheck.Run()
+	require.NoError(t, err)
+
+	mockSender.AssertExpectations(t)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 1)
+	mockSender.AssertNumberOfCalls(t, "MonotonicCount", 9)
+}
+
+func TestRunError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	promCheck := prometheusFactory().(*Check)
+	err := promCheck.Configure([]byte(fmt.Sprintf("prometheus_url: %s\nmetrics: ['*']", ts.URL)), nil)
+	require.NoError(t, err)
+
+	mockSender := aggregator.NewMockSender(promCheck.ID())
+	mockSender.On("ServiceCheck", serviceCheckName, metrics.ServiceCheckCritical, "", []string{"url:" + ts.URL}, mock.AnythingOfType("string")).Return().Times(1)
+	mockSender.On("Commit").Return().Times(1)
+
+	err = promCheck.Run()
+	assert.Error(t, err)
+	mockSender.AssertExpectations(t)
+}
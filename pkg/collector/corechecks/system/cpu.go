@@ -7,14 +7,11 @@ package system
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
 	log "github.com/cihub/seelog"
 	"github.com/shirou/gopsutil/cpu"
-
-	"github.com/DataDog/datadog-agent/pkg/aggregator"
 )
 
 // For testing purpose
@@ -23,19 +20,15 @@ var cpuInfo = cpu.Info
 
 // CPUCheck doesn't need additional fields
 type CPUCheck struct {
-	nbCPU        float64
-	lastNbCycle  float64
-	lastWarnings []error
-	lastTimes    cpu.TimesStat
-}
-
-func (c *CPUCheck) String() string {
-	return "cpu"
+	core.CheckBase
+	nbCPU       float64
+	lastNbCycle float64
+	lastTimes   cpu.TimesStat
 }
 
 // Run executes the check
 func (c *CPUCheck) Run() error {
-	sender, err := aggregator.GetSender(c.ID())
+	sender, err := c.GetSender()
 	if err != nil {
 		return err
 	}
@@ -64,12 +57,12 @@ func (c *CPUCheck) Run() error {
 		stolen := (t.Stolen - c.lastTimes.Stolen) / c.nbCPU
 		guest := (t.Guest - c.lastTimes.Guest) / c.nbCPU
 
-		sender.Gauge("system.cpu.user", user*toPercent, "", nil)
-		sender.Gauge("system.cpu.system", system*toPercent, "", nil)
-		sender.Gauge("system.cpu.iowait", iowait*toPercent, "", nil)
-		sender.Gauge("system.cpu.idle", idle*toPercent, "", nil)
-		sender.Gauge("system.cpu.stolen", stolen*toPercent, "", nil)
-		sender.Gauge("system.cpu.guest", guest*toPercent, "", nil)
+		sender.Gauge("system.cpu.user", user*toPercent, "", c.Tags())
+		sender.Gauge("system.cpu.system", system*toPercent, "", c.Tags())
+		sender.Gauge("system.cpu.iowait", iowait*toPercent, "", c.Tags())
+		sender.Gauge("system.cpu.idle", idle*toPercent, "", c.Tags())
+		sender.Gauge("system.cpu.stolen", stolen*toPercent, "", c.Tags())
+		sender.Gauge("system.cpu.guest", guest*toPercent, "", c.Tags())
 		sender.Commit()
 	}
 
@@ -80,7 +73,10 @@ func (c *CPUCheck) Run() error {
 
 // Configure the CPU check doesn't need configuration
 func (c *CPUCheck) Configure(data check.ConfigData, initConfig check.ConfigData) error {
-	// do nothing
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+
 	info, err := cpuInfo()
 	if err != nil {
 		return fmt.Errorf("system.CPUCheck: could not query CPU info")
@@ -91,53 +87,10 @@ func (c *CPUCheck) Configure(data check.ConfigData, initConfig check.ConfigData)
 	return nil
 }
 
-// Interval returns the scheduling time for the check
-func (c *CPUCheck) Interval() time.Duration {
-	return check.DefaultCheckInterval
-}
-
-// ID returns the name of the check since there should be only one instance running
-func (c *CPUCheck) ID() check.ID {
-	return check.ID(c.String())
-}
-
-// Stop does nothing
-func (c *CPUCheck) Stop() {}
-
-// GetWarnings grabs the last warnings from the sender
-func (c *CPUCheck) GetWarnings() []error {
-	w := c.lastWarnings
-	c.lastWarnings = []error{}
-	return w
-}
-
-// Warn will log a warning and add it to the warnings
-func (c *CPUCheck) warn(v ...interface{}) error {
-	w := log.Warn(v)
-	c.lastWarnings = append(c.lastWarnings, w)
-
-	return w
-}
-
-// Warnf will log a formatted warning and add it to the warnings
-func (c *CPUCheck) warnf(format string, params ...interface{}) error {
-	w := log.Warnf(format, params)
-	c.lastWarnings = append(c.lastWarnings, w)
-
-	return w
-}
-
-// GetMetricStats returns the stats from the last run of the check
-func (c *CPUCheck) GetMetricStats() (map[string]int64, error) {
-	sender, err := aggregator.GetSender(c.ID())
-	if err != nil {
-		return nil, fmt.Errorf("Failed to retrieve a Sender instance: %v", err)
-	}
-	return sender.GetMetricStats(), nil
-}
-
 func cpuFactory() check.Check {
-	return &CPUCheck{}
+	return &CPUCheck{
+		CheckBase: core.NewCheckBase("cpu"),
+	}
 }
 
 func init() {
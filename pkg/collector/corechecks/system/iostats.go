@@ -6,18 +6,13 @@
 package system
 
 import (
-	"fmt"
 	"regexp"
-	"time"
 
-	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
 
 	log "github.com/cihub/seelog"
 	"github.com/shirou/gopsutil/disk"
-
-	"gopkg.in/yaml.v2"
 )
 
 const (
@@ -29,73 +24,29 @@ const (
 // For testing purpose
 var ioCounters = disk.IOCounters
 
-func (c *IOCheck) String() string {
-	return "io"
+type ioInitConfig struct {
+	DeviceBlacklistRe string `yaml:"device_blacklist_re"`
 }
 
 // Configure the IOstats check
 func (c *IOCheck) commonConfigure(data check.ConfigData, initConfig check.ConfigData) error {
-	err := error(nil)
-
-	conf := make(map[interface{}]interface{})
-
-	err = yaml.Unmarshal([]byte(initConfig), &conf)
-	if err != nil {
+	if err := c.CommonConfigure(data); err != nil {
 		return err
 	}
 
-	blacklistRe, ok := conf["device_blacklist_re"]
-	if ok && blacklistRe != "" {
-		if regex, ok := blacklistRe.(string); ok {
-			c.blacklist, err = regexp.Compile(regex)
-		}
+	conf := ioInitConfig{}
+	if err := core.UnmarshalConfig(initConfig, &conf); err != nil {
+		return err
 	}
-	return err
-}
-
-// Interval returns the scheduling time for the check
-func (c *IOCheck) Interval() time.Duration {
-	return check.DefaultCheckInterval
-}
-
-// ID returns the name of the check since there should be only one instance running
-func (c *IOCheck) ID() check.ID {
-	return check.ID(c.String())
-}
-
-// Stop does nothing
-func (c *IOCheck) Stop() {}
 
-// GetWarnings grabs the last warnings from the sender
-func (c *IOCheck) GetWarnings() []error {
-	w := c.lastWarnings
-	c.lastWarnings = []error{}
-	return w
-}
-
-// Warn will log a warning and add it to the warnings
-func (c *IOCheck) warn(v ...interface{}) error {
-	w := log.Warn(v)
-	c.lastWarnings = append(c.lastWarnings, w)
-
-	return w
-}
-
-// Warnf will log a formatted warning and add it to the warnings
-func (c *IOCheck) warnf(format string, params ...interface{}) error {
-	w := log.Warnf(format, params)
-	c.lastWarnings = append(c.lastWarnings, w)
-
-	return w
-}
-
-// GetMetricStats returns the stats from the last run of the check
-func (c *IOCheck) GetMetricStats() (map[string]int64, error) {
-	sender, err := aggregator.GetSender(c.ID())
-	if err != nil {
-		return nil, fmt.Errorf("Failed to retrieve a Sender instance: %v", err)
+	if conf.DeviceBlacklistRe != "" {
+		var err error
+		c.blacklist, err = regexp.Compile(conf.DeviceBlacklistRe)
+		if err != nil {
+			return err
+		}
 	}
-	return sender.GetMetricStats(), nil
+	return nil
 }
 
 func init() {
@@ -104,6 +55,8 @@ func init() {
 
 func ioFactory() check.Check {
 	log.Debug("IOCheck factory")
-	c := &IOCheck{}
+	c := &IOCheck{
+		CheckBase: core.NewCheckBase("io"),
+	}
 	return c
 }
@@ -12,8 +12,8 @@ import (
 	"regexp"
 	"time"
 
-	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
 	"github.com/DataDog/datadog-agent/pkg/util/xc"
 	log "github.com/cihub/seelog"
 	"github.com/shirou/gopsutil/disk"
@@ -24,10 +24,10 @@ var hz int64
 
 // IOCheck doesn't need additional fields
 type IOCheck struct {
-	blacklist    *regexp.Regexp
-	lastWarnings []error
-	ts           int64
-	stats        map[string]disk.IOCountersStat
+	core.CheckBase
+	blacklist *regexp.Regexp
+	ts        int64
+	stats     map[string]disk.IOCountersStat
 }
 
 // Configure the IOstats check
@@ -36,7 +36,7 @@ func (c *IOCheck) Configure(data check.ConfigData, initConfig check.ConfigData)
 	return err
 }
 func (c *IOCheck) nixIO() error {
-	sender, err := aggregator.GetSender(c.ID())
+	sender, err := c.GetSender()
 	if err != nil {
 		return err
 	}
@@ -60,7 +60,7 @@ func (c *IOCheck) nixIO() error {
 		tagbuff.Reset()
 		tagbuff.WriteString("device:")
 		tagbuff.WriteString(device)
-		tags := []string{tagbuff.String()}
+		tags := append([]string{tagbuff.String()}, c.Tags()...)
 
 		sender.Rate("system.io.r_s", float64(ioStats.ReadCount), "", tags)
 		sender.Rate("system.io.w_s", float64(ioStats.WriteCount), "", tags)
@@ -136,7 +136,7 @@ func (c *IOCheck) nixIO() error {
 
 // Run executes the check
 func (c *IOCheck) Run() error {
-	sender, err := aggregator.GetSender(c.ID())
+	sender, err := c.GetSender()
 	if err != nil {
 		return err
 	}
@@ -12,8 +12,8 @@ import (
 	"syscall"
 	"unsafe"
 
-	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
 	"github.com/StackExchange/wmi"
 	log "github.com/cihub/seelog"
 )
@@ -46,9 +46,9 @@ type Win32_PerfRawData_PerfDisk_LogicalDisk struct {
 
 // IOCheck doesn't need additional fields
 type IOCheck struct {
-	blacklist    *regexp.Regexp
-	drivemap     map[string]Win32_PerfRawData_PerfDisk_LogicalDisk
-	lastWarnings []error
+	core.CheckBase
+	blacklist *regexp.Regexp
+	drivemap  map[string]Win32_PerfRawData_PerfDisk_LogicalDisk
 }
 
 // Configure the IOstats check
@@ -122,7 +122,7 @@ func computeValue(pvs Win32_PerfRawData_PerfDisk_LogicalDisk, cur *Win32_PerfRaw
 
 // Run executes the check
 func (c *IOCheck) Run() error {
-	sender, err := aggregator.GetSender(c.ID())
+	sender, err := c.GetSender()
 	if err != nil {
 		return err
 	}
@@ -148,7 +148,7 @@ func (c *IOCheck) Run() error {
 		tagbuff.Reset()
 		tagbuff.WriteString("device:")
 		tagbuff.WriteString(drive)
-		tags := []string{tagbuff.String()}
+		tags := append([]string{tagbuff.String()}, c.Tags()...)
 		if prev, ok := c.drivemap[d.Name]; ok {
 			// have a previous value we can compute from
 			metrics, err := computeValue(prev, &d)
@@ -7,9 +7,7 @@ package system
 
 import (
 	"fmt"
-	"time"
 
-	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
 	log "github.com/cihub/seelog"
@@ -21,17 +19,13 @@ var loadAvg = load.Avg
 
 // LoadCheck doesn't need additional fields
 type LoadCheck struct {
-	lastWarnings []error
-	nbCPU        int32
-}
-
-func (c *LoadCheck) String() string {
-	return "load"
+	core.CheckBase
+	nbCPU int32
 }
 
 // Run executes the check
 func (c *LoadCheck) Run() error {
-	sender, err := aggregator.GetSender(c.ID())
+	sender, err := c.GetSender()
 	if err != nil {
 		return err
 	}
@@ -42,13 +36,13 @@ func (c *LoadCheck) Run() error {
 		return err
 	}
 
-	sender.Gauge("system.load.1", avg.Load1, "", nil)
-	sender.Gauge("system.load.5", avg.Load5, "", nil)
-	sender.Gauge("system.load.15", avg.Load15, "", nil)
+	sender.Gauge("system.load.1", avg.Load1, "", c.Tags())
+	sender.Gauge("system.load.5", avg.Load5, "", c.Tags())
+	sender.Gauge("system.load.15", avg.Load15, "", c.Tags())
 	cpus := float64(c.nbCPU)
-	sender.Gauge("system.load.norm.1", avg.Load1/cpus, "", nil)
-	sender.Gauge("system.load.norm.5", avg.Load5/cpus, "", nil)
-	sender.Gauge("system.load.norm.15", avg.Load15/cpus, "", nil)
+	sender.Gauge("system.load.norm.1", avg.Load1/cpus, "", c.Tags())
+	sender.Gauge("system.load.norm.5", avg.Load5/cpus, "", c.Tags())
+	sender.Gauge("system.load.norm.15", avg.Load15/cpus, "", c.Tags())
 	sender.Commit()
 
 	return nil
@@ -56,7 +50,10 @@ func (c *LoadCheck) Run() error {
 
 // Configure the CPU check doesn't need configuration
 func (c *LoadCheck) Configure(data check.ConfigData, initConfig check.ConfigData) error {
-	// do nothing
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+
 	info, err := cpuInfo()
 	if err != nil {
 		return fmt.Errorf("system.LoadCheck: could not query CPU info")
@@ -67,53 +64,10 @@ func (c *LoadCheck) Configure(data check.ConfigData, initConfig check.ConfigData
 	return nil
 }
 
-// Interval returns the scheduling time for the check
-func (c *LoadCheck) Interval() time.Duration {
-	return check.DefaultCheckInterval
-}
-
-// ID returns the name of the check since there should be only one instance running
-func (c *LoadCheck) ID() check.ID {
-	return check.ID(c.String())
-}
-
-// Stop does nothing
-func (c *LoadCheck) Stop() {}
-
-// GetWarnings grabs the last warnings from the sender
-func (c *LoadCheck) GetWarnings() []error {
-	w := c.lastWarnings
-	c.lastWarnings = []error{}
-	return w
-}
-
-// Warn will log a warning and add it to the warnings
-func (c *LoadCheck) warn(v ...interface{}) error {
-	w := log.Warn(v)
-	c.lastWarnings = append(c.lastWarnings, w)
-
-	return w
-}
-
-// Warnf will log a formatted warning and add it to the warnings
-func (c *LoadCheck) warnf(format string, params ...interface{}) error {
-	w := log.Warnf(format, params)
-	c.lastWarnings = append(c.lastWarnings, w)
-
-	return w
-}
-
-// GetMetricStats returns the stats from the last run of the check
-func (c *LoadCheck) GetMetricStats() (map[string]int64, error) {
-	sender, err := aggregator.GetSender(c.ID())
-	if err != nil {
-		return nil, fmt.Errorf("Failed to retrieve a Sender instance: %v", err)
-	}
-	return sender.GetMetricStats(), nil
-}
-
 func loadFactory() check.Check {
-	return &LoadCheck{}
+	return &LoadCheck{
+		CheckBase: core.NewCheckBase("load"),
+	}
 }
 
 func init() {
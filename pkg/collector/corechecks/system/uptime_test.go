@@ -7,8 +7,13 @@ package system
 
 import (
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
 )
 
 func uptimeSampler() (uint64, error) {
@@ -30,3 +35,20 @@ func TestUptimeCheckLinux(t *testing.T) {
 	mock.AssertNumberOfCalls(t, "Gauge", 1)
 	mock.AssertNumberOfCalls(t, "Commit", 1)
 }
+
+func TestUptimeCheckTags(t *testing.T) {
+	uptime = uptimeSampler
+	uptimeCheck := uptimeFactory()
+	err := uptimeCheck.Configure([]byte("tags: [\"env:prod\"]\nmin_collection_interval: 30"), nil)
+	require.Nil(t, err)
+	assert.Equal(t, check.ID("uptime"), uptimeCheck.ID())
+	assert.Equal(t, 30*time.Second, uptimeCheck.Interval())
+
+	mock := aggregator.NewMockSender(uptimeCheck.ID())
+
+	mock.On("Gauge", "system.uptime", 555.0, "", []string{"env:prod"}).Return().Times(1)
+	mock.On("Commit").Return().Times(1)
+
+	uptimeCheck.Run()
+	mock.AssertExpectations(t)
+}
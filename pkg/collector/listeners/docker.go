@@ -9,8 +9,10 @@ package listeners
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 
@@ -100,6 +102,11 @@ func (l *DockerListener) Stop() {
 	l.stop <- true
 }
 
+// String returns a string representation of the DockerListener
+func (l *DockerListener) String() string {
+	return "Docker Listener"
+}
+
 // init looks at currently running Docker containers,
 // creates services for them, and pass them to the ConfigResolver.
 // It is typically called at start up.
@@ -119,6 +126,7 @@ func (l *DockerListener) init() {
 			ADIdentifiers: l.getConfigIDFromPs(co),
 			Hosts:         l.getHostsFromPs(co),
 			Ports:         l.getPortsFromPs(co),
+			Labels:        l.getLabelsFromPs(co),
 		}
 		l.newService <- &svc
 		l.services[id] = &svc
@@ -190,6 +198,10 @@ func (l *DockerListener) createService(cID ID) {
 	if err != nil {
 		log.Errorf("Failed to inspect container %s - %s", cID[:12], err)
 	}
+	_, err = svc.GetEnv()
+	if err != nil {
+		log.Errorf("Failed to inspect container %s - %s", cID[:12], err)
+	}
 
 	l.m.Lock()
 	l.services[ID(cID)] = &svc
@@ -243,23 +255,28 @@ func (l *DockerListener) getPortsFromInspect(co types.ContainerJSON) []int {
 // These id are sorted to reflect the priority we want the ConfigResolver to
 // use when matching a template.
 //
+// The container ID, prefixed like the tagger entities (`docker://<ID>`), always
+// comes first so the templates of a given container (e.g. declared in its pod
+// annotations) are matched.
+//
 // When the special identifier label in `identifierLabel` is set by the user,
 // it overrides any other meaning of template identification for the service
-// and the return value will contain only the label value.
+// and the return value will contain only the container ID and the label value.
 //
 // If the special label was not set, the priority order is the following:
-//   1. Long image name
-//   2. Short image name
+//   1. Container ID
+//   2. Long image name
+//   3. Short image name
 func (l *DockerListener) getConfigIDFromPs(co types.Container) []string {
+	ids := []string{docker.ContainerIDToEntityName(co.ID)}
+
 	// check for an identifier label
 	for l, v := range co.Labels {
 		if l == identifierLabel {
-			return []string{v}
+			return append(ids, v)
 		}
 	}
 
-	ids := []string{}
-
 	// use the image name
 	ids = append(ids, co.Image) // TODO: check if it's the sha256
 	// TODO: add the short name with lower priority
@@ -290,6 +307,15 @@ func (l *DockerListener) getPortsFromPs(co types.Container) []int {
 	return ports
 }
 
+// getLabelsFromPs returns the labels of a container
+func (l *DockerListener) getLabelsFromPs(co types.Container) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range co.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
 // GetID returns the service ID
 func (s *DockerService) GetID() ID {
 	return s.ID
@@ -299,13 +325,18 @@ func (s *DockerService) GetID() ID {
 // These id are sorted to reflect the priority we want the ConfigResolver to
 // use when matching a template.
 //
+// The container ID, prefixed like the tagger entities (`docker://<ID>`), always
+// comes first so the templates of a given container (e.g. declared in its pod
+// annotations) are matched.
+//
 // When the special identifier label in `identifierLabel` is set by the user,
 // it overrides any other meaning of template identification for the service
-// and the return value will contain only the label value.
+// and the return value will contain only the container ID and the label value.
 //
 // If the special label was not set, the priority order is the following:
-//   1. Long image name
-//   2. Short image name
+//   1. Container ID
+//   2. Long image name
+//   3. Short image name
 func (s *DockerService) GetADIdentifiers() ([]string, error) {
 	if len(s.ADIdentifiers) == 0 {
 		cj, err := docker.Inspect(string(s.ID), false)
@@ -313,15 +344,16 @@ func (s *DockerService) GetADIdentifiers() ([]string, error) {
 			return []string{}, err
 		}
 
+		ids := []string{docker.ContainerIDToEntityName(string(s.ID))}
+
 		// check for an identifier label
 		for l, v := range cj.Config.Labels {
 			if l == identifierLabel {
-				return []string{v}, nil
+				s.ADIdentifiers = append(ids, v)
+				return s.ADIdentifiers, nil
 			}
 		}
 
-		ids := []string{}
-
 		// use the image name
 		ids = append(ids, cj.Image) // TODO: check if it's the sha256
 		// TODO: add the short name with lower priority
@@ -367,3 +399,69 @@ func (s *DockerService) GetPid() (int, error) {
 
 	return s.Pid, nil
 }
+
+// GetHostname inspects the container and returns its hostname
+func (s *DockerService) GetHostname() (string, error) {
+	if s.Hostname == "" {
+		if err := s.inspect(); err != nil {
+			return "", err
+		}
+	}
+	return s.Hostname, nil
+}
+
+// GetEnv inspects the container and returns its environment variables
+func (s *DockerService) GetEnv() (map[string]string, error) {
+	if s.Env == nil {
+		if err := s.inspect(); err != nil {
+			return nil, err
+		}
+	}
+	return s.Env, nil
+}
+
+// GetLabels returns the labels of the container, it's inspected when they
+// weren't listed
+func (s *DockerService) GetLabels() (map[string]string, error) {
+	if s.Labels == nil {
+		if err := s.inspect(); err != nil {
+			return nil, err
+		}
+	}
+	return s.Labels, nil
+}
+
+// GetNamedPorts is not supported, docker doesn't name the ports of the
+// containers
+func (s *DockerService) GetNamedPorts() (map[string]int, error) {
+	return nil, errors.New("docker doesn't name the ports of the containers")
+}
+
+// inspect stores the hostname, the environment and the labels of the container
+func (s *DockerService) inspect() error {
+	cj, err := docker.Inspect(string(s.ID), false)
+	if err != nil {
+		return err
+	}
+	s.Hostname = cj.Config.Hostname
+	s.Env = parseContainerEnv(cj.Config.Env)
+	s.Labels = make(map[string]string)
+	for k, v := range cj.Config.Labels {
+		s.Labels[k] = v
+	}
+	return nil
+}
+
+// parseContainerEnv splits the `VAR=value` entries of the environment of a
+// container, the entries without value are ignored
+func parseContainerEnv(env []string) map[string]string {
+	vars := make(map[string]string)
+	for _, e := range env {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		vars[parts[0]] = parts[1]
+	}
+	return vars
+}
@@ -29,8 +29,7 @@ func TestGetConfigIDFromPs(t *testing.T) {
 	dl := DockerListener{}
 
 	ids := dl.getConfigIDFromPs(co)
-	assert.Len(t, ids, 1)
-	assert.Equal(t, "test", ids[0])
+	assert.Equal(t, []string{"docker://deadbeef", "test"}, ids)
 
 	labeledCo := types.Container{
 		ID:     "deadbeef",
@@ -38,8 +37,7 @@ func TestGetConfigIDFromPs(t *testing.T) {
 		Labels: map[string]string{"io.datadog.check.id": "w00tw00t"},
 	}
 	ids = dl.getConfigIDFromPs(labeledCo)
-	assert.Len(t, ids, 1)
-	assert.Equal(t, "w00tw00t", ids[0])
+	assert.Equal(t, []string{"docker://deadbeef", "w00tw00t"}, ids)
 }
 
 func TestGetHostsFromPs(t *testing.T) {
@@ -106,8 +104,7 @@ func TestGetADIdentifiers(t *testing.T) {
 
 	ids, err := s.GetADIdentifiers()
 	assert.Nil(t, err)
-	assert.Len(t, ids, 1)
-	assert.Equal(t, "test", ids[0])
+	assert.Equal(t, []string{"docker://deadbeef", "test"}, ids)
 
 	s = DockerService{ID: ID("deadbeef")}
 	labeledCo := types.ContainerJSON{
@@ -120,8 +117,7 @@ func TestGetADIdentifiers(t *testing.T) {
 
 	ids, err = s.GetADIdentifiers()
 	assert.Nil(t, err)
-	assert.Len(t, ids, 1)
-	assert.Equal(t, "w00tw00t", ids[0])
+	assert.Equal(t, []string{"docker://deadbeef", "w00tw00t"}, ids)
 }
 
 func TestGetHostsFromInspect(t *testing.T) {
@@ -228,3 +224,32 @@ func TestGetPid(t *testing.T) {
 	assert.Equal(t, 1337, pid)
 	assert.Nil(t, err)
 }
+
+func TestGetEnvLabelsHostname(t *testing.T) {
+	s := DockerService{ID: ID("cafe")}
+
+	// Setting mocked data in cache
+	co := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: "cafe", Image: "redis"},
+		Config: &container.Config{
+			Hostname: "cafe12345678",
+			Env:      []string{"REDIS_PASSWORD=s3cr3t", "EMPTY=", "INVALID"},
+			Labels:   map[string]string{"com.example.port": "6380"},
+		},
+	}
+	cacheKey := docker.GetInspectCacheKey("cafe")
+	cache.Cache.Set(cacheKey, co, 10*time.Second)
+
+	env, err := s.GetEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"REDIS_PASSWORD": "s3cr3t", "EMPTY": ""}, env)
+	labels, err := s.GetLabels()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"com.example.port": "6380"}, labels)
+	hostname, err := s.GetHostname()
+	assert.Nil(t, err)
+	assert.Equal(t, "cafe12345678", hostname)
+
+	_, err = s.GetNamedPorts()
+	assert.NotNil(t, err)
+}
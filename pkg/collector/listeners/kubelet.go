@@ -0,0 +1,347 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package listeners
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+const (
+	// how often the kubelet is polled
+	kubeletPollInterval = 5 * time.Second
+	// containers that aren't listed anymore are removed after this duration
+	kubeletExpiryDuration = 15 * time.Second
+	// the network of the pod IP in the hosts of the services
+	podNetwork = "pod"
+)
+
+// Abstraction for testing
+type podWatcher interface {
+	PullChanges() ([]*kubelet.Pod, error)
+	ExpireContainers() ([]string, error)
+}
+
+// KubeletListener implements the ServiceListener interface.
+// It polls the kubelet for the pods of the node and reports their containers
+// to Auto Discovery, it doesn't need a Docker daemon.
+type KubeletListener struct {
+	watcher    podWatcher
+	services   map[ID]Service
+	newService chan<- Service
+	delService chan<- Service
+	ticker     *time.Ticker
+	stop       chan bool
+	m          sync.RWMutex
+}
+
+// KubeContainerService implements the Service interface for the containers
+// of the pods listed by the kubelet
+type KubeContainerService struct {
+	ID            ID                // container ID as reported by the kubelet, e.g. `docker://<ID>`
+	ADIdentifiers []string          // identifiers on which templates will be matched
+	Hosts         map[string]string // network --> IP address
+	Ports         []int
+	NamedPorts    map[string]int    // port name --> port
+	Hostname      string            // hostname of the pod
+	Env           map[string]string // environment variable --> value
+	Labels        map[string]string // labels of the pod
+}
+
+// NewKubeletListener connects to the kubelet and instanciates a KubeletListener
+func NewKubeletListener() (*KubeletListener, error) {
+	watcher, err := kubelet.NewPodWatcher(kubeletExpiryDuration)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to the kubelet, auto discovery will not work: %s", err)
+	}
+
+	return &KubeletListener{
+		watcher:  watcher,
+		services: make(map[ID]Service),
+		stop:     make(chan bool),
+	}, nil
+}
+
+// Listen polls the kubelet regularly and reports the new containers and the
+// ones that went away as Services.
+func (l *KubeletListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
+	// setup the I/O channels
+	l.newService = newSvc
+	l.delService = delSvc
+	l.ticker = time.NewTicker(kubeletPollInterval)
+
+	go func() {
+		// process the containers that might be already running
+		l.poll()
+		for {
+			select {
+			case <-l.stop:
+				l.ticker.Stop()
+				return
+			case <-l.ticker.C:
+				l.poll()
+			}
+		}
+	}()
+}
+
+// Stop queues a shutdown of KubeletListener
+func (l *KubeletListener) Stop() {
+	l.stop <- true
+}
+
+// String returns a string representation of the KubeletListener
+func (l *KubeletListener) String() string {
+	return "Kubelet Listener"
+}
+
+// GetServices returns a copy of the current services
+func (l *KubeletListener) GetServices() map[ID]Service {
+	l.m.RLock()
+	defer l.m.RUnlock()
+
+	ret := make(map[ID]Service)
+	for k, v := range l.services {
+		ret[k] = v
+	}
+
+	return ret
+}
+
+// poll pulls the pods that changed since the last call to create the services
+// of their new containers, then removes the services of the containers the
+// kubelet doesn't list anymore
+func (l *KubeletListener) poll() {
+	pods, err := l.watcher.PullChanges()
+	if err != nil {
+		log.Errorf("Couldn't retrieve the pod list from the kubelet - %s", err)
+		return
+	}
+	for _, pod := range pods {
+		l.processPod(pod)
+	}
+
+	expired, err := l.watcher.ExpireContainers()
+	if err != nil {
+		log.Errorf("Couldn't expire the containers of the pods - %s", err)
+		return
+	}
+	for _, id := range expired {
+		l.removeService(ID(id))
+	}
+}
+
+// processPod creates a service for every container of the pod that doesn't
+// have one yet
+func (l *KubeletListener) processPod(pod *kubelet.Pod) {
+	for _, container := range pod.Status.Containers {
+		// the container is not created yet
+		if container.ID == "" {
+			continue
+		}
+		id := ID(container.ID)
+
+		l.m.RLock()
+		_, found := l.services[id]
+		l.m.RUnlock()
+		if found {
+			continue
+		}
+
+		svc := &KubeContainerService{
+			ID:            id,
+			ADIdentifiers: getADIdentifiersFromPod(container),
+			Hosts:         getHostsFromPod(pod),
+			Ports:         getPortsFromPod(pod, container.Name),
+			NamedPorts:    getNamedPortsFromPod(pod, container.Name),
+			Hostname:      getHostnameFromPod(pod),
+			Env:           getEnvFromPod(pod, container.Name),
+			Labels:        pod.Metadata.Labels,
+		}
+
+		l.m.Lock()
+		l.services[id] = svc
+		l.m.Unlock()
+
+		l.newService <- svc
+	}
+}
+
+// removeService removes the service of a container from the cache and tells
+// the ConfigResolver that this service stopped.
+func (l *KubeletListener) removeService(id ID) {
+	l.m.Lock()
+	svc, ok := l.services[id]
+	delete(l.services, id)
+	l.m.Unlock()
+
+	if ok {
+		l.delService <- svc
+	} else {
+		log.Debugf("Container %s not found, not removing", id)
+	}
+}
+
+// getADIdentifiersFromPod returns the AD identifiers of a container, sorted
+// by priority:
+//   1. Container ID, e.g. `docker://<ID>`
+//   2. Long image name, e.g. `gcr.io/google_containers/redis:3.2`
+//   3. Short image name, e.g. `redis`
+func getADIdentifiersFromPod(container kubelet.ContainerStatus) []string {
+	ids := []string{container.ID}
+	if container.Image == "" {
+		return ids
+	}
+	ids = append(ids, container.Image)
+
+	short := container.Image
+	// strip the digest, the tag and the repository
+	if i := strings.Index(short, "@"); i != -1 {
+		short = short[:i]
+	}
+	if i := strings.LastIndex(short, "/"); i != -1 {
+		short = short[i+1:]
+	}
+	if i := strings.Index(short, ":"); i != -1 {
+		short = short[:i]
+	}
+	if short != "" && short != container.Image {
+		ids = append(ids, short)
+	}
+	return ids
+}
+
+// getHostsFromPod returns the pod IP, the containers of a pod share it
+func getHostsFromPod(pod *kubelet.Pod) map[string]string {
+	hosts := make(map[string]string)
+	if pod.Status.PodIP != "" {
+		hosts[podNetwork] = pod.Status.PodIP
+	}
+	return hosts
+}
+
+// getPortsFromPod returns the ports declared in the spec of a container
+func getPortsFromPod(pod *kubelet.Pod, containerName string) []int {
+	ports := make([]int, 0)
+	for _, container := range pod.Spec.Containers {
+		if container.Name != containerName {
+			continue
+		}
+		for _, port := range container.Ports {
+			ports = append(ports, port.ContainerPort)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// getNamedPortsFromPod returns the ports of a container that have a name
+func getNamedPortsFromPod(pod *kubelet.Pod, containerName string) map[string]int {
+	ports := make(map[string]int)
+	for _, container := range pod.Spec.Containers {
+		if container.Name != containerName {
+			continue
+		}
+		for _, port := range container.Ports {
+			if port.Name != "" {
+				ports[port.Name] = port.ContainerPort
+			}
+		}
+	}
+	return ports
+}
+
+// getHostnameFromPod returns the hostname of the pod, its name unless the spec
+// sets one
+func getHostnameFromPod(pod *kubelet.Pod) string {
+	if pod.Spec.Hostname != "" {
+		return pod.Spec.Hostname
+	}
+	return pod.Metadata.Name
+}
+
+// getEnvFromPod returns the environment variables declared in the spec of a
+// container, the ones set from a source (secrets, config maps, fields) aren't
+// known by the kubelet so they're left out
+func getEnvFromPod(pod *kubelet.Pod, containerName string) map[string]string {
+	env := make(map[string]string)
+	for _, container := range pod.Spec.Containers {
+		if container.Name != containerName {
+			continue
+		}
+		for _, v := range container.Env {
+			if v.Value != "" {
+				env[v.Name] = v.Value
+			}
+		}
+	}
+	return env
+}
+
+// GetID returns the service ID
+func (s *KubeContainerService) GetID() ID {
+	return s.ID
+}
+
+// GetADIdentifiers returns the service AD identifiers
+func (s *KubeContainerService) GetADIdentifiers() ([]string, error) {
+	return s.ADIdentifiers, nil
+}
+
+// GetHosts returns the pod IP of the container
+func (s *KubeContainerService) GetHosts() (map[string]string, error) {
+	return s.Hosts, nil
+}
+
+// GetPorts returns the ports declared for the container
+func (s *KubeContainerService) GetPorts() ([]int, error) {
+	return s.Ports, nil
+}
+
+// GetTags retrieves tags using the Tagger
+func (s *KubeContainerService) GetTags() ([]string, error) {
+	tags, err := tagger.Tag(string(s.ID), true)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return tags, nil
+}
+
+// GetPid is not supported, the kubelet doesn't report the process of the
+// containers
+func (s *KubeContainerService) GetPid() (int, error) {
+	return -1, errors.New("the pid of the containers is not available from the kubelet")
+}
+
+// GetHostname returns the hostname of the pod of the container
+func (s *KubeContainerService) GetHostname() (string, error) {
+	return s.Hostname, nil
+}
+
+// GetEnv returns the environment variables declared for the container
+func (s *KubeContainerService) GetEnv() (map[string]string, error) {
+	return s.Env, nil
+}
+
+// GetLabels returns the labels of the pod of the container
+func (s *KubeContainerService) GetLabels() (map[string]string, error) {
+	return s.Labels, nil
+}
+
+// GetNamedPorts returns the ports of the container that have a name
+func (s *KubeContainerService) GetNamedPorts() (map[string]int, error) {
+	return s.NamedPorts, nil
+}
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package listeners
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+type podWatcherStub struct {
+	pods    []*kubelet.Pod
+	expired []string
+}
+
+func (w *podWatcherStub) PullChanges() ([]*kubelet.Pod, error) {
+	pods := w.pods
+	w.pods = nil
+	return pods, nil
+}
+
+func (w *podWatcherStub) ExpireContainers() ([]string, error) {
+	expired := w.expired
+	w.expired = nil
+	return expired, nil
+}
+
+func TestKubeletListenerPoll(t *testing.T) {
+	pod := &kubelet.Pod{
+		Metadata: kubelet.PodMetadata{
+			Name:   "redis-1234",
+			Labels: map[string]string{"app": "redis"},
+		},
+		Spec: kubelet.Spec{
+			Containers: []kubelet.ContainerSpec{
+				{
+					Name:  "redis",
+					Ports: []kubelet.ContainerPort{{ContainerPort: 6380}, {ContainerPort: 6379, Name: "redis"}},
+					Env:   []kubelet.EnvVar{{Name: "REDIS_PORT", Value: "6379"}, {Name: "REDIS_PASSWORD"}},
+				},
+				{Name: "sidecar"},
+			},
+		},
+		Status: kubelet.Status{
+			PodIP: "10.0.0.3",
+			Containers: []kubelet.ContainerStatus{
+				{Name: "redis", Image: "gcr.io/google_containers/redis:3.2", ID: "docker://abcd"},
+				{Name: "sidecar", Image: "busybox", ID: "docker://ef01"},
+				{Name: "pending", Image: "busybox"},
+			},
+		},
+	}
+	watcher := &podWatcherStub{pods: []*kubelet.Pod{pod}}
+	newSvc := make(chan Service, 10)
+	delSvc := make(chan Service, 10)
+	l := &KubeletListener{
+		watcher:    watcher,
+		services:   make(map[ID]Service),
+		newService: newSvc,
+		delService: delSvc,
+	}
+
+	l.poll()
+	require.Len(t, newSvc, 2)
+	redis := (<-newSvc).(*KubeContainerService)
+	assert.Equal(t, ID("docker://abcd"), redis.GetID())
+	ids, err := redis.GetADIdentifiers()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"docker://abcd", "gcr.io/google_containers/redis:3.2", "redis"}, ids)
+	hosts, err := redis.GetHosts()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"pod": "10.0.0.3"}, hosts)
+	ports, err := redis.GetPorts()
+	assert.Nil(t, err)
+	assert.Equal(t, []int{6379, 6380}, ports)
+	namedPorts, err := redis.GetNamedPorts()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"redis": 6379}, namedPorts)
+	hostname, err := redis.GetHostname()
+	assert.Nil(t, err)
+	assert.Equal(t, "redis-1234", hostname)
+	env, err := redis.GetEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"REDIS_PORT": "6379"}, env)
+	labels, err := redis.GetLabels()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"app": "redis"}, labels)
+	_, err = redis.GetPid()
+	assert.NotNil(t, err)
+
+	sidecar := (<-newSvc).(*KubeContainerService)
+	assert.Equal(t, []string{"docker://ef01", "busybox"}, sidecar.ADIdentifiers)
+	assert.Empty(t, sidecar.Ports)
+	assert.Len(t, l.GetServices(), 2)
+
+	// an updated pod doesn't create the services again
+	watcher.pods = []*kubelet.Pod{pod}
+	l.poll()
+	assert.Len(t, newSvc, 0)
+
+	// expired containers are removed, unknown ones are ignored
+	watcher.expired = []string{"docker://abcd", "docker://unknown"}
+	l.poll()
+	require.Len(t, delSvc, 1)
+	assert.Equal(t, ID("docker://abcd"), (<-delSvc).GetID())
+	assert.Len(t, l.GetServices(), 1)
+}
+
+func TestGetADIdentifiersFromPod(t *testing.T) {
+	for image, expected := range map[string][]string{
+		"":                          {"docker://1"},
+		"redis":                     {"docker://1", "redis"},
+		"redis:latest":              {"docker://1", "redis:latest", "redis"},
+		"localhost:5000/app/nginx":  {"docker://1", "localhost:5000/app/nginx", "nginx"},
+		"nginx@sha256:0123456789ab": {"docker://1", "nginx@sha256:0123456789ab", "nginx"},
+	} {
+		assert.Equal(t, expected, getADIdentifiersFromPod(kubelet.ContainerStatus{ID: "docker://1", Image: image}), image)
+	}
+}
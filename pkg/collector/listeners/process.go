@@ -0,0 +1,532 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+// +build linux
+
+package listeners
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+const (
+	// how often /proc is scanned
+	processPollInterval = 10 * time.Second
+	// a matching process is reported once it listens on a port, or once it
+	// ran this long without listening
+	processPortsGracePeriod = 30 * time.Second
+	// the network of the hosts of the processes
+	processNetwork = "host"
+	// the TCP state of the listening sockets in /proc/<pid>/net/tcp
+	tcpListenState = "0A"
+)
+
+// processRuleConfig is a rule of `process_listener_rules`, the processes
+// whose executable name is `exe` or whose command line matches `cmdline` get
+// `ad_identifier`
+type processRuleConfig struct {
+	ADIdentifier string `mapstructure:"ad_identifier"`
+	Exe          string `mapstructure:"exe"`
+	Cmdline      string `mapstructure:"cmdline"`
+}
+
+type processRule struct {
+	adIdentifier string
+	exe          string
+	cmdline      *regexp.Regexp
+}
+
+// ProcessListener implements the ServiceListener interface for the processes
+// running on the host. It scans `proc_root` regularly and reports the
+// processes matching the rules of `process_listener_rules` as Services, and
+// the ones that exited.
+type ProcessListener struct {
+	procRoot   string
+	rules      []processRule
+	services   map[ID]*ProcessService
+	pending    map[ID]*ProcessService // matching processes not reported yet
+	ignored    map[int]ignoredProcess // pid --> the processes matching no rule
+	newService chan<- Service
+	delService chan<- Service
+	ticker     *time.Ticker
+	stop       chan bool
+	m          sync.RWMutex
+}
+
+// ignoredProcess identifies a process matching no rule, a process that
+// `exec`s keeps its pid and start time but changes its command line
+type ignoredProcess struct {
+	startTime uint64
+	cmdline   string
+	exe       string
+}
+
+// ProcessService implements the Service interface for the processes running
+// on the host
+type ProcessService struct {
+	ID            ID       // `process://<pid>`
+	ADIdentifiers []string // identifiers on which templates will be matched
+	Pid           int
+	Ports         []int     // listening TCP ports
+	StartTime     uint64    // in clock ticks after boot, tells apart the processes reusing a pid
+	firstSeen     time.Time // when the listener found the process
+	procRoot      string
+}
+
+// NewProcessListener creates a ProcessListener with the rules of
+// `process_listener_rules`
+func NewProcessListener() (*ProcessListener, error) {
+	var rulesConfig []processRuleConfig
+	if err := config.Datadog.UnmarshalKey("process_listener_rules", &rulesConfig); err != nil {
+		return nil, fmt.Errorf("invalid process_listener_rules: %s", err)
+	}
+	rules, err := parseProcessRules(rulesConfig)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("process_listener_rules is empty, no process would be reported")
+	}
+
+	return newProcessListener(config.Datadog.GetString("proc_root"), rules), nil
+}
+
+func newProcessListener(procRoot string, rules []processRule) *ProcessListener {
+	return &ProcessListener{
+		procRoot: procRoot,
+		rules:    rules,
+		services: make(map[ID]*ProcessService),
+		pending:  make(map[ID]*ProcessService),
+		ignored:  make(map[int]ignoredProcess),
+		stop:     make(chan bool),
+	}
+}
+
+// parseProcessRules validates the rules and compiles their regexps
+func parseProcessRules(rulesConfig []processRuleConfig) ([]processRule, error) {
+	rules := make([]processRule, 0, len(rulesConfig))
+	for i, rc := range rulesConfig {
+		if rc.ADIdentifier == "" {
+			return nil, fmt.Errorf("process listener rule %d has no ad_identifier", i)
+		}
+		if (rc.Exe == "") == (rc.Cmdline == "") {
+			return nil, fmt.Errorf("process listener rule %d (%s) must set either exe or cmdline", i, rc.ADIdentifier)
+		}
+		rule := processRule{adIdentifier: rc.ADIdentifier, exe: rc.Exe}
+		if rc.Cmdline != "" {
+			re, err := regexp.Compile(rc.Cmdline)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cmdline of process listener rule %d (%s): %s", i, rc.ADIdentifier, err)
+			}
+			rule.cmdline = re
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Listen scans the processes regularly and reports the new ones matching a
+// rule and the ones that exited as Services.
+func (l *ProcessListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
+	// setup the I/O channels
+	l.newService = newSvc
+	l.delService = delSvc
+	l.ticker = time.NewTicker(processPollInterval)
+
+	go func() {
+		// process the processes that might be already running
+		l.poll()
+		for {
+			select {
+			case <-l.stop:
+				l.ticker.Stop()
+				return
+			case <-l.ticker.C:
+				l.poll()
+			}
+		}
+	}()
+}
+
+// Stop queues a shutdown of ProcessListener
+func (l *ProcessListener) Stop() {
+	l.stop <- true
+}
+
+// String returns a string representation of the ProcessListener
+func (l *ProcessListener) String() string {
+	return "Process Listener"
+}
+
+// GetServices returns a copy of the current services
+func (l *ProcessListener) GetServices() map[ID]Service {
+	l.m.RLock()
+	defer l.m.RUnlock()
+
+	ret := make(map[ID]Service)
+	for k, v := range l.services {
+		ret[k] = v
+	}
+
+	return ret
+}
+
+// poll lists the processes, reports the new ones matching a rule once they
+// listen on a port or once their grace period is over, then removes the
+// services of the processes that exited
+func (l *ProcessListener) poll() {
+	entries, err := ioutil.ReadDir(l.procRoot)
+	if err != nil {
+		log.Errorf("Couldn't list the processes in %s - %s", l.procRoot, err)
+		return
+	}
+
+	running := make(map[ID]struct{})
+	runningPids := make(map[int]struct{})
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		startTime, err := getProcessStartTime(l.procRoot, pid)
+		if err != nil {
+			// the process exited
+			continue
+		}
+		id := ID(fmt.Sprintf("process://%d", pid))
+		if l.isReported(id, startTime) {
+			running[id] = struct{}{}
+			runningPids[pid] = struct{}{}
+			continue
+		}
+
+		cmdline, exe := getProcessCommand(l.procRoot, pid)
+		process := ignoredProcess{startTime: startTime, cmdline: strings.Join(cmdline, " "), exe: exe}
+		runningPids[pid] = struct{}{}
+		if l.ignored[pid] == process {
+			continue
+		}
+		// a new process, or an ignored one that exec'd
+		delete(l.ignored, pid)
+		svc := l.matchProcess(pid, startTime, process.cmdline, exe)
+		if svc == nil {
+			l.ignored[pid] = process
+			continue
+		}
+		// the pid was reused, the new process is reported after the old one
+		// is removed
+		if old, found := l.pending[id]; !found || old.StartTime != startTime {
+			l.pending[id] = svc
+		}
+		running[id] = struct{}{}
+	}
+
+	// the processes that exited
+	for pid := range l.ignored {
+		if _, found := runningPids[pid]; !found {
+			delete(l.ignored, pid)
+		}
+	}
+	for id := range l.pending {
+		if _, found := running[id]; !found {
+			delete(l.pending, id)
+		}
+	}
+	l.m.RLock()
+	removed := []ID{}
+	for id, svc := range l.services {
+		if _, found := running[id]; !found || l.pending[id] != nil && l.pending[id].StartTime != svc.StartTime {
+			removed = append(removed, id)
+		}
+	}
+	l.m.RUnlock()
+	for _, id := range removed {
+		l.removeService(id)
+	}
+
+	l.reportPending()
+}
+
+// isReported returns whether the process is already reported, a process
+// reusing its pid isn't
+func (l *ProcessListener) isReported(id ID, startTime uint64) bool {
+	l.m.RLock()
+	defer l.m.RUnlock()
+	svc, found := l.services[id]
+	return found && svc.StartTime == startTime
+}
+
+// matchProcess returns a service for the process if its command line or the
+// name of its executable matches a rule, nil otherwise
+func (l *ProcessListener) matchProcess(pid int, startTime uint64, cmdline, exe string) *ProcessService {
+	if cmdline == "" {
+		// the process exited, or it's a kernel thread
+		return nil
+	}
+
+	id := ID(fmt.Sprintf("process://%d", pid))
+	adIDs := []string{string(id)}
+	seen := map[string]struct{}{}
+	for _, rule := range l.rules {
+		if _, found := seen[rule.adIdentifier]; found {
+			continue
+		}
+		if rule.exe != "" && rule.exe == exe || rule.cmdline != nil && rule.cmdline.MatchString(cmdline) {
+			adIDs = append(adIDs, rule.adIdentifier)
+			seen[rule.adIdentifier] = struct{}{}
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+
+	return &ProcessService{
+		ID:            id,
+		ADIdentifiers: adIDs,
+		Pid:           pid,
+		Ports:         []int{},
+		StartTime:     startTime,
+		firstSeen:     time.Now(),
+		procRoot:      l.procRoot,
+	}
+}
+
+// reportPending reports the pending processes listening on a port, or
+// running for longer than the grace period
+func (l *ProcessListener) reportPending() {
+	for id, svc := range l.pending {
+		ports, err := getProcessPorts(l.procRoot, svc.Pid)
+		if err != nil {
+			log.Debugf("Couldn't get the ports of process %d - %s", svc.Pid, err)
+		}
+		if len(ports) == 0 && time.Since(svc.firstSeen) < processPortsGracePeriod {
+			continue
+		}
+		svc.Ports = ports
+		delete(l.pending, id)
+
+		l.m.Lock()
+		l.services[id] = svc
+		l.m.Unlock()
+
+		l.newService <- svc
+	}
+}
+
+// removeService removes the service of a process from the cache and tells
+// the ConfigResolver that this service stopped.
+func (l *ProcessListener) removeService(id ID) {
+	l.m.Lock()
+	svc, ok := l.services[id]
+	delete(l.services, id)
+	l.m.Unlock()
+
+	if ok {
+		l.delService <- svc
+	} else {
+		log.Debugf("Process %s not found, not removing", id)
+	}
+}
+
+// getProcessStartTime returns the start time of a process, the 22nd field of
+// /proc/<pid>/stat
+func getProcessStartTime(procRoot string, pid int) (uint64, error) {
+	stat, err := ioutil.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// the command name is between parentheses and can contain spaces
+	end := bytes.LastIndexByte(stat, ')')
+	if end == -1 {
+		return 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	// the fields after the command name start with the 3rd one
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// getProcessCommand returns the arguments of a process and the name of its
+// executable, both are empty when the process exited or is a kernel thread
+func getProcessCommand(procRoot string, pid int) ([]string, string) {
+	cmdline, err := getProcessCmdline(procRoot, pid)
+	if err != nil || len(cmdline) == 0 {
+		return nil, ""
+	}
+	return cmdline, getProcessExeName(procRoot, pid, cmdline)
+}
+
+// getProcessCmdline returns the arguments of a process
+func getProcessCmdline(procRoot string, pid int) ([]string, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimRight(raw, "\x00")
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(raw), "\x00"), nil
+}
+
+// getProcessExeName returns the name of the executable of a process, the
+// executable of the processes of other users can't be read so the name of the
+// first argument is used instead
+func getProcessExeName(procRoot string, pid int, cmdline []string) string {
+	exe, err := os.Readlink(filepath.Join(procRoot, strconv.Itoa(pid), "exe"))
+	if err == nil {
+		return filepath.Base(strings.TrimSuffix(exe, " (deleted)"))
+	}
+	return filepath.Base(cmdline[0])
+}
+
+// getProcessPorts returns the TCP ports a process listens on, from the
+// sockets of its network namespace it has a file descriptor of. The file
+// descriptors of the processes of other users can't be read.
+func getProcessPorts(procRoot string, pid int) ([]int, error) {
+	pidRoot := filepath.Join(procRoot, strconv.Itoa(pid))
+	listening := make(map[string]int) // socket inode --> port
+	for _, file := range []string{"tcp", "tcp6"} {
+		if err := parseListeningSockets(filepath.Join(pidRoot, "net", file), listening); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if len(listening) == 0 {
+		return []int{}, nil
+	}
+
+	fds, err := ioutil.ReadDir(filepath.Join(pidRoot, "fd"))
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int]struct{})
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(pidRoot, "fd", fd.Name()))
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+		if port, ok := listening[inode]; ok {
+			found[port] = struct{}{}
+		}
+	}
+
+	ports := make([]int, 0, len(found))
+	for port := range found {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
+
+// parseListeningSockets adds the inodes and the ports of the listening
+// sockets of a /proc/net/tcp file to `listening`
+func parseListeningSockets(path string, listening map[string]int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// skip the header
+	scanner.Scan()
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListenState {
+			continue
+		}
+		i := strings.LastIndex(fields[1], ":")
+		if i == -1 {
+			continue
+		}
+		port, err := strconv.ParseUint(fields[1][i+1:], 16, 16)
+		if err != nil {
+			continue
+		}
+		listening[fields[9]] = int(port)
+	}
+	return scanner.Err()
+}
+
+// GetID returns the service ID
+func (s *ProcessService) GetID() ID {
+	return s.ID
+}
+
+// GetADIdentifiers returns the service AD identifiers
+func (s *ProcessService) GetADIdentifiers() ([]string, error) {
+	return s.ADIdentifiers, nil
+}
+
+// GetHosts returns the loopback address, the processes run on the host
+func (s *ProcessService) GetHosts() (map[string]string, error) {
+	return map[string]string{processNetwork: "127.0.0.1"}, nil
+}
+
+// GetPorts returns the TCP ports the process listened on when it was reported
+func (s *ProcessService) GetPorts() ([]int, error) {
+	return s.Ports, nil
+}
+
+// GetTags returns no tag, the tagger doesn't know the processes
+func (s *ProcessService) GetTags() ([]string, error) {
+	return []string{}, nil
+}
+
+// GetPid returns the pid of the process
+func (s *ProcessService) GetPid() (int, error) {
+	return s.Pid, nil
+}
+
+// GetHostname returns the hostname of the host
+func (s *ProcessService) GetHostname() (string, error) {
+	return os.Hostname()
+}
+
+// GetEnv returns the environment variables of the process, the environment of
+// the processes of other users can't be read
+func (s *ProcessService) GetEnv() (map[string]string, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(s.procRoot, strconv.Itoa(s.Pid), "environ"))
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string)
+	for _, v := range strings.Split(string(raw), "\x00") {
+		if parts := strings.SplitN(v, "=", 2); len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env, nil
+}
+
+// GetLabels is not supported, the processes don't have labels
+func (s *ProcessService) GetLabels() (map[string]string, error) {
+	return nil, errors.New("the processes don't have labels")
+}
+
+// GetNamedPorts is not supported, the ports of the processes don't have names
+func (s *ProcessService) GetNamedPorts() (map[string]int, error) {
+	return nil, errors.New("the ports of the processes don't have names")
+}
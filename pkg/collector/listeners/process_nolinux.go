@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+// +build !linux
+
+package listeners
+
+import (
+	"errors"
+)
+
+// ProcessListener is only supported on Linux
+type ProcessListener struct{}
+
+// NewProcessListener returns an error, the processes are listed from /proc
+func NewProcessListener() (*ProcessListener, error) {
+	return nil, errors.New("the process listener is only supported on Linux")
+}
+
+// Listen does nothing
+func (l *ProcessListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {}
+
+// Stop does nothing
+func (l *ProcessListener) Stop() {}
+
+// String returns a string representation of the ProcessListener
+func (l *ProcessListener) String() string {
+	return "Process Listener"
+}
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+// +build linux
+
+package listeners
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	procTCPHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	// 0.0.0.0:6379 listening, socket inode 1001
+	procTCPRedis = "   0: 00000000:18EB 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 1001 1 0000000000000000 100 0 0 10 0\n"
+	// 127.0.0.1:37000 connected to 6379, socket inode 1002
+	procTCPClient = "   1: 0100007F:9088 0100007F:18EB 01 00000000:00000000 00:00000000 00000000  1000        0 1002 1 0000000000000000 20 4 30 10 -1\n"
+)
+
+// fakeProc builds a /proc tree in a temporary folder
+type fakeProc struct {
+	t    *testing.T
+	root string
+}
+
+func newFakeProc(t *testing.T) *fakeProc {
+	root, err := ioutil.TempDir("", "proc")
+	require.Nil(t, err)
+	return &fakeProc{t: t, root: root}
+}
+
+// addProcess creates a process, `exe` is the target of its exe link, it's
+// not readable when empty
+func (p *fakeProc) addProcess(pid int, startTime uint64, exe string, cmdline []string, sockets ...string) {
+	dir := filepath.Join(p.root, strconv.Itoa(pid))
+	require.Nil(p.t, os.RemoveAll(dir))
+	require.Nil(p.t, os.MkdirAll(filepath.Join(dir, "fd"), 0755))
+	require.Nil(p.t, os.MkdirAll(filepath.Join(dir, "net"), 0755))
+
+	stat := fmt.Sprintf("%d (my (weird) comm) S 1 %d %d 0 -1 4194560 1 0 0 0 0 0 0 0 20 0 1 0 %d 1000 100", pid, pid, pid, startTime)
+	require.Nil(p.t, ioutil.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644))
+	raw := ""
+	if len(cmdline) > 0 {
+		raw = strings.Join(cmdline, "\x00") + "\x00"
+	}
+	require.Nil(p.t, ioutil.WriteFile(filepath.Join(dir, "cmdline"), []byte(raw), 0644))
+	require.Nil(p.t, ioutil.WriteFile(filepath.Join(dir, "environ"), []byte("HOME=/root\x00REDIS_PASSWORD=a=b\x00"), 0644))
+	if exe != "" {
+		require.Nil(p.t, os.Symlink(exe, filepath.Join(dir, "exe")))
+	}
+	require.Nil(p.t, ioutil.WriteFile(filepath.Join(dir, "net", "tcp"), []byte(procTCPHeader+procTCPRedis+procTCPClient), 0644))
+
+	for i, socket := range sockets {
+		require.Nil(p.t, os.Symlink(socket, filepath.Join(dir, "fd", strconv.Itoa(i+3))))
+	}
+}
+
+func (p *fakeProc) removeProcess(pid int) {
+	require.Nil(p.t, os.RemoveAll(filepath.Join(p.root, strconv.Itoa(pid))))
+}
+
+func TestParseProcessRules(t *testing.T) {
+	rules, err := parseProcessRules([]processRuleConfig{
+		{ADIdentifier: "redis", Exe: "redis-server"},
+		{ADIdentifier: "my-app", Cmdline: `java .*-jar /opt/app\.jar`},
+	})
+	require.Nil(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "redis-server", rules[0].exe)
+	assert.True(t, rules[1].cmdline.MatchString("/usr/bin/java -Xmx1g -jar /opt/app.jar"))
+
+	for _, rc := range []processRuleConfig{
+		{Exe: "redis-server"},
+		{ADIdentifier: "redis"},
+		{ADIdentifier: "redis", Exe: "redis-server", Cmdline: "redis"},
+		{ADIdentifier: "redis", Cmdline: "redis("},
+	} {
+		_, err := parseProcessRules([]processRuleConfig{rc})
+		assert.NotNil(t, err, "%v", rc)
+	}
+}
+
+func TestProcessListenerPoll(t *testing.T) {
+	proc := newFakeProc(t)
+	defer os.RemoveAll(proc.root)
+	rules, err := parseProcessRules([]processRuleConfig{
+		{ADIdentifier: "redis", Exe: "redis-server"},
+		{ADIdentifier: "my-app", Cmdline: `java .*-jar /opt/app\.jar`},
+	})
+	require.Nil(t, err)
+
+	// redis listens, the java app doesn't yet, the other ones match no rule
+	proc.addProcess(10, 1000, "/usr/bin/redis-server", []string{"redis-server *:6379"}, "socket:[1001]", "pipe:[42]", "/dev/null")
+	proc.addProcess(11, 1000, "", []string{"/usr/bin/java", "-jar", "/opt/app.jar"})
+	proc.addProcess(12, 1000, "/bin/bash", []string{"bash"}, "socket:[1002]")
+	proc.addProcess(2, 5, "", nil)
+	require.Nil(t, os.MkdirAll(filepath.Join(proc.root, "net"), 0755))
+
+	newSvc := make(chan Service, 10)
+	delSvc := make(chan Service, 10)
+	l := newProcessListener(proc.root, rules)
+	l.newService = newSvc
+	l.delService = delSvc
+
+	l.poll()
+	require.Len(t, newSvc, 1)
+	redis := (<-newSvc).(*ProcessService)
+	assert.Equal(t, ID("process://10"), redis.GetID())
+	ids, err := redis.GetADIdentifiers()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"process://10", "redis"}, ids)
+	ports, err := redis.GetPorts()
+	assert.Nil(t, err)
+	assert.Equal(t, []int{6379}, ports)
+	pid, err := redis.GetPid()
+	assert.Nil(t, err)
+	assert.Equal(t, 10, pid)
+	hosts, err := redis.GetHosts()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"host": "127.0.0.1"}, hosts)
+	env, err := redis.GetEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"HOME": "/root", "REDIS_PASSWORD": "a=b"}, env)
+	_, err = redis.GetNamedPorts()
+	assert.NotNil(t, err)
+	assert.Len(t, l.pending, 1)
+	assert.Equal(t, map[int]ignoredProcess{
+		12: {startTime: 1000, cmdline: "bash", exe: "bash"},
+		2:  {startTime: 5},
+	}, l.ignored)
+
+	// the java app is reported once its grace period is over
+	l.pending["process://11"].firstSeen = l.pending["process://11"].firstSeen.Add(-processPortsGracePeriod)
+	l.poll()
+	require.Len(t, newSvc, 1)
+	app := (<-newSvc).(*ProcessService)
+	ids, _ = app.GetADIdentifiers()
+	assert.Equal(t, []string{"process://11", "my-app"}, ids)
+	ports, _ = app.GetPorts()
+	assert.Empty(t, ports)
+	assert.Len(t, l.GetServices(), 2)
+
+	// nothing changed
+	l.poll()
+	assert.Len(t, newSvc, 0)
+	assert.Len(t, delSvc, 0)
+
+	// redis exits, its pid is reused by another redis, the java app exits
+	proc.addProcess(10, 2000, "/usr/bin/redis-server", []string{"redis-server *:6379"}, "socket:[1001]")
+	proc.removeProcess(11)
+	l.poll()
+	require.Len(t, delSvc, 2)
+	removed := []ID{(<-delSvc).GetID(), (<-delSvc).GetID()}
+	assert.Contains(t, removed, ID("process://10"))
+	assert.Contains(t, removed, ID("process://11"))
+	require.Len(t, newSvc, 1)
+	redis = (<-newSvc).(*ProcessService)
+	assert.Equal(t, ID("process://10"), redis.GetID())
+	assert.Equal(t, uint64(2000), redis.StartTime)
+
+	// the ignored processes are forgotten when they exit
+	proc.removeProcess(12)
+	l.poll()
+	assert.Equal(t, map[int]ignoredProcess{2: {startTime: 5}}, l.ignored)
+	assert.Len(t, newSvc, 0)
+	assert.Len(t, delSvc, 0)
+}
+
+func TestProcessListenerExec(t *testing.T) {
+	proc := newFakeProc(t)
+	defer os.RemoveAll(proc.root)
+	rules, err := parseProcessRules([]processRuleConfig{{ADIdentifier: "redis", Exe: "redis-server"}})
+	require.Nil(t, err)
+
+	// a shell wrapper exec's redis, keeping its pid and start time
+	proc.addProcess(10, 1000, "/bin/sh", []string{"/bin/sh", "/usr/local/bin/start-redis.sh"})
+	newSvc := make(chan Service, 10)
+	delSvc := make(chan Service, 10)
+	l := newProcessListener(proc.root, rules)
+	l.newService = newSvc
+	l.delService = delSvc
+
+	l.poll()
+	assert.Len(t, newSvc, 0)
+	assert.Contains(t, l.ignored, 10)
+
+	proc.addProcess(10, 1000, "/usr/bin/redis-server", []string{"redis-server *:6379"}, "socket:[1001]")
+	l.poll()
+	require.Len(t, newSvc, 1)
+	redis := (<-newSvc).(*ProcessService)
+	assert.Equal(t, ID("process://10"), redis.GetID())
+	assert.Equal(t, uint64(1000), redis.StartTime)
+	assert.Empty(t, l.ignored)
+	assert.Len(t, delSvc, 0)
+}
+
+func TestGetProcessStartTime(t *testing.T) {
+	proc := newFakeProc(t)
+	defer os.RemoveAll(proc.root)
+	proc.addProcess(42, 123456, "", []string{"sleep", "60"})
+
+	start, err := getProcessStartTime(proc.root, 42)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(123456), start)
+
+	_, err = getProcessStartTime(proc.root, 43)
+	assert.NotNil(t, err)
+}
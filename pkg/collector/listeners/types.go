@@ -14,7 +14,10 @@ type DockerService struct {
 	ADIdentifiers []string          // identifiers on which templates will be matched
 	Hosts         map[string]string // network --> IP address
 	Ports         []int
-	Pid           int // Process identifier
+	Pid           int               // Process identifier
+	Hostname      string            // hostname of the container
+	Env           map[string]string // environment variable --> value
+	Labels        map[string]string // label --> value
 }
 
 // Service represents an application we can run a check against.
@@ -27,6 +30,10 @@ type Service interface {
 	GetPorts() ([]int, error)
 	GetTags() ([]string, error)
 	GetPid() (int, error)
+	GetHostname() (string, error)
+	GetEnv() (map[string]string, error)
+	GetLabels() (map[string]string, error)
+	GetNamedPorts() (map[string]int, error)
 }
 
 // ServiceListener monitors running services and triggers check (un)scheduling
@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package metadata
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/metadata/inventory"
+	md "github.com/DataDog/datadog-agent/pkg/metadata"
+	"github.com/DataDog/datadog-agent/pkg/serializer"
+)
+
+// InventoryCollector sends the inventory of the Agent: version, build tags,
+// config providers, listeners and the origin of every check instance
+type InventoryCollector struct{}
+
+// Send collects the data needed and submits the payload
+func (ic *InventoryCollector) Send(s *serializer.Serializer) error {
+	payload := inventory.GetPayload()
+	if err := s.SendMetadata(payload); err != nil {
+		return fmt.Errorf("unable to submit inventory metadata payload, %s", err)
+	}
+	return nil
+}
+
+func init() {
+	md.RegisterCollector("inventory", new(InventoryCollector))
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package inventory
+
+import (
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/autodiscovery"
+	"github.com/DataDog/datadog-agent/pkg/collector/runner"
+	"github.com/DataDog/datadog-agent/pkg/metadata/common"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// GetPayload builds a payload describing the Agent and every check instance
+// it loaded, along with where its configuration comes from
+func GetPayload() *Payload {
+	hostname, _ := util.GetHostname()
+	inv := autodiscovery.GetInventory()
+
+	payload := &Payload{
+		Payload:        *common.GetPayload(hostname),
+		Timestamp:      time.Now().Unix(),
+		Agent:          getAgentInfo(inv),
+		CheckInstances: []CheckInstance{},
+		LoaderErrors:   map[string]map[string]string{},
+	}
+
+	checkStats := runner.GetCheckStats()
+	runErrors := autodiscovery.GetRunErrors()
+	for _, c := range inv.Checks {
+		instance := CheckInstance{
+			ID:           c.ID,
+			Name:         c.Name,
+			Loader:       c.Loader,
+			ConfigDigest: c.ConfigDigest,
+			Provider:     c.Provider,
+		}
+		if stats, found := checkStats[c.ID]; found {
+			instance.LastError = stats.LastError
+			instance.TotalErrors = stats.TotalErrors
+			if stats.LastError != "" {
+				instance.LastErrorTime = stats.UpdateTimestamp
+			}
+		}
+		// an error while scheduling the instance means it never ran
+		if err, found := runErrors[c.ID]; found && instance.LastError == "" {
+			instance.LastError = err
+		}
+		payload.CheckInstances = append(payload.CheckInstances, instance)
+	}
+
+	for check, errs := range autodiscovery.GetLoaderErrors() {
+		payload.LoaderErrors[check] = errs
+	}
+
+	return payload
+}
+
+func getAgentInfo(inv autodiscovery.Inventory) AgentInfo {
+	info := AgentInfo{
+		Version:         version.AgentVersion,
+		BuildTags:       []string{},
+		GoVersion:       runtime.Version(),
+		ConfigProviders: inv.Providers,
+		Listeners:       inv.Listeners,
+	}
+	if av, err := version.New(version.AgentVersion); err == nil {
+		info.Commit = av.Commit
+	}
+	if version.AgentBuildTags != "" {
+		info.BuildTags = strings.Split(version.AgentBuildTags, ",")
+	}
+
+	return info
+}
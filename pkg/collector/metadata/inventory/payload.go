@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/metadata/common"
+	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+)
+
+// Payload handles the JSON unmarshalling of the inventory metadata payload
+type Payload struct {
+	common.Payload
+	Timestamp      int64                        `json:"timestamp"`
+	Agent          AgentInfo                    `json:"agent"`
+	CheckInstances []CheckInstance              `json:"check_instances"`
+	LoaderErrors   map[string]map[string]string `json:"loader_errors"`
+}
+
+// AgentInfo describes the running Agent and how it was built
+type AgentInfo struct {
+	Version         string   `json:"version"`
+	Commit          string   `json:"commit"`
+	BuildTags       []string `json:"build_tags"`
+	GoVersion       string   `json:"go_version"`
+	ConfigProviders []string `json:"config_providers"`
+	Listeners       []string `json:"listeners"`
+}
+
+// CheckInstance describes a check instance loaded by the Agent
+type CheckInstance struct {
+	ID            check.ID `json:"id"`
+	Name          string   `json:"name"`
+	Loader        string   `json:"loader"`
+	ConfigDigest  string   `json:"config_digest"`
+	Provider      string   `json:"provider"`
+	LastError     string   `json:"last_error"`
+	LastErrorTime int64    `json:"last_error_time"` // unix timestamp of the run that reported LastError
+	TotalErrors   uint64   `json:"total_errors"`
+}
+
+// MarshalJSON serialization a Payload to JSON
+func (p *Payload) MarshalJSON() ([]byte, error) {
+	// use an alias to avoid infinit recursion while serializing
+	type PayloadAlias Payload
+
+	return json.Marshal((*PayloadAlias)(p))
+}
+
+// Marshal not implemented
+func (p *Payload) Marshal() ([]byte, error) {
+	return nil, fmt.Errorf("Inventory Payload serialization is not implemented")
+}
+
+// SplitPayload breaks the payload into times number of pieces
+func (p *Payload) SplitPayload(times int) ([]marshaler.Marshaler, error) {
+	return nil, fmt.Errorf("Inventory Payload splitting is not implemented")
+}
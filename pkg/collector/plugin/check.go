@@ -0,0 +1,293 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package plugin
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/plugin/sdk"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	log "github.com/cihub/seelog"
+)
+
+var (
+	pluginStats   = expvar.NewMap("plugins")
+	crashStats    = expvar.Map{}
+	restartsStats = expvar.Map{}
+
+	// a plugin that crashed is restarted at the next run, then after a
+	// backoff doubling at every crash in a row
+	restartBackoffMin = 2 * time.Second
+	restartBackoffMax = 5 * time.Minute
+)
+
+func init() {
+	crashStats.Init()
+	restartsStats.Init()
+	pluginStats.Set("Crashes", &crashStats)
+	pluginStats.Set("Restarts", &restartsStats)
+}
+
+// pluginInitConfig holds the settings of the init_config used by the agent
+type pluginInitConfig struct {
+	Transport string `yaml:"plugin_transport" default:"stdio"`
+}
+
+// PluginCheck runs a check served by an external binary. The process is
+// started when the check is configured and restarted when it crashes.
+type PluginCheck struct {
+	core.CheckBase
+	path       string
+	transport  string
+	instance   check.ConfigData
+	initConfig check.ConfigData
+	process    *process
+	crashes    int       // consecutive crashes
+	restartAt  time.Time // the process isn't restarted before
+	m          sync.Mutex
+}
+
+// NewPluginCheck returns a check named `name` served by the binary at `path`
+func NewPluginCheck(name, path string) *PluginCheck {
+	return &PluginCheck{
+		CheckBase: core.NewCheckBase(name),
+		path:      path,
+	}
+}
+
+// Configure starts the plugin and passes it the configuration
+func (c *PluginCheck) Configure(data check.ConfigData, initConfig check.ConfigData) error {
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+	conf := pluginInitConfig{}
+	if err := core.UnmarshalConfig(initConfig, &conf); err != nil {
+		return err
+	}
+	if conf.Transport != transportStdio && conf.Transport != transportUnix {
+		return fmt.Errorf("invalid plugin_transport %q, it must be %q or %q", conf.Transport, transportStdio, transportUnix)
+	}
+
+	c.BuildID(data, initConfig)
+	c.transport = conf.Transport
+	c.instance = data
+	c.initConfig = initConfig
+
+	c.m.Lock()
+	defer c.m.Unlock()
+	p, err := c.start()
+	if err != nil {
+		return err
+	}
+	c.process = p
+	return nil
+}
+
+// Run asks the plugin to run the check, restarting it first if it crashed
+func (c *PluginCheck) Run() error {
+	sender, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	p, err := c.getProcess()
+	if err != nil {
+		return err
+	}
+
+	err = p.call(sdk.MethodRun, nil, func(msg sdk.Message) {
+		c.submit(sender, msg)
+	})
+	if crash, ok := err.(*CrashError); ok {
+		c.crashed(p, crash)
+		return err
+	}
+
+	c.m.Lock()
+	c.crashes = 0
+	c.m.Unlock()
+
+	sender.Commit()
+	return err
+}
+
+// Stop stops the plugin
+func (c *PluginCheck) Stop() {
+	c.m.Lock()
+	p := c.process
+	c.process = nil
+	c.m.Unlock()
+
+	if p != nil {
+		p.stop()
+	}
+}
+
+// start runs the plugin binary and configures it, c.m must be held
+func (c *PluginCheck) start() (*process, error) {
+	p, err := startProcess(string(c.ID()), c.path, c.transport)
+	if err != nil {
+		return nil, fmt.Errorf("can't start the plugin %s: %s", c.path, err)
+	}
+
+	params := sdk.ConfigureParams{
+		Instance:   string(c.instance),
+		InitConfig: string(c.initConfig),
+	}
+	if err := p.call(sdk.MethodConfigure, params, nil); err != nil {
+		p.kill()
+		return nil, fmt.Errorf("can't configure the plugin %s: %s", c.path, err)
+	}
+	return p, nil
+}
+
+// getProcess returns the running plugin, restarting it if it crashed and
+// the restart backoff elapsed
+func (c *PluginCheck) getProcess() (*process, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.process != nil {
+		return c.process, nil
+	}
+
+	if now := time.Now(); now.Before(c.restartAt) {
+		return nil, fmt.Errorf("the plugin crashed %d times in a row, restarting it in %s", c.crashes, c.restartAt.Sub(now))
+	}
+
+	log.Infof("Restarting plugin %s for check %s", c.path, c.ID())
+	restartsStats.Add(string(c.ID()), 1)
+	p, err := c.start()
+	if err != nil {
+		c.crashes++
+		c.restartAt = time.Now().Add(restartBackoff(c.crashes))
+		return nil, err
+	}
+	c.process = p
+	return p, nil
+}
+
+// crashed forgets a process that crashed, it'll be restarted at the next
+// run once the backoff elapsed
+func (c *PluginCheck) crashed(p *process, err error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.process != p {
+		// stopped meanwhile
+		return
+	}
+	log.Errorf("Plugin %s for check %s: %s", c.path, c.ID(), err)
+	crashStats.Add(string(c.ID()), 1)
+	c.process = nil
+	c.crashes++
+	c.restartAt = time.Now().Add(restartBackoff(c.crashes))
+}
+
+// restartBackoff returns how long to wait before restarting a plugin that
+// crashed `crashes` times in a row
+func restartBackoff(crashes int) time.Duration {
+	if crashes <= 1 {
+		return 0
+	}
+	backoff := restartBackoffMin
+	for i := 2; i < crashes && backoff < restartBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > restartBackoffMax {
+		backoff = restartBackoffMax
+	}
+	return backoff
+}
+
+// submit forwards the data sent by the plugin to the sender, the instance
+// tags are added to it
+func (c *PluginCheck) submit(sender aggregator.Sender, msg sdk.Message) {
+	var err error
+	switch msg.Method {
+	case sdk.MethodMetric:
+		m := sdk.MetricParams{}
+		if err = json.Unmarshal(msg.Params, &m); err != nil {
+			break
+		}
+		tags := c.mergeTags(m.Tags)
+		switch m.Type {
+		case sdk.Gauge:
+			sender.Gauge(m.Name, m.Value, m.Hostname, tags)
+		case sdk.Rate:
+			sender.Rate(m.Name, m.Value, m.Hostname, tags)
+		case sdk.Count:
+			sender.Count(m.Name, m.Value, m.Hostname, tags)
+		case sdk.MonotonicCount:
+			sender.MonotonicCount(m.Name, m.Value, m.Hostname, tags)
+		case sdk.Counter:
+			sender.Counter(m.Name, m.Value, m.Hostname, tags)
+		case sdk.Histogram:
+			sender.Histogram(m.Name, m.Value, m.Hostname, tags)
+		case sdk.Historate:
+			sender.Historate(m.Name, m.Value, m.Hostname, tags)
+		default:
+			err = fmt.Errorf("unknown metric type %q", m.Type)
+		}
+	case sdk.MethodServiceCheck:
+		sc := sdk.ServiceCheckParams{}
+		if err = json.Unmarshal(msg.Params, &sc); err != nil {
+			break
+		}
+		status, e := metrics.GetServiceCheckStatus(int(sc.Status))
+		if e != nil {
+			err = e
+			break
+		}
+		sender.ServiceCheck(sc.Name, status, sc.Hostname, c.mergeTags(sc.Tags), sc.Message)
+	case sdk.MethodEvent:
+		e := sdk.EventParams{}
+		if err = json.Unmarshal(msg.Params, &e); err != nil {
+			break
+		}
+		if e.Timestamp == 0 {
+			e.Timestamp = time.Now().Unix()
+		}
+		sender.Event(metrics.Event{
+			Title:          e.Title,
+			Text:           e.Text,
+			Ts:             e.Timestamp,
+			Priority:       metrics.EventPriority(e.Priority),
+			Host:           e.Host,
+			Tags:           c.mergeTags(e.Tags),
+			AlertType:      metrics.EventAlertType(e.AlertType),
+			AggregationKey: e.AggregationKey,
+			SourceTypeName: e.SourceTypeName,
+		})
+	case sdk.MethodWarning:
+		w := sdk.WarningParams{}
+		if err = json.Unmarshal(msg.Params, &w); err != nil {
+			break
+		}
+		c.Warn(w.Message)
+	default:
+		err = fmt.Errorf("unknown notification %q", msg.Method)
+	}
+
+	if err != nil {
+		c.Warnf("Plugin %s sent invalid data: %s", c.path, err)
+	}
+}
+
+// mergeTags returns the tags sent by the plugin along with the instance tags
+func (c *PluginCheck) mergeTags(tags []string) []string {
+	if len(c.Tags()) == 0 {
+		return tags
+	}
+	return append(append([]string{}, tags...), c.Tags()...)
+}
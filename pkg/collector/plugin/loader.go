@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/loaders"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
+	log "github.com/cihub/seelog"
+)
+
+// PluginCheckLoader loads the checks served by external binaries, a check
+// named `foo` is served by the executable `foo` (`foo.exe` on Windows) found
+// in one of the search paths
+type PluginCheckLoader struct {
+	paths []string
+}
+
+// NewPluginCheckLoader creates a loader searching plugins in the
+// `plugin_checks_path` and `additional_checksd` folders
+func NewPluginCheckLoader() (*PluginCheckLoader, error) {
+	paths := []string{}
+	for _, key := range []string{"plugin_checks_path", "additional_checksd"} {
+		if path := config.Datadog.GetString(key); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return &PluginCheckLoader{paths: paths}, nil
+}
+
+// Load returns a list of checks, one for every configuration instance found in `config`
+func (l *PluginCheckLoader) Load(config check.Config) ([]check.Check, error) {
+	checks := []check.Check{}
+
+	path, err := l.find(config.Name)
+	if err != nil {
+		return checks, err
+	}
+
+	errs := []string{}
+	for _, instance := range config.Instances {
+		c := NewPluginCheck(config.Name, path)
+		if err := c.Configure(instance, config.InitConfig); err != nil {
+			log.Errorf("plugin.loader: could not configure check %s: %s", c, secrets.Scrub(err.Error()))
+			errs = append(errs, err.Error())
+			continue
+		}
+		checks = append(checks, c)
+	}
+
+	if len(checks) == 0 && len(errs) > 0 {
+		return checks, fmt.Errorf("could not configure any instance of %s: %s", config.Name, strings.Join(errs, "; "))
+	}
+	return checks, nil
+}
+
+// find returns the path of the plugin serving the check `name`
+func (l *PluginCheckLoader) find(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid plugin name %q", name)
+	}
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	for _, dir := range l.paths {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if runtime.GOOS != "windows" {
+			if info.Mode().Perm()&0111 == 0 {
+				log.Debugf("plugin.loader: %s is not executable, skipping", path)
+				continue
+			}
+			if info.Mode().Perm()&0002 != 0 {
+				log.Warnf("plugin.loader: %s is world-writable, refusing to run it", path)
+				continue
+			}
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("plugin %s not found in %s", name, strings.Join(l.paths, ", "))
+}
+
+func (l *PluginCheckLoader) String() string {
+	return "Plugin Check Loader"
+}
+
+func init() {
+	factory := func() (check.Loader, error) {
+		return NewPluginCheckLoader()
+	}
+
+	loaders.RegisterLoader(25, factory)
+}
@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/plugin/sdk"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// the test binary serves testPlugin when this is set
+const helperEnvVar = "DD_PLUGIN_TEST_HELPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnvVar) == "1" {
+		if err := sdk.Serve(&testPlugin{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	os.Setenv(helperEnvVar, "1")
+	os.Exit(m.Run())
+}
+
+type testPlugin struct {
+	Mode   string `yaml:"mode"`
+	Marker string `yaml:"marker"`
+}
+
+func (p *testPlugin) Configure(instance, initConfig []byte) error {
+	if err := yaml.Unmarshal(instance, p); err != nil {
+		return err
+	}
+	if p.Mode == "invalid" {
+		return errors.New("invalid mode")
+	}
+	return nil
+}
+
+func (p *testPlugin) Run(s *sdk.Sender) error {
+	switch p.Mode {
+	case "fail":
+		return errors.New("boom")
+	case "crash":
+		os.Exit(3)
+	case "crash_once":
+		if _, err := os.Stat(p.Marker); os.IsNotExist(err) {
+			ioutil.WriteFile(p.Marker, nil, 0644)
+			os.Exit(3)
+		}
+	}
+	s.Gauge("test.gauge", 1, "", []string{"foo:bar"})
+	s.ServiceCheck("test.can_connect", sdk.ServiceCheckOK, "", nil, "")
+	s.Warn("careful")
+	return s.Err()
+}
+
+func (p *testPlugin) Stop() {}
+
+func newTestCheck(t *testing.T, instance, initConfig string) *PluginCheck {
+	c := NewPluginCheck("test", os.Args[0])
+	require.Nil(t, c.Configure([]byte(instance), []byte(initConfig)))
+	return c
+}
+
+func expectRun(c *PluginCheck) *aggregator.MockSender {
+	sender := aggregator.NewMockSender(c.ID())
+	sender.On("Gauge", "test.gauge", 1.0, "", []string{"foo:bar", "env:test"}).Return().Times(1)
+	sender.On("ServiceCheck", "test.can_connect", metrics.ServiceCheckOK, "", []string{"env:test"}, "").Return().Times(1)
+	sender.On("Commit").Return().Times(1)
+	return sender
+}
+
+func TestRun(t *testing.T) {
+	for _, transport := range []string{transportStdio, transportUnix} {
+		c := newTestCheck(t, "tags: [\"env:test\"]", "plugin_transport: "+transport)
+		sender := expectRun(c)
+
+		require.Nil(t, c.Run(), transport)
+		sender.AssertExpectations(t)
+		warnings := c.GetWarnings()
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "careful", warnings[0].Error())
+
+		c.Stop()
+		assert.Nil(t, c.process)
+	}
+}
+
+func TestConfigureErrors(t *testing.T) {
+	c := NewPluginCheck("test", os.Args[0])
+	err := c.Configure([]byte("mode: invalid"), nil)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid mode")
+
+	err = c.Configure(nil, []byte("plugin_transport: carrier_pigeon"))
+	assert.NotNil(t, err)
+
+	c = NewPluginCheck("test", "/does/not/exist")
+	err = c.Configure(nil, nil)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "can't start the plugin")
+}
+
+func TestRunError(t *testing.T) {
+	c := newTestCheck(t, "mode: fail", "")
+	defer c.Stop()
+	sender := aggregator.NewMockSender(c.ID())
+	sender.On("Commit").Return().Times(1)
+
+	err := c.Run()
+	require.NotNil(t, err)
+	assert.Equal(t, "boom", err.Error())
+	sender.AssertExpectations(t)
+	// the plugin keeps running
+	assert.NotNil(t, c.process)
+}
+
+func TestCrashRestart(t *testing.T) {
+	marker := filepath.Join(os.TempDir(), fmt.Sprintf("dd-plugin-test-%d", time.Now().UnixNano()))
+	defer os.Remove(marker)
+
+	c := newTestCheck(t, fmt.Sprintf("mode: crash_once\nmarker: %s\ntags: [\"env:test\"]", marker), "")
+	defer c.Stop()
+	sender := expectRun(c)
+
+	err := c.Run()
+	require.NotNil(t, err)
+	_, ok := err.(*CrashError)
+	assert.True(t, ok)
+	assert.Nil(t, c.process)
+	assert.Equal(t, 1, c.crashes)
+
+	// restarted right away the first time
+	require.Nil(t, c.Run())
+	sender.AssertExpectations(t)
+	assert.Equal(t, 0, c.crashes)
+}
+
+func TestCrashLoopBackoff(t *testing.T) {
+	c := newTestCheck(t, "mode: crash", "")
+	defer c.Stop()
+	aggregator.NewMockSender(c.ID())
+
+	_, ok := c.Run().(*CrashError)
+	require.True(t, ok)
+	_, ok = c.Run().(*CrashError)
+	require.True(t, ok)
+
+	// crashed twice in a row, the restart is delayed
+	err := c.Run()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the plugin crashed 2 times in a row, restarting it in")
+	assert.Nil(t, c.process)
+}
+
+func TestRestartBackoff(t *testing.T) {
+	assert.Equal(t, time.Duration(0), restartBackoff(1))
+	assert.Equal(t, restartBackoffMin, restartBackoff(2))
+	assert.Equal(t, 2*restartBackoffMin, restartBackoff(3))
+	assert.Equal(t, restartBackoffMax, restartBackoff(100))
+}
+
+func TestSubmitInvalid(t *testing.T) {
+	c := NewPluginCheck("test", os.Args[0])
+	sender := aggregator.NewMockSender("test")
+	sender.On("Event", mock.AnythingOfType("metrics.Event")).Return().Times(1)
+
+	c.submit(sender, sdk.Message{Method: sdk.MethodMetric, Params: []byte(`{"type": "distribution", "name": "foo"}`)})
+	c.submit(sender, sdk.Message{Method: "log", Params: []byte(`{}`)})
+	c.submit(sender, sdk.Message{Method: sdk.MethodEvent, Params: []byte(`{"title": "foo"}`)})
+
+	sender.AssertExpectations(t)
+	assert.Len(t, c.GetWarnings(), 2)
+}
+
+func TestLoaderFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dd-plugins-")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "foo"), []byte("#!/bin/sh"), 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "notexec"), []byte("#!/bin/sh"), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "writable"), []byte("#!/bin/sh"), 0755))
+	require.Nil(t, os.Chmod(filepath.Join(dir, "writable"), 0777))
+
+	l := &PluginCheckLoader{paths: []string{"/does/not/exist", dir}}
+	path, err := l.find("foo")
+	require.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "foo"), path)
+
+	for _, name := range []string{"bar", "notexec", "writable", "../foo", "", ".foo"} {
+		_, err = l.find(name)
+		assert.NotNil(t, err, name)
+	}
+}
@@ -0,0 +1,310 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/plugin/sdk"
+	log "github.com/cihub/seelog"
+)
+
+// Transports a plugin can speak over
+const (
+	transportStdio = "stdio"
+	transportUnix  = "unix"
+)
+
+var (
+	// how long a plugin has to connect to the Unix socket
+	connectTimeout = 10 * time.Second
+	// how long a plugin has to exit once asked to stop
+	stopGracePeriod = 5 * time.Second
+)
+
+// CrashError is returned when the plugin process exited or its connection
+// broke while handling a request
+type CrashError struct {
+	Err error
+}
+
+// Error returns the error message
+func (e *CrashError) Error() string {
+	return fmt.Sprintf("plugin crashed: %s", e.Err)
+}
+
+// process is a running plugin binary, requests are sent one at a time
+type process struct {
+	name    string
+	cmd     *exec.Cmd
+	conn    io.ReadWriteCloser
+	enc     *json.Encoder
+	dec     *json.Decoder
+	nextID  uint64
+	exited  chan struct{} // closed when the process exits
+	exitErr error         // set before exited is closed
+	logs    *io.PipeWriter
+	tmpDir  string // holds the Unix socket
+	m       sync.Mutex
+}
+
+// startProcess runs the plugin binary at `path`, it returns once the
+// protocol connection is established
+func startProcess(name, path, transport string) (*process, error) {
+	p := &process{
+		name:   name,
+		cmd:    exec.Command(path),
+		exited: make(chan struct{}),
+	}
+	p.logs = newLogWriter(name)
+	p.cmd.Stderr = p.logs
+
+	var err error
+	switch transport {
+	case transportStdio:
+		err = p.startStdio()
+	case transportUnix:
+		err = p.startUnix()
+	default:
+		err = fmt.Errorf("unknown transport %q", transport)
+	}
+	if err != nil {
+		p.kill()
+		return nil, err
+	}
+
+	p.enc = json.NewEncoder(p.conn)
+	p.dec = json.NewDecoder(p.conn)
+	return p, nil
+}
+
+// startStdio speaks with the plugin over pipes to its stdin and stdout.
+// The pipes are plain files so the output written by the plugin before
+// exiting can still be read once it exited.
+func (p *process) startStdio() error {
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		stdinR.Close()
+		stdinW.Close()
+		return err
+	}
+	p.cmd.Stdin = stdinR
+	p.cmd.Stdout = stdoutW
+	p.conn = &pipeConn{Reader: stdoutR, Writer: stdinW, closers: []io.Closer{stdinW, stdoutR}}
+
+	err = p.run()
+	// the child has its own copies
+	stdinR.Close()
+	stdoutW.Close()
+	return err
+}
+
+// startUnix gives the plugin the path of a Unix socket to connect to, its
+// stdout is logged along with its stderr
+func (p *process) startUnix() error {
+	var err error
+	p.tmpDir, err = ioutil.TempDir("", "dd-plugin-")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(p.tmpDir, "plugin.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	p.cmd.Stdout = p.cmd.Stderr
+	p.cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", sdk.SocketEnvVar, path))
+	if err = p.run(); err != nil {
+		return err
+	}
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	select {
+	case conn := <-accepted:
+		p.conn = conn
+		return nil
+	case <-p.exited:
+		return fmt.Errorf("the plugin exited before connecting: %v", p.exitErr)
+	case <-time.After(connectTimeout):
+		return fmt.Errorf("the plugin didn't connect within %s", connectTimeout)
+	}
+}
+
+// run starts the process and watches its exit
+func (p *process) run() error {
+	if err := p.cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		p.exitErr = p.cmd.Wait()
+		if p.exitErr == nil {
+			p.exitErr = errors.New("exit status 0")
+		}
+		p.logs.Close()
+		close(p.exited)
+	}()
+	return nil
+}
+
+// call sends a request and waits for its response, the notifications sent
+// by the plugin meanwhile are passed to `notify`. The error is a CrashError
+// when the plugin crashed, the error returned by the plugin otherwise.
+func (p *process) call(method string, params interface{}, notify func(sdk.Message)) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	req := sdk.Message{Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = raw
+	}
+	p.nextID++
+	req.ID = p.nextID
+
+	if err := p.enc.Encode(req); err != nil {
+		return p.crashed(err)
+	}
+
+	for {
+		var msg sdk.Message
+		if err := p.dec.Decode(&msg); err != nil {
+			return p.crashed(err)
+		}
+		if msg.ID == 0 {
+			if notify != nil {
+				notify(msg)
+			}
+			continue
+		}
+		if msg.ID != req.ID {
+			log.Warnf("Plugin %s: ignoring the response to unknown request %d", p.name, msg.ID)
+			continue
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+		return nil
+	}
+}
+
+// crashed returns the error to report when the connection with the plugin
+// broke, the process is killed if it's still running
+func (p *process) crashed(err error) error {
+	select {
+	case <-p.exited:
+		err = p.exitErr
+	case <-time.After(time.Second):
+		log.Warnf("Plugin %s: the connection broke (%s), killing the process", p.name, err)
+	}
+	p.kill()
+	return &CrashError{Err: err}
+}
+
+// stop asks the plugin to stop, the process is killed if it doesn't exit
+// within the grace period
+func (p *process) stop() {
+	done := make(chan struct{})
+	go func() {
+		if err := p.call(sdk.MethodStop, nil, nil); err != nil {
+			log.Debugf("Plugin %s: error stopping: %s", p.name, err)
+		}
+		close(done)
+	}()
+
+	timeout := time.After(stopGracePeriod)
+	select {
+	case <-done:
+	case <-timeout:
+	}
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	select {
+	case <-p.exited:
+	case <-timeout:
+		log.Warnf("Plugin %s didn't exit within %s, killing it", p.name, stopGracePeriod)
+	}
+	p.kill()
+}
+
+// kill terminates the process and frees its resources
+func (p *process) kill() {
+	if p.cmd.Process != nil {
+		select {
+		case <-p.exited:
+		default:
+			p.cmd.Process.Kill()
+		}
+	}
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	if p.tmpDir != "" {
+		os.RemoveAll(p.tmpDir)
+	}
+	if p.cmd.Process == nil {
+		// never started
+		p.logs.Close()
+	}
+}
+
+// pipeConn joins the pipes to the stdin and stdout of the plugin
+type pipeConn struct {
+	io.Reader
+	io.Writer
+	closers []io.Closer
+	once    sync.Once
+}
+
+// Close closes both pipes
+func (c *pipeConn) Close() error {
+	c.once.Do(func() {
+		for _, closer := range c.closers {
+			closer.Close()
+		}
+	})
+	return nil
+}
+
+// newLogWriter returns a writer logging every line written by a plugin,
+// until it's closed
+func newLogWriter(name string) *io.PipeWriter {
+	r, w := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			log.Infof("Plugin %s: %s", name, scanner.Text())
+		}
+		r.CloseWithError(scanner.Err())
+	}()
+	return w
+}
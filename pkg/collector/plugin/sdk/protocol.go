@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package sdk
+
+import "encoding/json"
+
+// SocketEnvVar is the environment variable giving the path of the Unix
+// socket a plugin connects to. When it's not set, the plugin speaks the
+// protocol over its stdin and stdout.
+const SocketEnvVar = "DD_PLUGIN_SOCKET"
+
+// Methods of the requests sent by the agent to the plugin
+const (
+	MethodConfigure = "configure"
+	MethodRun       = "run"
+	MethodStop      = "stop"
+)
+
+// Methods of the notifications sent by the plugin to the agent while a check
+// runs
+const (
+	MethodMetric       = "metric"
+	MethodServiceCheck = "service_check"
+	MethodEvent        = "event"
+	MethodWarning      = "warning"
+)
+
+// Metric types
+const (
+	Gauge          = "gauge"
+	Rate           = "rate"
+	Count          = "count"
+	MonotonicCount = "monotonic_count"
+	Counter        = "counter"
+	Histogram      = "histogram"
+	Historate      = "historate"
+)
+
+// ServiceCheckStatus is the status of a service check
+type ServiceCheckStatus int
+
+// Service check statuses, the values match the agent ones
+const (
+	ServiceCheckOK       ServiceCheckStatus = 0
+	ServiceCheckWarning  ServiceCheckStatus = 1
+	ServiceCheckCritical ServiceCheckStatus = 2
+	ServiceCheckUnknown  ServiceCheckStatus = 3
+)
+
+// Message is the unit of the protocol, every message is a JSON document on
+// its own line. Requests have an ID and a Method, responses have the ID of
+// the request they answer and an Error when the request failed,
+// notifications have a Method and no ID.
+type Message struct {
+	ID     uint64          `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ConfigureParams are the parameters of a `configure` request, the YAML of
+// the instance and of the init_config
+type ConfigureParams struct {
+	Instance   string `json:"instance"`
+	InitConfig string `json:"init_config"`
+}
+
+// MetricParams are the parameters of a `metric` notification
+type MetricParams struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Value    float64  `json:"value"`
+	Hostname string   `json:"hostname,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// ServiceCheckParams are the parameters of a `service_check` notification
+type ServiceCheckParams struct {
+	Name     string             `json:"name"`
+	Status   ServiceCheckStatus `json:"status"`
+	Hostname string             `json:"hostname,omitempty"`
+	Tags     []string           `json:"tags,omitempty"`
+	Message  string             `json:"message,omitempty"`
+}
+
+// EventParams are the parameters of an `event` notification
+type EventParams struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	Timestamp      int64    `json:"timestamp,omitempty"`
+	Priority       string   `json:"priority,omitempty"`
+	Host           string   `json:"host,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	AlertType      string   `json:"alert_type,omitempty"`
+	AggregationKey string   `json:"aggregation_key,omitempty"`
+	SourceTypeName string   `json:"source_type_name,omitempty"`
+}
+
+// WarningParams are the parameters of a `warning` notification
+type WarningParams struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,190 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// Check is implemented by the checks served by a plugin
+type Check interface {
+	// Configure is called once, with the YAML of the instance and of the
+	// init_config, before the first run
+	Configure(instance, initConfig []byte) error
+	// Run collects the data and submits it with the sender
+	Run(sender *Sender) error
+	// Stop is called before the plugin is terminated
+	Stop()
+}
+
+// Serve runs the protocol for the check until the agent stops it. It speaks
+// over the Unix socket given by the agent, or over stdin and stdout: in that
+// case the plugin must only write its logs to stderr.
+func Serve(c Check) error {
+	if path := os.Getenv(SocketEnvVar); path != "" {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return fmt.Errorf("can't connect to the agent: %s", err)
+		}
+		defer conn.Close()
+		return ServeConn(c, conn, conn)
+	}
+	return ServeConn(c, os.Stdin, os.Stdout)
+}
+
+// ServeConn runs the protocol for the check over `r` and `w`, it returns
+// when the agent sends a stop request or closes the connection
+func ServeConn(c Check, r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+	sender := &Sender{enc: json.NewEncoder(w)}
+
+	for {
+		var req Message
+		if err := dec.Decode(&req); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var err error
+		switch req.Method {
+		case MethodConfigure:
+			params := ConfigureParams{}
+			if err = json.Unmarshal(req.Params, &params); err == nil {
+				err = c.Configure([]byte(params.Instance), []byte(params.InitConfig))
+			}
+		case MethodRun:
+			err = c.Run(sender)
+		case MethodStop:
+			c.Stop()
+		default:
+			err = fmt.Errorf("unknown method %q", req.Method)
+		}
+
+		resp := Message{ID: req.ID}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		if err := sender.send(resp); err != nil {
+			return err
+		}
+		if req.Method == MethodStop {
+			return nil
+		}
+	}
+}
+
+// Sender submits the data collected by a check run to the agent
+type Sender struct {
+	enc *json.Encoder
+	err error // first error writing to the agent
+	m   sync.Mutex
+}
+
+// Gauge submits a gauge
+func (s *Sender) Gauge(name string, value float64, hostname string, tags []string) {
+	s.metric(Gauge, name, value, hostname, tags)
+}
+
+// Rate submits a rate
+func (s *Sender) Rate(name string, value float64, hostname string, tags []string) {
+	s.metric(Rate, name, value, hostname, tags)
+}
+
+// Count submits a count
+func (s *Sender) Count(name string, value float64, hostname string, tags []string) {
+	s.metric(Count, name, value, hostname, tags)
+}
+
+// MonotonicCount submits a monotonic count
+func (s *Sender) MonotonicCount(name string, value float64, hostname string, tags []string) {
+	s.metric(MonotonicCount, name, value, hostname, tags)
+}
+
+// Counter submits a counter
+func (s *Sender) Counter(name string, value float64, hostname string, tags []string) {
+	s.metric(Counter, name, value, hostname, tags)
+}
+
+// Histogram submits a histogram
+func (s *Sender) Histogram(name string, value float64, hostname string, tags []string) {
+	s.metric(Histogram, name, value, hostname, tags)
+}
+
+// Historate submits a historate
+func (s *Sender) Historate(name string, value float64, hostname string, tags []string) {
+	s.metric(Historate, name, value, hostname, tags)
+}
+
+// ServiceCheck submits a service check
+func (s *Sender) ServiceCheck(name string, status ServiceCheckStatus, hostname string, tags []string, message string) {
+	s.notify(MethodServiceCheck, ServiceCheckParams{
+		Name:     name,
+		Status:   status,
+		Hostname: hostname,
+		Tags:     tags,
+		Message:  message,
+	})
+}
+
+// Event submits an event
+func (s *Sender) Event(e EventParams) {
+	s.notify(MethodEvent, e)
+}
+
+// Warn adds a warning to the check run, shown in the agent status
+func (s *Sender) Warn(message string) {
+	s.notify(MethodWarning, WarningParams{Message: message})
+}
+
+// Err returns the first error sending data to the agent, nil if there's none
+func (s *Sender) Err() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.err
+}
+
+func (s *Sender) metric(metricType, name string, value float64, hostname string, tags []string) {
+	s.notify(MethodMetric, MetricParams{
+		Type:     metricType,
+		Name:     name,
+		Value:    value,
+		Hostname: hostname,
+		Tags:     tags,
+	})
+}
+
+func (s *Sender) notify(method string, params interface{}) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		s.setErr(err)
+		return
+	}
+	s.setErr(s.send(Message{Method: method, Params: raw}))
+}
+
+func (s *Sender) send(msg Message) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.enc.Encode(msg)
+}
+
+func (s *Sender) setErr(err error) {
+	if err == nil {
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type echoCheck struct {
+	instance string
+	stopped  bool
+}
+
+func (c *echoCheck) Configure(instance, initConfig []byte) error {
+	c.instance = string(instance)
+	return nil
+}
+
+func (c *echoCheck) Run(s *Sender) error {
+	s.Gauge("echo", 1, "", []string{"instance:" + c.instance})
+	return nil
+}
+
+func (c *echoCheck) Stop() {
+	c.stopped = true
+}
+
+func TestServeConn(t *testing.T) {
+	in := strings.Join([]string{
+		`{"id": 1, "method": "configure", "params": {"instance": "foo"}}`,
+		`{"id": 2, "method": "run"}`,
+		`{"id": 3, "method": "reload"}`,
+		`{"id": 4, "method": "stop"}`,
+		`{"id": 5, "method": "run"}`,
+	}, "\n")
+	out := &bytes.Buffer{}
+	c := &echoCheck{}
+
+	require.Nil(t, ServeConn(c, strings.NewReader(in), out))
+	assert.True(t, c.stopped)
+
+	dec := json.NewDecoder(out)
+	messages := []Message{}
+	for dec.More() {
+		var msg Message
+		require.Nil(t, dec.Decode(&msg))
+		messages = append(messages, msg)
+	}
+
+	// the requests after stop are ignored
+	require.Len(t, messages, 5)
+	assert.Equal(t, Message{ID: 1}, messages[0])
+	assert.Equal(t, MethodMetric, messages[1].Method)
+	metric := MetricParams{}
+	require.Nil(t, json.Unmarshal(messages[1].Params, &metric))
+	assert.Equal(t, MetricParams{Type: Gauge, Name: "echo", Value: 1, Tags: []string{"instance:foo"}}, metric)
+	assert.Equal(t, Message{ID: 2}, messages[2])
+	assert.Equal(t, Message{ID: 3, Error: `unknown method "reload"`}, messages[3])
+	assert.Equal(t, Message{ID: 4}, messages[4])
+}
@@ -13,6 +13,7 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"time"
 
 	log "github.com/cihub/seelog"
 	consul "github.com/hashicorp/consul/api"
@@ -21,6 +22,10 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 )
 
+// consulWatchWaitTime is the maximum duration of the blocking queries used to
+// watch the templates, `stop` is checked between two queries
+const consulWatchWaitTime = 30 * time.Second
+
 // Abstractions for testing
 type consulKVBackend interface {
 	Keys(prefix, separator string, q *consul.QueryOptions) ([]string, *consul.QueryMeta, error)
@@ -47,6 +52,7 @@ type ConsulConfigProvider struct {
 	Cache       map[string][]check.Config
 	cacheIdx    map[string]ADEntryIndex
 	TemplateDir string
+	watchIdx    uint64 // index of the template dir when it was last watched
 }
 
 // NewConsulConfigProvider creates a client connection to consul and create a new ConsulConfigProvider
@@ -126,6 +132,45 @@ func (p *ConsulConfigProvider) Collect() ([]check.Config, error) {
 	return configs, nil
 }
 
+// Watch blocks until a key of the template dir changes, using consul blocking
+// queries on the template dir
+func (p *ConsulConfigProvider) Watch(stop <-chan struct{}) error {
+	kv := p.Client.KV()
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		q := &consul.QueryOptions{WaitIndex: p.watchIdx, WaitTime: consulWatchWaitTime}
+		_, meta, err := kv.Keys(p.TemplateDir, "", q)
+		if err != nil {
+			return fmt.Errorf("Can't watch the templates in consul: %s", err)
+		}
+		if meta == nil {
+			return fmt.Errorf("Can't watch the templates in consul: no index returned")
+		}
+
+		switch {
+		case meta.LastIndex == p.watchIdx:
+			// the query timed out, nothing changed
+			continue
+		case meta.LastIndex < p.watchIdx:
+			// the index went backwards, consul must have been reset: start over
+			p.watchIdx = 0
+		default:
+			p.watchIdx = meta.LastIndex
+		}
+		return nil
+	}
+}
+
+// String returns a string representation of the ConsulConfigProvider
+func (p *ConsulConfigProvider) String() string {
+	return "consul Configuration Provider"
+}
+
 // getIdentifiers gets folders at the root of the TemplateDir
 // verifies they have the right content to be a valid template
 // and return their names.
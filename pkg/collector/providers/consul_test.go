@@ -45,10 +45,11 @@ func (m *consulKVMock) Get(key string, q *consul.QueryOptions) (*consul.KVPair,
 
 func (m *consulKVMock) Keys(prefix, separator string, q *consul.QueryOptions) ([]string, *consul.QueryMeta, error) {
 	args := m.Called(prefix, separator, q)
+	meta, _ := args.Get(1).(*consul.QueryMeta)
 	if v, ok := args.Get(0).([]string); ok {
-		return v, nil, args.Error(2)
+		return v, meta, args.Error(2)
 	}
-	return nil, nil, args.Error(2)
+	return nil, meta, args.Error(2)
 }
 
 func (m *consulKVMock) Txn(txn consul.KVTxnOps, q *consul.QueryOptions) (bool, *consul.KVTxnResponse, *consul.QueryMeta, error) {
@@ -320,3 +321,40 @@ func TestConsulCollect(t *testing.T) {
 	provider.AssertExpectations(t)
 	kv.AssertExpectations(t)
 }
+
+func TestConsulWatch(t *testing.T) {
+	kv := &consulKVMock{}
+	provider := &consulMock{kv: kv}
+	consulCli := ConsulConfigProvider{
+		Client:      provider,
+		TemplateDir: "/datadog/tpl",
+	}
+	query := func(idx uint64) *consul.QueryOptions {
+		return &consul.QueryOptions{WaitIndex: idx, WaitTime: consulWatchWaitTime}
+	}
+
+	// the first query returns right away
+	kv.On("Keys", "/datadog/tpl", "", query(0)).Return([]string{}, &consul.QueryMeta{LastIndex: 10}, nil).Times(1)
+	// then a query times out before a change
+	kv.On("Keys", "/datadog/tpl", "", query(10)).Return([]string{}, &consul.QueryMeta{LastIndex: 10}, nil).Times(1)
+	kv.On("Keys", "/datadog/tpl", "", query(10)).Return([]string{}, &consul.QueryMeta{LastIndex: 12}, nil).Times(1)
+	// the index went backwards
+	kv.On("Keys", "/datadog/tpl", "", query(12)).Return([]string{}, &consul.QueryMeta{LastIndex: 3}, nil).Times(1)
+
+	stop := make(chan struct{})
+	assert.Nil(t, consulCli.Watch(stop))
+	assert.Equal(t, uint64(10), consulCli.watchIdx)
+	assert.Nil(t, consulCli.Watch(stop))
+	assert.Equal(t, uint64(12), consulCli.watchIdx)
+	assert.Nil(t, consulCli.Watch(stop))
+	assert.Equal(t, uint64(0), consulCli.watchIdx)
+	kv.AssertExpectations(t)
+
+	kv.On("Keys", "/datadog/tpl", "", query(0)).Return(nil, nil, errors.New("connection refused")).Times(1)
+	assert.NotNil(t, consulCli.Watch(stop))
+
+	// nothing is queried once stopped
+	close(stop)
+	assert.Nil(t, consulCli.Watch(stop))
+	kv.AssertExpectations(t)
+}
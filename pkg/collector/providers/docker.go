@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+// +build docker
+
+package providers
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/docker"
+)
+
+// the templates of a container are declared in its labels:
+//   com.datadoghq.ad.check_names: '["redisdb"]'
+//   com.datadoghq.ad.init_configs: '[{}]'
+//   com.datadoghq.ad.instances: '[{"host": "%%host%%"}]'
+const dockerADLabelPrefix = "com.datadoghq.ad."
+
+// Abstraction for testing
+type dockerBackend interface {
+	ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error)
+	Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error)
+}
+
+// DockerConfigProvider implements the ConfigProvider interface for the
+// containers running on the host. It should be called periodically and
+// returns the templates declared in the labels of the containers. It listens
+// to the container start and die events to know the running containers, they
+// are listed again when the event stream fails.
+type DockerConfigProvider struct {
+	client     dockerBackend
+	labelCache map[string]map[string]string // container ID --> AD labels
+	streaming  bool
+	m          sync.Mutex
+}
+
+// NewDockerConfigProvider returns a new ConfigProvider connected to docker
+func NewDockerConfigProvider(cfg config.ConfigurationProviders) (ConfigProvider, error) {
+	c, err := docker.ConnectToDocker()
+	if err != nil {
+		return nil, err
+	}
+	return &DockerConfigProvider{
+		client:     c,
+		labelCache: make(map[string]map[string]string),
+	}, nil
+}
+
+// String returns a string representation of the DockerConfigProvider
+func (d *DockerConfigProvider) String() string {
+	return "Docker container labels Configuration Provider"
+}
+
+// Collect returns the templates found in the labels of the running containers
+func (d *DockerConfigProvider) Collect() ([]check.Config, error) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if !d.streaming {
+		if err := d.listen(); err != nil {
+			return []check.Config{}, err
+		}
+	}
+	return parseDockerLabels(d.labelCache), nil
+}
+
+// listen lists the running containers then keeps the label cache up to date
+// with the container events, d.m must be held
+func (d *DockerConfigProvider) listen() error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// subscribe before listing the containers so no event is missed
+	eventFilters := filters.NewArgs()
+	eventFilters.Add("type", "container")
+	eventFilters.Add("event", "start")
+	eventFilters.Add("event", "die")
+	messages, errs := d.client.Events(ctx, types.EventsOptions{Filters: eventFilters})
+
+	containers, err := d.client.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		cancel()
+		return err
+	}
+	d.labelCache = make(map[string]map[string]string)
+	for _, co := range containers {
+		d.setLabels(co.ID, co.Labels)
+	}
+	d.streaming = true
+
+	go d.processEvents(cancel, messages, errs)
+	return nil
+}
+
+// processEvents updates the label cache until the event stream fails
+func (d *DockerConfigProvider) processEvents(cancel context.CancelFunc, messages <-chan events.Message, errs <-chan error) {
+	defer cancel()
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				d.stopStreaming(nil)
+				return
+			}
+			d.m.Lock()
+			switch msg.Action {
+			case "start":
+				// the attributes of the event contain the container labels
+				d.setLabels(msg.Actor.ID, msg.Actor.Attributes)
+			case "die":
+				delete(d.labelCache, msg.Actor.ID)
+			}
+			d.m.Unlock()
+		case err := <-errs:
+			d.stopStreaming(err)
+			return
+		}
+	}
+}
+
+// stopStreaming makes the next Collect list the containers again
+func (d *DockerConfigProvider) stopStreaming(err error) {
+	log.Warnf("Docker event stream stopped, containers will be listed again: %v", err)
+	d.m.Lock()
+	d.streaming = false
+	d.m.Unlock()
+}
+
+// setLabels stores the AD labels of a container, d.m must be held
+func (d *DockerConfigProvider) setLabels(containerID string, labels map[string]string) {
+	adLabels := make(map[string]string)
+	for k, v := range labels {
+		if strings.HasPrefix(k, dockerADLabelPrefix) {
+			adLabels[k] = v
+		}
+	}
+	if len(adLabels) == 0 {
+		delete(d.labelCache, containerID)
+		return
+	}
+	d.labelCache[containerID] = adLabels
+}
+
+// parseDockerLabels builds the templates of each container, the templates are
+// keyed to the container so they only match it
+func parseDockerLabels(labelCache map[string]map[string]string) []check.Config {
+	configs := []check.Config{}
+
+	ids := make([]string, 0, len(labelCache))
+	for id := range labelCache {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		templates, err := extractTemplatesFromMap(docker.ContainerIDToEntityName(id), labelCache[id], dockerADLabelPrefix)
+		if err != nil {
+			log.Errorf("Can't parse the templates of container %s: %s", id, err)
+			continue
+		}
+		configs = append(configs, templates...)
+	}
+	return configs
+}
+
+func init() {
+	RegisterProvider("docker", NewDockerConfigProvider)
+}
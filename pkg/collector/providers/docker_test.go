@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+// +build docker
+
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+type dockerStub struct {
+	containers []types.Container
+	messages   chan events.Message
+	errs       chan error
+}
+
+func (d *dockerStub) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	return d.containers, nil
+}
+
+func (d *dockerStub) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	d.messages = make(chan events.Message)
+	d.errs = make(chan error, 1)
+	return d.messages, d.errs
+}
+
+var redisLabels = map[string]string{
+	"com.datadoghq.ad.check_names":  `["redisdb"]`,
+	"com.datadoghq.ad.init_configs": `[{}]`,
+	"com.datadoghq.ad.instances":    `[{"host": "%%host%%", "port": "6379"}]`,
+	"maintainer":                    "foo",
+}
+
+func TestDockerCollect(t *testing.T) {
+	stub := &dockerStub{containers: []types.Container{
+		{ID: "abcd", Labels: redisLabels},
+		{ID: "ef01", Labels: map[string]string{"maintainer": "foo"}},
+	}}
+	provider := &DockerConfigProvider{client: stub, labelCache: make(map[string]map[string]string)}
+
+	configs, err := provider.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "redisdb", configs[0].Name)
+	assert.Equal(t, []string{"docker://abcd"}, configs[0].ADIdentifiers)
+	assert.Equal(t, check.ConfigData("{}"), configs[0].InitConfig)
+	assert.Equal(t, []check.ConfigData{check.ConfigData(`{"host":"%%host%%","port":"6379"}`)}, configs[0].Instances)
+
+	// a new container is started, the event contains its labels
+	attributes := map[string]string{"image": "nginx", "name": "web"}
+	attributes["com.datadoghq.ad.check_names"] = `["nginx"]`
+	attributes["com.datadoghq.ad.init_configs"] = `[{}]`
+	attributes["com.datadoghq.ad.instances"] = `[{"nginx_status_url": "http://%%host%%/nginx_status"}]`
+	stub.messages <- events.Message{Action: "start", Actor: events.Actor{ID: "2345", Attributes: attributes}}
+	stub.messages <- events.Message{Action: "die", Actor: events.Actor{ID: "abcd"}}
+	// the stub channel is unbuffered, wait for the last message to be processed
+	stub.messages <- events.Message{Action: "die", Actor: events.Actor{ID: "unknown"}}
+
+	configs, err = provider.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "nginx", configs[0].Name)
+	assert.Equal(t, []string{"docker://2345"}, configs[0].ADIdentifiers)
+
+	// the containers are listed again when the stream fails
+	stub.errs <- errors.New("connection reset")
+	for i := 0; i < 100 && isStreaming(provider); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.False(t, isStreaming(provider))
+	configs, err = provider.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "redisdb", configs[0].Name)
+}
+
+func isStreaming(d *DockerConfigProvider) bool {
+	d.m.Lock()
+	defer d.m.Unlock()
+	return d.streaming
+}
+
+func TestParseDockerLabels(t *testing.T) {
+	configs := parseDockerLabels(map[string]map[string]string{
+		"b": redisLabels,
+		"a": redisLabels,
+		"c": {"com.datadoghq.ad.check_names": `["redisdb"]`},
+	})
+	require.Len(t, configs, 2)
+	assert.Equal(t, []string{"docker://a"}, configs[0].ADIdentifiers)
+	assert.Equal(t, []string{"docker://b"}, configs[1].ADIdentifiers)
+}
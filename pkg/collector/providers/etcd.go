@@ -11,6 +11,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/cihub/seelog"
@@ -23,7 +24,9 @@ import (
 // EtcdConfigProvider implements the Config Provider interface
 // It should be called periodically and returns templates from etcd for AutoConf.
 type EtcdConfigProvider struct {
-	Client client.KeysAPI
+	Client   client.KeysAPI
+	watchIdx uint64 // index of the last change seen, by Collect or when watching
+	m        sync.Mutex
 }
 
 // NewEtcdConfigProvider creates a client connection to etcd and create a new EtcdConfigProvider
@@ -61,9 +64,59 @@ func (p *EtcdConfigProvider) Collect() ([]check.Config, error) {
 	return configs, nil
 }
 
+// Watch blocks until a key of the template dir changes, using an etcd watcher
+func (p *EtcdConfigProvider) Watch(stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// watch from the index of the last collect, so the changes made since
+	// then aren't missed
+	p.m.Lock()
+	afterIdx := p.watchIdx
+	p.m.Unlock()
+	watcher := p.Client.Watcher(config.Datadog.GetString("autoconf_template_dir"), &client.WatcherOptions{
+		AfterIndex: afterIdx,
+		Recursive:  true,
+	})
+	resp, err := watcher.Next(ctx)
+	if err != nil {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		if etcdErr, ok := err.(client.Error); ok && etcdErr.Code == client.ErrorCodeEventIndexCleared {
+			// the changes since the last one we saw were dropped from the
+			// history: watch from now on, collecting again catches up
+			p.setWatchIdx(0)
+			return nil
+		}
+		return fmt.Errorf("Can't watch the templates in etcd: %s", err)
+	}
+
+	if resp.Node != nil {
+		p.setWatchIdx(resp.Node.ModifiedIndex)
+	}
+	return nil
+}
+
+func (p *EtcdConfigProvider) setWatchIdx(idx uint64) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.watchIdx = idx
+}
+
 // getIdentifiers gets folders at the root of the TemplateDir
 // verifies they have the right content to be a valid template
-// and return their names.
+// and return their names. The etcd index of the read is the one the next
+// watch starts from.
 func (p *EtcdConfigProvider) getIdentifiers(key string) []string {
 	identifiers := make([]string, 0)
 	resp, err := p.Client.Get(context.Background(), key, &client.GetOptions{Recursive: true})
@@ -71,6 +124,7 @@ func (p *EtcdConfigProvider) getIdentifiers(key string) []string {
 		log.Error("Can't get templates keys from etcd: ", err)
 		return identifiers
 	}
+	p.setWatchIdx(resp.Index)
 	children := resp.Node.Nodes
 	for _, node := range children {
 		if node.Dir && hasTemplateFields(node.Nodes) {
@@ -8,12 +8,40 @@
 package providers
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/coreos/etcd/client"
 	"github.com/stretchr/testify/assert"
 )
 
+type etcdKeysStub struct {
+	client.KeysAPI
+	afterIndex []uint64
+	next       []func(ctx context.Context) (*client.Response, error)
+	index      uint64
+}
+
+func (k *etcdKeysStub) Get(ctx context.Context, key string, opts *client.GetOptions) (*client.Response, error) {
+	return &client.Response{Node: &client.Node{Key: key, Dir: true}, Index: k.index}, nil
+}
+
+func (k *etcdKeysStub) Watcher(key string, opts *client.WatcherOptions) client.Watcher {
+	k.afterIndex = append(k.afterIndex, opts.AfterIndex)
+	next := k.next[0]
+	k.next = k.next[1:]
+	return &etcdWatcherStub{next: next}
+}
+
+type etcdWatcherStub struct {
+	next func(ctx context.Context) (*client.Response, error)
+}
+
+func (w *etcdWatcherStub) Next(ctx context.Context) (*client.Response, error) {
+	return w.next(ctx)
+}
+
 func createTestNode(key string) *client.Node {
 	return &client.Node{
 		Key:           key,
@@ -46,3 +74,64 @@ func TestHasTemplateFields(t *testing.T) {
 	res = hasTemplateFields(validNodes)
 	assert.True(t, res)
 }
+
+func TestEtcdWatch(t *testing.T) {
+	keys := &etcdKeysStub{next: []func(ctx context.Context) (*client.Response, error){
+		func(ctx context.Context) (*client.Response, error) {
+			return &client.Response{Node: createTestNode("/datadog/check_configs/redis/instances")}, nil
+		},
+		func(ctx context.Context) (*client.Response, error) {
+			return nil, client.Error{Code: client.ErrorCodeEventIndexCleared}
+		},
+		func(ctx context.Context) (*client.Response, error) {
+			return nil, errors.New("connection refused")
+		},
+		func(ctx context.Context) (*client.Response, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}}
+	provider := &EtcdConfigProvider{Client: keys}
+	stop := make(chan struct{})
+
+	assert.Nil(t, provider.Watch(stop))
+	assert.Equal(t, uint64(123456), provider.watchIdx)
+	assert.Nil(t, provider.Watch(stop))
+	assert.Equal(t, uint64(0), provider.watchIdx)
+	assert.NotNil(t, provider.Watch(stop))
+
+	// the watch is cancelled when stopping
+	close(stop)
+	assert.Nil(t, provider.Watch(stop))
+	assert.Equal(t, []uint64{0, 123456, 0, 0}, keys.afterIndex)
+}
+
+func TestEtcdWatchAfterCollect(t *testing.T) {
+	keys := &etcdKeysStub{index: 42, next: []func(ctx context.Context) (*client.Response, error){
+		func(ctx context.Context) (*client.Response, error) {
+			return &client.Response{Node: createTestNode("/datadog/check_configs/redis/instances")}, nil
+		},
+		func(ctx context.Context) (*client.Response, error) {
+			return nil, client.Error{Code: client.ErrorCodeEventIndexCleared}
+		},
+		func(ctx context.Context) (*client.Response, error) {
+			return nil, errors.New("connection refused")
+		},
+	}}
+	provider := &EtcdConfigProvider{Client: keys}
+	stop := make(chan struct{})
+
+	// the first watch starts from the index of the collect, the changes
+	// made in between aren't missed
+	_, err := provider.Collect()
+	assert.Nil(t, err)
+	assert.Nil(t, provider.Watch(stop))
+
+	// collecting again after the history was cleared catches up
+	keys.index = 200000
+	assert.Nil(t, provider.Watch(stop))
+	_, err = provider.Collect()
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.Watch(stop))
+	assert.Equal(t, []uint64{42, 123456, 200000}, keys.afterIndex)
+}
@@ -10,9 +10,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	log "github.com/cihub/seelog"
+	"github.com/fsnotify/fsnotify"
 
 	"gopkg.in/yaml.v2"
 )
@@ -24,21 +26,42 @@ type configFormat struct {
 	Instances     []check.ConfigRawMap
 }
 
-// FileConfigProvider collect configuration files from disk
+// FileConfigProvider collect configuration files from disk. When a file
+// can't be parsed anymore, the configuration it had the last time it was
+// valid is kept so that its checks keep running, and the error is reported.
 type FileConfigProvider struct {
-	paths []string
+	paths         []string
+	checksPaths   []string                // folders of the check code, only watched
+	lastValid     map[string]check.Config // file path --> last valid config
+	errors        map[string]string       // file path --> parse error
+	watcher       *fsnotify.Watcher       // only used by Watch
+	changedChecks map[string]struct{}     // checks whose code changed since the last reload
+	m             sync.Mutex
 }
 
 // NewFileConfigProvider creates a new FileConfigProvider searching for
 // configuration files on the given paths
 func NewFileConfigProvider(paths []string) *FileConfigProvider {
-	return &FileConfigProvider{paths: paths}
+	return &FileConfigProvider{
+		paths:         paths,
+		lastValid:     make(map[string]check.Config),
+		errors:        make(map[string]string),
+		changedChecks: make(map[string]struct{}),
+	}
 }
 
 // Collect scans provided paths searching for configuration files. When found,
 // it parses the files and try to unmarshall Yaml contents into a CheckConfig
 // instance
 func (c *FileConfigProvider) Collect() ([]check.Config, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	// only keep track of the files still there
+	lastValid := c.lastValid
+	c.lastValid = make(map[string]check.Config)
+	c.errors = make(map[string]string)
+
 	configs := []check.Config{}
 	configNames := make(map[string]struct{}) // use this map as a python set
 	defaultConfigs := []check.Config{}
@@ -54,7 +77,7 @@ func (c *FileConfigProvider) Collect() ([]check.Config, error) {
 
 		for _, entry := range entries {
 			if entry.IsDir() {
-				dirConfigs := collectDir(path, entry)
+				dirConfigs := c.collectDir(path, entry, lastValid)
 				if len(dirConfigs) > 0 {
 					configs = append(configs, dirConfigs...)
 					configNames[dirConfigs[0].Name] = struct{}{}
@@ -83,12 +106,10 @@ func (c *FileConfigProvider) Collect() ([]check.Config, error) {
 			}
 
 			checkName = checkName[:len(checkName)-len(ext)]
-			conf, err := GetCheckConfigFromFile(checkName, filepath.Join(path, entry.Name()))
-			if err != nil {
-				log.Warnf("%s is not a valid config file: %s", entry.Name(), err)
+			conf, found := c.getConfig(checkName, filepath.Join(path, entry.Name()), lastValid)
+			if !found {
 				continue
 			}
-			log.Debug("Found valid configuration in file:", entry.Name())
 			// determine if a check has to be run by default by
 			// searching for check.yaml.default files
 			if isDefault {
@@ -117,7 +138,42 @@ func (c *FileConfigProvider) String() string {
 	return "File Configuration Provider"
 }
 
-func collectDir(parentPath string, folder os.FileInfo) []check.Config {
+// getConfig parses a config file, when it's invalid the error is recorded and
+// the config of the file the last time it was valid is returned if any. c.m
+// must be held.
+func (c *FileConfigProvider) getConfig(name, fpath string, lastValid map[string]check.Config) (check.Config, bool) {
+	conf, err := GetCheckConfigFromFile(name, fpath)
+	if err == nil {
+		log.Debug("Found valid configuration in file:", fpath)
+		c.lastValid[fpath] = conf
+		return conf, true
+	}
+
+	c.errors[fpath] = err.Error()
+	conf, found := lastValid[fpath]
+	if found {
+		log.Warnf("%s is not a valid config file anymore, keeping its last valid configuration: %s", fpath, err)
+		c.lastValid[fpath] = conf
+	} else {
+		log.Warnf("%s is not a valid config file: %s", fpath, err)
+	}
+	return conf, found
+}
+
+// GetConfigErrors returns the errors parsing the config files found by the
+// last collection, by file path
+func (c *FileConfigProvider) GetConfigErrors() map[string]string {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	configErrors := make(map[string]string, len(c.errors))
+	for path, err := range c.errors {
+		configErrors[path] = err
+	}
+	return configErrors
+}
+
+func (c *FileConfigProvider) collectDir(parentPath string, folder os.FileInfo, lastValid map[string]check.Config) []check.Config {
 	configs := []check.Config{}
 
 	if filepath.Ext(folder.Name()) != ".d" {
@@ -142,13 +198,22 @@ func collectDir(parentPath string, folder os.FileInfo) []check.Config {
 	for _, sEntry := range subEntries {
 		if !sEntry.IsDir() {
 			filePath := filepath.Join(dirPath, sEntry.Name())
-			conf, err := GetCheckConfigFromFile(checkName, filePath)
-			if err != nil {
-				log.Warnf("%s is not a valid config file: %s", sEntry.Name(), err)
+			ext := filepath.Ext(sEntry.Name())
+			if ext != ".yaml" && ext != ".yml" {
+				// don't report the errors of the files that aren't meant
+				// to be configs
+				conf, err := GetCheckConfigFromFile(checkName, filePath)
+				if err != nil {
+					log.Warnf("%s is not a valid config file: %s", sEntry.Name(), err)
+					continue
+				}
+				log.Debug("Found valid configuration in file:", filePath)
+				configs = append(configs, conf)
 				continue
 			}
-			log.Debug("Found valid configuration in file:", filePath)
-			configs = append(configs, conf)
+			if conf, found := c.getConfig(checkName, filePath, lastValid); found {
+				configs = append(configs, conf)
+			}
 		}
 	}
 
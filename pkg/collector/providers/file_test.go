@@ -6,6 +6,9 @@
 package providers
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
@@ -92,3 +95,38 @@ func TestCollect(t *testing.T) {
 		assert.Contains(t, string(nc[0].InitConfig), "IsNotOnTheDefaultFile")
 	}
 }
+
+func TestCollectKeepsLastValidConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confd")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "foo.yaml")
+	provider := NewFileConfigProvider([]string{dir})
+
+	// never valid: no config
+	require.Nil(t, ioutil.WriteFile(path, []byte("instances: [{"), 0644))
+	configs, err := provider.Collect()
+	require.Nil(t, err)
+	assert.Empty(t, configs)
+	assert.Contains(t, provider.GetConfigErrors(), path)
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("instances: [{host: localhost}]"), 0644))
+	valid, err := provider.Collect()
+	require.Nil(t, err)
+	require.Len(t, valid, 1)
+	assert.Empty(t, provider.GetConfigErrors())
+
+	// broken: the last valid config is kept
+	require.Nil(t, ioutil.WriteFile(path, []byte("instances: [{host: localhost}"), 0644))
+	configs, err = provider.Collect()
+	require.Nil(t, err)
+	assert.Equal(t, valid, configs)
+	assert.Contains(t, provider.GetConfigErrors(), path)
+
+	// removed: the config is gone
+	require.Nil(t, os.Remove(path))
+	configs, err = provider.Collect()
+	require.Nil(t, err)
+	assert.Empty(t, configs)
+	assert.Empty(t, provider.GetConfigErrors())
+}
@@ -0,0 +1,168 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package providers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/fsnotify/fsnotify"
+)
+
+// the files are collected once no change happened for this long, editors
+// usually write a file several times when saving it
+var fileWatchDebounce = time.Second
+
+// SetChecksPaths sets the folders of the plugin checks to watch, the instances
+// of a check are restarted when its executable changes. The Python checks are
+// ignored, their module stays imported so restarting them wouldn't load the
+// new code.
+func (c *FileConfigProvider) SetChecksPaths(paths []string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.checksPaths = paths
+}
+
+// ChecksToReload returns the names of the checks whose code changed since
+// the last call
+func (c *FileConfigProvider) ChecksToReload() []string {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	names := make([]string, 0, len(c.changedChecks))
+	for name := range c.changedChecks {
+		names = append(names, name)
+	}
+	c.changedChecks = make(map[string]struct{})
+	return names
+}
+
+// Watch blocks until a file of the search paths or of the check folders
+// changed and no other change happened for `fileWatchDebounce`, it uses
+// inotify on Linux. The search paths that don't exist yet aren't watched.
+func (c *FileConfigProvider) Watch(stop <-chan struct{}) error {
+	if c.watcher == nil {
+		if err := c.startWatcher(); err != nil {
+			return err
+		}
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			c.stopWatcher()
+			return nil
+		case <-debounce:
+			return nil
+		case event, ok := <-c.watcher.Events:
+			if !ok {
+				c.stopWatcher()
+				return fmt.Errorf("the watcher of the config files was closed")
+			}
+			if c.handleEvent(event) {
+				debounce = time.After(fileWatchDebounce)
+			}
+		case err := <-c.watcher.Errors:
+			c.stopWatcher()
+			return fmt.Errorf("can't watch the config files: %s", err)
+		}
+	}
+}
+
+// startWatcher watches the search paths, their `<check>.d` folders and the
+// check folders
+func (c *FileConfigProvider) startWatcher() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("can't watch the config files: %s", err)
+	}
+
+	c.m.Lock()
+	dirs := append(append([]string{}, c.paths...), c.checksPaths...)
+	c.m.Unlock()
+
+	for _, dir := range dirs {
+		if err := w.Add(dir); err != nil {
+			if os.IsNotExist(err) {
+				log.Debugf("Not watching %s: %s", dir, err)
+				continue
+			}
+			w.Close()
+			return fmt.Errorf("can't watch %s: %s", dir, err)
+		}
+	}
+	for _, path := range c.paths {
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && filepath.Ext(entry.Name()) == ".d" {
+				if err := w.Add(filepath.Join(path, entry.Name())); err != nil {
+					log.Warnf("Can't watch %s: %s", filepath.Join(path, entry.Name()), err)
+				}
+			}
+		}
+	}
+
+	c.watcher = w
+	return nil
+}
+
+func (c *FileConfigProvider) stopWatcher() {
+	c.watcher.Close()
+	c.watcher = nil
+}
+
+// handleEvent returns whether the event is a change of a config or of the
+// executable of a plugin check, it starts watching the `<check>.d` folders created in the
+// search paths
+func (c *FileConfigProvider) handleEvent(event fsnotify.Event) bool {
+	name := filepath.Base(event.Name)
+	// skip the hidden files like the swap files of the editors, and the
+	// changes of permissions
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") || event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	dir := filepath.Dir(event.Name)
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for _, path := range c.checksPaths {
+		if filepath.Clean(path) != dir {
+			continue
+		}
+		// only the executables of the plugins, e.g. `foo` or `foo.exe`
+		ext := filepath.Ext(name)
+		if ext != "" && ext != ".exe" {
+			return false
+		}
+		c.changedChecks[strings.TrimSuffix(name, ext)] = struct{}{}
+		return true
+	}
+
+	if event.Op&fsnotify.Create != 0 && filepath.Ext(name) == ".d" {
+		for _, path := range c.paths {
+			if filepath.Clean(path) != dir {
+				continue
+			}
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				if err := c.watcher.Add(event.Name); err != nil {
+					log.Warnf("Can't watch %s: %s", event.Name, err)
+				}
+			}
+			break
+		}
+	}
+	return true
+}
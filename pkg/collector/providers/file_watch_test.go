@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchAsync runs Watch in a goroutine and returns the channel of its result
+func watchAsync(provider *FileConfigProvider, stop chan struct{}) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- provider.Watch(stop) }()
+	return done
+}
+
+func TestFileWatch(t *testing.T) {
+	defer func(d time.Duration) { fileWatchDebounce = d }(fileWatchDebounce)
+	fileWatchDebounce = 50 * time.Millisecond
+
+	confd, err := ioutil.TempDir("", "confd")
+	require.Nil(t, err)
+	defer os.RemoveAll(confd)
+	checksd, err := ioutil.TempDir("", "checksd")
+	require.Nil(t, err)
+	defer os.RemoveAll(checksd)
+	require.Nil(t, os.Mkdir(filepath.Join(confd, "foo.d"), 0755))
+
+	provider := NewFileConfigProvider([]string{confd, filepath.Join(confd, "missing")})
+	provider.SetChecksPaths([]string{checksd})
+	stop := make(chan struct{})
+	require.Nil(t, provider.startWatcher())
+
+	// a config file is written several times
+	done := watchAsync(provider, stop)
+	for i := 0; i < 3; i++ {
+		require.Nil(t, ioutil.WriteFile(filepath.Join(confd, "foo.d", "conf.yaml"), []byte("instances: [{}]"), 0644))
+	}
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the change of the config wasn't reported")
+	}
+	assert.Empty(t, provider.ChecksToReload())
+
+	// the config folders created are watched
+	done = watchAsync(provider, stop)
+	require.Nil(t, os.Mkdir(filepath.Join(confd, "bar.d"), 0755))
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the new config folder wasn't reported")
+	}
+	done = watchAsync(provider, stop)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(confd, "bar.d", "conf.yaml"), []byte("instances: [{}]"), 0644))
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the change in the new config folder wasn't reported")
+	}
+
+	// the executable of a plugin check changes, the Python checks are ignored
+	done = watchAsync(provider, stop)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(checksd, "bar.py"), []byte("pass"), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(checksd, "foo"), []byte("#!/bin/sh"), 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(checksd, ".foo.swp"), []byte("#!/bin/sh"), 0644))
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the change of the check wasn't reported")
+	}
+	assert.Equal(t, []string{"foo"}, provider.ChecksToReload())
+	assert.Empty(t, provider.ChecksToReload())
+
+	// stopping
+	done = watchAsync(provider, stop)
+	close(stop)
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the watch didn't stop")
+	}
+	assert.Nil(t, provider.watcher)
+}
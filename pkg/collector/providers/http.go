@@ -0,0 +1,262 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package providers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+const (
+	httpMinBackoff = 10 * time.Second
+	httpMaxBackoff = 5 * time.Minute
+	// maximum size of the document served by the endpoint
+	httpMaxBodySize = 10 * 1024 * 1024
+)
+
+// Abstraction for testing
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// httpTemplates is the document served by the endpoint:
+//   {"templates": [{"name": "redisdb", "ad_identifiers": ["redis"],
+//                   "init_config": {}, "instances": [{"host": "%%host%%"}]}]}
+// a template without AD identifiers is a plain config scheduled as is
+type httpTemplates struct {
+	Templates []httpTemplate `json:"templates"`
+}
+
+type httpTemplate struct {
+	Name          string            `json:"name"`
+	ADIdentifiers []string          `json:"ad_identifiers"`
+	InitConfig    json.RawMessage   `json:"init_config"`
+	Instances     []json.RawMessage `json:"instances"`
+}
+
+// HTTPConfigProvider implements the ConfigProvider interface for a JSON
+// document of templates served over HTTP. It should be called periodically,
+// the document is only downloaded again when its ETag changed. When the
+// endpoint fails, it isn't requested again before an exponential backoff.
+type HTTPConfigProvider struct {
+	client    httpDoer
+	url       string
+	token     string
+	etag      string
+	configs   []check.Config
+	backoff   time.Duration
+	nextRetry time.Time
+}
+
+// NewHTTPConfigProvider returns a new ConfigProvider fetching the templates
+// from the `template_url` of the provider, authenticated with the bearer
+// `token` and the client certificate if they're set
+func NewHTTPConfigProvider(cfg config.ConfigurationProviders) (ConfigProvider, error) {
+	u, err := url.Parse(cfg.TemplateURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template_url: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid template_url %q: the scheme must be http or https", cfg.TemplateURL)
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if u.Scheme == "https" {
+		tlsConfig, err := buildHTTPTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	} else if cfg.Token != "" {
+		log.Warnf("The token of the http config provider is sent in clear text to %s", u.Host)
+	}
+
+	return &HTTPConfigProvider{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(config.Datadog.GetInt("autoconf_template_url_timeout")) * time.Second,
+		},
+		url:     cfg.TemplateURL,
+		token:   cfg.Token,
+		configs: []check.Config{},
+	}, nil
+}
+
+// buildHTTPTLSConfig trusts the CA of `ca_file` on top of the system ones and
+// presents the client certificate of `cert_file` and `key_file`
+func buildHTTPTLSConfig(cfg config.ConfigurationProviders) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if cfg.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the ca_file: %s", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the ca_file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load the client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// String returns a string representation of the HTTPConfigProvider
+func (p *HTTPConfigProvider) String() string {
+	return "HTTP Configuration Provider"
+}
+
+// Collect returns the templates served by the endpoint, or the ones of the
+// last download when the document didn't change
+func (p *HTTPConfigProvider) Collect() ([]check.Config, error) {
+	if time.Now().Before(p.nextRetry) {
+		return []check.Config{}, fmt.Errorf("backing off after an error, next request to %s at %s", p.url, p.nextRetry.Format(time.RFC3339))
+	}
+
+	configs, err := p.fetch()
+	if err != nil {
+		p.backoff *= 2
+		if p.backoff < httpMinBackoff {
+			p.backoff = httpMinBackoff
+		} else if p.backoff > httpMaxBackoff {
+			p.backoff = httpMaxBackoff
+		}
+		p.nextRetry = time.Now().Add(p.backoff)
+		return []check.Config{}, err
+	}
+	p.backoff = 0
+	p.nextRetry = time.Time{}
+
+	return configs, nil
+}
+
+// fetch downloads the document if its ETag changed and parses it
+func (p *HTTPConfigProvider) fetch() ([]check.Config, error) {
+	req, err := http.NewRequest("GET", p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	if p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("can't get the templates from %s: %s", p.url, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return p.configs, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("can't get the templates from %s: unexpected status %s", p.url, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, httpMaxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("can't read the templates from %s: %s", p.url, err)
+	}
+	configs, err := parseHTTPTemplates(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid templates from %s: %s", p.url, err)
+	}
+
+	p.configs = configs
+	p.etag = resp.Header.Get("ETag")
+	return configs, nil
+}
+
+// parseHTTPTemplates builds the configs of the document, the whole document is
+// rejected if one of its templates is invalid so that the checks of the valid
+// ones aren't unscheduled and scheduled again
+func parseHTTPTemplates(body []byte) ([]check.Config, error) {
+	var doc httpTemplates
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %s", err)
+	}
+
+	configs := make([]check.Config, 0, len(doc.Templates))
+	for i, tpl := range doc.Templates {
+		if tpl.Name == "" {
+			return nil, fmt.Errorf("template %d has no name", i)
+		}
+		if len(tpl.Instances) == 0 {
+			return nil, fmt.Errorf("template %d (%s) has no instances", i, tpl.Name)
+		}
+
+		initConfig, err := parseJSONObject(tpl.InitConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid init_config of template %d (%s): %s", i, tpl.Name, err)
+		}
+		instances := make([]check.ConfigData, 0, len(tpl.Instances))
+		for _, raw := range tpl.Instances {
+			instance, err := parseJSONObject(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid instance of template %d (%s): %s", i, tpl.Name, err)
+			}
+			instances = append(instances, instance)
+		}
+
+		configs = append(configs, check.Config{
+			Name:          tpl.Name,
+			ADIdentifiers: tpl.ADIdentifiers,
+			InitConfig:    initConfig,
+			Instances:     instances,
+		})
+	}
+	return configs, nil
+}
+
+// parseJSONObject returns the compacted JSON of `raw`, which must be an object,
+// a missing value is an empty object. JSON being YAML, the checks can load it.
+func parseJSONObject(raw json.RawMessage) (check.ConfigData, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return check.ConfigData("{}"), nil
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("found non JSON object type, value is: '%s'", raw)
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return check.ConfigData(data), nil
+}
+
+func init() {
+	RegisterProvider("http", NewHTTPConfigProvider)
+}
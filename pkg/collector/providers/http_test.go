@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+const httpTestTemplates = `{"templates": [
+	{"name": "redisdb", "ad_identifiers": ["redis"], "init_config": {}, "instances": [{"port": "6379", "host": "%%host%%"}]},
+	{"name": "http_check", "instances": [{"url": "http://example.com", "name": "example"}]}
+]}`
+
+func TestParseHTTPTemplates(t *testing.T) {
+	configs, err := parseHTTPTemplates([]byte(httpTestTemplates))
+	require.Nil(t, err)
+	require.Len(t, configs, 2)
+
+	assert.Equal(t, "redisdb", configs[0].Name)
+	assert.Equal(t, []string{"redis"}, configs[0].ADIdentifiers)
+	assert.Equal(t, check.ConfigData("{}"), configs[0].InitConfig)
+	assert.Equal(t, []check.ConfigData{check.ConfigData(`{"host":"%%host%%","port":"6379"}`)}, configs[0].Instances)
+
+	// no AD identifiers: a plain config
+	assert.Equal(t, "http_check", configs[1].Name)
+	assert.False(t, configs[1].IsTemplate())
+	assert.Equal(t, check.ConfigData("{}"), configs[1].InitConfig)
+
+	for _, doc := range []string{
+		`not json`,
+		`{"templates": [{"ad_identifiers": ["redis"], "instances": [{}]}]}`,
+		`{"templates": [{"name": "redisdb", "instances": []}]}`,
+		`{"templates": [{"name": "redisdb", "init_config": [], "instances": [{}]}]}`,
+		`{"templates": [{"name": "redisdb", "instances": ["host"]}]}`,
+	} {
+		_, err := parseHTTPTemplates([]byte(doc))
+		assert.NotNil(t, err, doc)
+	}
+}
+
+func TestHTTPCollect(t *testing.T) {
+	requests := 0
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(httpTestTemplates))
+	}))
+	defer server.Close()
+
+	provider, err := NewHTTPConfigProvider(config.ConfigurationProviders{
+		Name:        "http",
+		TemplateURL: server.URL,
+		Token:       "s3cr3t",
+	})
+	require.Nil(t, err)
+	p := provider.(*HTTPConfigProvider)
+
+	configs, err := p.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 2)
+	assert.Equal(t, `"v1"`, p.etag)
+
+	// not modified: the configs of the last download
+	cached, err := p.Collect()
+	require.Nil(t, err)
+	assert.Equal(t, configs, cached)
+	assert.Equal(t, 2, requests)
+
+	// an error backs off
+	status = http.StatusInternalServerError
+	_, err = p.Collect()
+	assert.NotNil(t, err)
+	assert.Equal(t, httpMinBackoff, p.backoff)
+	_, err = p.Collect()
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, requests)
+
+	p.nextRetry = time.Now()
+	_, err = p.Collect()
+	assert.NotNil(t, err)
+	assert.Equal(t, 2*httpMinBackoff, p.backoff)
+	assert.Equal(t, 4, requests)
+
+	// the backoff is reset once the endpoint answers again
+	status = http.StatusOK
+	p.nextRetry = time.Now()
+	cached, err = p.Collect()
+	require.Nil(t, err)
+	assert.Equal(t, configs, cached)
+	assert.Equal(t, time.Duration(0), p.backoff)
+}
+
+func TestNewHTTPConfigProvider(t *testing.T) {
+	_, err := NewHTTPConfigProvider(config.ConfigurationProviders{TemplateURL: "127.0.0.1:8080"})
+	assert.NotNil(t, err)
+
+	_, err = NewHTTPConfigProvider(config.ConfigurationProviders{
+		TemplateURL: "https://127.0.0.1:8080/templates",
+		CAFile:      "/does/not/exist",
+	})
+	assert.NotNil(t, err)
+
+	_, err = NewHTTPConfigProvider(config.ConfigurationProviders{TemplateURL: "https://127.0.0.1:8080/templates"})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package providers
+
+import (
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+// the templates of a container are declared in the annotations of its pod:
+//   ad.datadoghq.com/<container name>.check_names: '["redisdb"]'
+//   ad.datadoghq.com/<container name>.init_configs: '[{}]'
+//   ad.datadoghq.com/<container name>.instances: '[{"host": "%%host%%"}]'
+const podAnnotationPrefix = "ad.datadoghq.com/"
+
+// Abstraction for testing
+type podLister interface {
+	GetLocalPodList() ([]*kubelet.Pod, error)
+}
+
+// KubeletConfigProvider implements the ConfigProvider interface for the pods
+// running on the node. It should be called periodically and returns the
+// templates declared in the pod annotations, the templates of a pod are gone
+// as soon as the kubelet doesn't list it anymore.
+type KubeletConfigProvider struct {
+	kubelet podLister
+}
+
+// NewKubeletConfigProvider returns a new ConfigProvider connected to the kubelet
+func NewKubeletConfigProvider(cfg config.ConfigurationProviders) (ConfigProvider, error) {
+	ku, err := kubelet.NewKubeUtil()
+	if err != nil {
+		return nil, err
+	}
+	return &KubeletConfigProvider{kubelet: ku}, nil
+}
+
+// String returns a string representation of the KubeletConfigProvider
+func (k *KubeletConfigProvider) String() string {
+	return "Kubernetes pod annotations Configuration Provider"
+}
+
+// Collect retrieves the local pods from the kubelet and returns the templates
+// found in their annotations
+func (k *KubeletConfigProvider) Collect() ([]check.Config, error) {
+	pods, err := k.kubelet.GetLocalPodList()
+	if err != nil {
+		return []check.Config{}, err
+	}
+	return parsePodAnnotations(pods), nil
+}
+
+// parsePodAnnotations builds the templates of the containers of each pod, the
+// templates are keyed to the container ID so they only match this container
+func parsePodAnnotations(pods []*kubelet.Pod) []check.Config {
+	configs := []check.Config{}
+
+	for _, pod := range pods {
+		for _, container := range pod.Status.Containers {
+			// the container is not created yet
+			if container.ID == "" {
+				continue
+			}
+			// the kubelet reports `docker://<container ID>`, it's also the
+			// identifier the docker listener gives to the container
+			templates, err := extractTemplatesFromMap(container.ID, pod.Metadata.Annotations, podAnnotationPrefix+container.Name+".")
+			if err != nil {
+				log.Errorf("Can't parse the templates of container %s in pod %s/%s: %s",
+					container.Name, pod.Metadata.Namespace, pod.Metadata.Name, err)
+				continue
+			}
+			configs = append(configs, templates...)
+		}
+	}
+	return configs
+}
+
+func init() {
+	RegisterProvider("kubelet", NewKubeletConfigProvider)
+}
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package providers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+type kubeletStub struct {
+	pods []*kubelet.Pod
+	err  error
+}
+
+func (k *kubeletStub) GetLocalPodList() ([]*kubelet.Pod, error) {
+	return k.pods, k.err
+}
+
+func newPod(name string, annotations map[string]string, containers ...kubelet.ContainerStatus) *kubelet.Pod {
+	return &kubelet.Pod{
+		Metadata: kubelet.PodMetadata{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+		Status: kubelet.Status{Containers: containers},
+	}
+}
+
+func TestKubeletCollect(t *testing.T) {
+	redis := newPod("redis", map[string]string{
+		"ad.datadoghq.com/redis.check_names":  `["redisdb", "tcp_check"]`,
+		"ad.datadoghq.com/redis.init_configs": `[{}, {}]`,
+		"ad.datadoghq.com/redis.instances":    `[{"host": "%%host%%", "port": "6379"}, {"name": "redis", "host": "%%host%%", "port": "6379"}]`,
+		"kubernetes.io/created-by":            "{}",
+	},
+		kubelet.ContainerStatus{Name: "redis", ID: "docker://abcd"},
+		kubelet.ContainerStatus{Name: "sidecar", ID: "docker://ef01"},
+	)
+	nginx := newPod("nginx", map[string]string{
+		"ad.datadoghq.com/nginx.check_names":  `["nginx"]`,
+		"ad.datadoghq.com/nginx.init_configs": `[{}]`,
+		"ad.datadoghq.com/nginx.instances":    `[{"nginx_status_url": "http://%%host%%/nginx_status"}]`,
+	},
+		kubelet.ContainerStatus{Name: "nginx", ID: "docker://2345"},
+	)
+	stub := &kubeletStub{pods: []*kubelet.Pod{redis, nginx}}
+	provider := &KubeletConfigProvider{kubelet: stub}
+
+	configs, err := provider.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 3)
+
+	assert.Equal(t, "redisdb", configs[0].Name)
+	assert.Equal(t, []string{"docker://abcd"}, configs[0].ADIdentifiers)
+	assert.Equal(t, check.ConfigData("{}"), configs[0].InitConfig)
+	assert.Equal(t, []check.ConfigData{check.ConfigData(`{"host":"%%host%%","port":"6379"}`)}, configs[0].Instances)
+	assert.True(t, configs[0].IsTemplate())
+	assert.Equal(t, "tcp_check", configs[1].Name)
+	assert.Equal(t, []string{"docker://abcd"}, configs[1].ADIdentifiers)
+	assert.Equal(t, "nginx", configs[2].Name)
+	assert.Equal(t, []string{"docker://2345"}, configs[2].ADIdentifiers)
+
+	// the templates of a pod are gone with it
+	stub.pods = []*kubelet.Pod{nginx}
+	configs, err = provider.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "nginx", configs[0].Name)
+
+	stub.err = errors.New("connection refused")
+	configs, err = provider.Collect()
+	assert.NotNil(t, err)
+	assert.Empty(t, configs)
+}
+
+func TestParsePodAnnotationsErrors(t *testing.T) {
+	pods := []*kubelet.Pod{
+		// not created yet
+		newPod("pending", map[string]string{
+			"ad.datadoghq.com/app.check_names":  `["http_check"]`,
+			"ad.datadoghq.com/app.init_configs": `[{}]`,
+			"ad.datadoghq.com/app.instances":    `[{}]`,
+		}, kubelet.ContainerStatus{Name: "app"}),
+		newPod("missing", map[string]string{
+			"ad.datadoghq.com/app.check_names": `["http_check"]`,
+			"ad.datadoghq.com/app.instances":   `[{}]`,
+		}, kubelet.ContainerStatus{Name: "app", ID: "docker://1"}),
+		newPod("invalid", map[string]string{
+			"ad.datadoghq.com/app.check_names":  `http_check`,
+			"ad.datadoghq.com/app.init_configs": `[{}]`,
+			"ad.datadoghq.com/app.instances":    `[{}]`,
+		}, kubelet.ContainerStatus{Name: "app", ID: "docker://2"}),
+		newPod("length", map[string]string{
+			"ad.datadoghq.com/app.check_names":  `["http_check", "tcp_check"]`,
+			"ad.datadoghq.com/app.init_configs": `[{}]`,
+			"ad.datadoghq.com/app.instances":    `[{}]`,
+		}, kubelet.ContainerStatus{Name: "app", ID: "docker://3"}),
+		newPod("valid", map[string]string{
+			"ad.datadoghq.com/app.check_names":  `["http_check"]`,
+			"ad.datadoghq.com/app.init_configs": `[{}]`,
+			"ad.datadoghq.com/app.instances":    `[{}]`,
+		}, kubelet.ContainerStatus{Name: "app", ID: "docker://4"}),
+	}
+
+	configs := parsePodAnnotations(pods)
+	require.Len(t, configs, 1)
+	assert.Equal(t, []string{"docker://4"}, configs[0].ADIdentifiers)
+}
@@ -32,3 +32,15 @@ type ConfigProviderFactory func(cfg config.ConfigurationProviders) (ConfigProvid
 type ConfigProvider interface {
 	Collect() ([]check.Config, error)
 }
+
+// WatchableConfigProvider is implemented by the providers that can be notified
+// of the changes of their configurations instead of being polled.
+//
+// Watch blocks until the configurations may have changed, then the caller is
+// expected to call Collect. It returns nil without waiting for a change when
+// `stop` is closed, and an error when the backend can't be watched, the
+// provider should be polled until a call to Watch succeeds again.
+type WatchableConfigProvider interface {
+	ConfigProvider
+	Watch(stop <-chan struct{}) error
+}
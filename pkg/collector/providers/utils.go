@@ -102,3 +102,38 @@ func buildTemplates(key string, checkNames []string, initConfigs, instances []ch
 	}
 	return templates
 }
+
+// extractTemplatesFromMap returns the templates declared in the `check_names`,
+// `init_configs` and `instances` keys of `input` prefixed with `prefix`, like
+// pod annotations or container labels, keyed to the `key` AD identifier. It
+// returns nil if none of the keys is set.
+func extractTemplatesFromMap(key string, input map[string]string, prefix string) ([]check.Config, error) {
+	checkNames, hasNames := input[prefix+checkNamePath]
+	initConfigs, hasInitConfigs := input[prefix+initConfigPath]
+	instances, hasInstances := input[prefix+instancePath]
+
+	if !hasNames && !hasInitConfigs && !hasInstances {
+		return nil, nil
+	}
+	if !hasNames || !hasInitConfigs || !hasInstances {
+		return nil, fmt.Errorf("the %s, %s and %s keys must all be set", checkNamePath, initConfigPath, instancePath)
+	}
+
+	names, err := parseCheckNames(checkNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", checkNamePath, err)
+	}
+	initConfigsData, err := parseJSONValue(initConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", initConfigPath, err)
+	}
+	instancesData, err := parseJSONValue(instances)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", instancePath, err)
+	}
+	if len(names) != len(initConfigsData) || len(names) != len(instancesData) {
+		return nil, fmt.Errorf("the %s, %s and %s keys don't have the same length", checkNamePath, initConfigPath, instancePath)
+	}
+
+	return buildTemplates(key, names, initConfigsData, instancesData), nil
+}
@@ -113,3 +113,47 @@ func TestBuildTemplates(t *testing.T) {
 	assert.Equal(t, res[1].InitConfig, check.ConfigData("{}"))
 	assert.Equal(t, res[1].Instances, []check.ConfigData{check.ConfigData("{1:2}")})
 }
+
+func TestExtractTemplatesFromMap(t *testing.T) {
+	prefix := "com.datadoghq.ad."
+
+	// no template
+	res, err := extractTemplatesFromMap("docker://1", map[string]string{"foo": "bar"}, prefix)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+
+	// missing key
+	res, err = extractTemplatesFromMap("docker://1", map[string]string{
+		"com.datadoghq.ad.check_names": `["http_check"]`,
+		"com.datadoghq.ad.instances":   `[{}]`,
+	}, prefix)
+	assert.EqualError(t, err, "the check_names, init_configs and instances keys must all be set")
+
+	// invalid value
+	res, err = extractTemplatesFromMap("docker://1", map[string]string{
+		"com.datadoghq.ad.check_names":  `http_check`,
+		"com.datadoghq.ad.init_configs": `[{}]`,
+		"com.datadoghq.ad.instances":    `[{}]`,
+	}, prefix)
+	assert.NotNil(t, err)
+
+	// different lengths
+	res, err = extractTemplatesFromMap("docker://1", map[string]string{
+		"com.datadoghq.ad.check_names":  `["http_check", "tcp_check"]`,
+		"com.datadoghq.ad.init_configs": `[{}]`,
+		"com.datadoghq.ad.instances":    `[{}]`,
+	}, prefix)
+	assert.EqualError(t, err, "the check_names, init_configs and instances keys don't have the same length")
+
+	// valid input
+	res, err = extractTemplatesFromMap("docker://1", map[string]string{
+		"com.datadoghq.ad.check_names":  `["http_check"]`,
+		"com.datadoghq.ad.init_configs": `[{}]`,
+		"com.datadoghq.ad.instances":    `[{"url": "http://%%host%%"}]`,
+	}, prefix)
+	assert.Nil(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "http_check", res[0].Name)
+	assert.Equal(t, []string{"docker://1"}, res[0].ADIdentifiers)
+	assert.Equal(t, []check.ConfigData{check.ConfigData(`{"url":"http://%%host%%"}`)}, res[0].Instances)
+}
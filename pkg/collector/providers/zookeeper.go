@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/cihub/seelog"
@@ -24,7 +25,9 @@ const sessionTimeout = 1 * time.Second
 
 type zkBackend interface {
 	Get(key string) ([]byte, *zk.Stat, error)
+	GetW(key string) ([]byte, *zk.Stat, <-chan zk.Event, error)
 	Children(key string) ([]string, *zk.Stat, error)
+	ChildrenW(key string) ([]string, *zk.Stat, <-chan zk.Event, error)
 }
 
 // ZookeeperConfigProvider implements the Config Provider interface It should
@@ -32,6 +35,8 @@ type zkBackend interface {
 type ZookeeperConfigProvider struct {
 	client      zkBackend
 	templateDir string
+	versions    map[string]int64 // path --> cversion of the dirs, mzxid of the keys, as of the last Collect
+	m           sync.Mutex
 }
 
 // NewZookeeperConfigProvider returns a new Client connected to a Zookeeper backend.
@@ -56,36 +61,131 @@ func (z *ZookeeperConfigProvider) String() string {
 // TODO: cache templates and last-modified index to avoid future full crawl if no template changed.
 func (z *ZookeeperConfigProvider) Collect() ([]check.Config, error) {
 	configs := make([]check.Config, 0)
-	identifiers, err := z.getIdentifiers(z.templateDir)
+	versions := make(map[string]int64)
+	identifiers, err := z.getIdentifiers(z.templateDir, versions)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, id := range identifiers {
-		c := z.getTemplates(id)
+		c := z.getTemplates(id, versions)
 		configs = append(configs, c...)
 	}
+
+	z.m.Lock()
+	z.versions = versions
+	z.m.Unlock()
 	return configs, nil
 }
 
+// Watch blocks until the template dir, an identifier or one of its template
+// keys changes, using zookeeper watches. The watches are set again on every
+// call since zookeeper only triggers them once, it returns at once when the
+// versions of the nodes differ from the ones seen by the last Collect: they
+// changed before the watches were set.
+func (z *ZookeeperConfigProvider) Watch(stop <-chan struct{}) error {
+	z.m.Lock()
+	seen := z.versions
+	z.m.Unlock()
+
+	watches := []<-chan zk.Event{}
+
+	children, stat, w, err := z.client.ChildrenW(z.templateDir)
+	if err != nil {
+		return fmt.Errorf("Can't watch '%s' in zookeeper: %s", z.templateDir, err)
+	}
+	if stat != nil && changedSince(seen, z.templateDir, int64(stat.Cversion)) {
+		return nil
+	}
+	watches = append(watches, w)
+
+	for _, child := range children {
+		nodePath := path.Join(z.templateDir, child)
+		nodes, stat, w, err := z.client.ChildrenW(nodePath)
+		if err != nil {
+			return fmt.Errorf("Can't watch '%s' in zookeeper: %s", nodePath, err)
+		}
+		if stat != nil && changedSince(seen, nodePath, int64(stat.Cversion)) {
+			return nil
+		}
+		watches = append(watches, w)
+
+		for _, node := range nodes {
+			switch node {
+			case instancePath, checkNamePath, initConfigPath:
+			default:
+				continue
+			}
+			key := path.Join(nodePath, node)
+			_, stat, w, err := z.client.GetW(key)
+			if err != nil {
+				return fmt.Errorf("Can't watch '%s' in zookeeper: %s", key, err)
+			}
+			if stat != nil && changedSince(seen, key, stat.Mzxid) {
+				return nil
+			}
+			watches = append(watches, w)
+		}
+	}
+
+	// wait for the first watch to trigger
+	events := make(chan zk.Event, len(watches))
+	done := make(chan struct{})
+	defer close(done)
+	for _, w := range watches {
+		go func(w <-chan zk.Event) {
+			select {
+			case ev := <-w:
+				events <- ev
+			case <-done:
+			}
+		}(w)
+	}
+
+	select {
+	case <-stop:
+		return nil
+	case ev := <-events:
+		if ev.Err != nil {
+			return fmt.Errorf("Zookeeper watch failed on '%s': %s", ev.Path, ev.Err)
+		}
+		return nil
+	}
+}
+
+// changedSince returns whether the version of a node differs from the one
+// seen by the last Collect, the nodes it didn't read are left to the watches
+func changedSince(seen map[string]int64, key string, version int64) bool {
+	v, found := seen[key]
+	return found && v != version
+}
+
 // getIdentifiers gets folders at the root of the template dir
 // verifies they have the right content to be a valid template
-// and return their names.
-func (z *ZookeeperConfigProvider) getIdentifiers(key string) ([]string, error) {
+// and return their names. The cversions of the folders are stored in
+// `versions`.
+func (z *ZookeeperConfigProvider) getIdentifiers(key string, versions map[string]int64) ([]string, error) {
 	identifiers := []string{}
 
-	children, _, err := z.client.Children(key)
+	children, stat, err := z.client.Children(key)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to list '%s' to get identifiers from zookeeper: %s", key, err)
 	}
+	if stat != nil {
+		versions[key] = int64(stat.Cversion)
+	}
 
 	for _, child := range children {
 		nodePath := path.Join(key, child)
-		nodes, _, err := z.client.Children(nodePath)
+		nodes, stat, err := z.client.Children(nodePath)
 		if err != nil {
 			log.Warnf("could not list keys in '%s': %s", nodePath, err)
 			continue
-		} else if len(nodes) < 3 {
+		}
+		if stat != nil {
+			versions[nodePath] = int64(stat.Cversion)
+		}
+		if len(nodes) < 3 {
 			continue
 		}
 
@@ -103,17 +203,21 @@ func (z *ZookeeperConfigProvider) getIdentifiers(key string) ([]string, error) {
 }
 
 // getTemplates takes a path and returns a slice of templates if it finds
-// sufficient data under this path to build one.
-func (z *ZookeeperConfigProvider) getTemplates(key string) []check.Config {
+// sufficient data under this path to build one. The mzxids of the keys are
+// stored in `versions`.
+func (z *ZookeeperConfigProvider) getTemplates(key string, versions map[string]int64) []check.Config {
 	checkNameKey := path.Join(key, checkNamePath)
 	initKey := path.Join(key, initConfigPath)
 	instanceKey := path.Join(key, instancePath)
 
-	rawNames, _, err := z.client.Get(checkNameKey)
+	rawNames, stat, err := z.client.Get(checkNameKey)
 	if err != nil {
 		log.Errorf("Couldn't get check names from key '%s' in zookeeper: %s", key, err)
 		return nil
 	}
+	if stat != nil {
+		versions[checkNameKey] = stat.Mzxid
+	}
 
 	checkNames, err := parseCheckNames(string(rawNames))
 	if err != nil {
@@ -121,13 +225,13 @@ func (z *ZookeeperConfigProvider) getTemplates(key string) []check.Config {
 		return nil
 	}
 
-	initConfigs, err := z.getJSONValue(initKey)
+	initConfigs, err := z.getJSONValue(initKey, versions)
 	if err != nil {
 		log.Errorf("Failed to retrieve init configs at %s. Error: %s", initKey, err)
 		return nil
 	}
 
-	instances, err := z.getJSONValue(instanceKey)
+	instances, err := z.getJSONValue(instanceKey, versions)
 	if err != nil {
 		log.Errorf("Failed to retrieve instances at %s. Error: %s", instanceKey, err)
 		return nil
@@ -136,11 +240,14 @@ func (z *ZookeeperConfigProvider) getTemplates(key string) []check.Config {
 	return buildTemplates(key, checkNames, initConfigs, instances)
 }
 
-func (z *ZookeeperConfigProvider) getJSONValue(key string) ([]check.ConfigData, error) {
-	rawValue, _, err := z.client.Get(key)
+func (z *ZookeeperConfigProvider) getJSONValue(key string, versions map[string]int64) ([]check.ConfigData, error) {
+	rawValue, stat, err := z.client.Get(key)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't get key '%s' from zookeeper: %s", key, err)
 	}
+	if stat != nil {
+		versions[key] = stat.Mzxid
+	}
 
 	return parseJSONValue(string(rawValue))
 }
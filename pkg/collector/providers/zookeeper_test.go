@@ -23,12 +23,13 @@ import (
 
 type zkTest struct {
 	mock.Mock
+	stats map[string]*zk.Stat // path --> stat returned with the node
 }
 
 func (m *zkTest) Get(key string) ([]byte, *zk.Stat, error) {
 	args := m.Called(key)
 	if v, ok := args.Get(0).([]byte); ok {
-		return v, nil, args.Error(1)
+		return v, m.stats[key], args.Error(1)
 	}
 	return nil, nil, args.Error(1)
 }
@@ -36,11 +37,29 @@ func (m *zkTest) Get(key string) ([]byte, *zk.Stat, error) {
 func (m *zkTest) Children(key string) ([]string, *zk.Stat, error) {
 	args := m.Called(key)
 	if v, ok := args.Get(0).([]string); ok {
-		return v, nil, args.Error(1)
+		return v, m.stats[key], args.Error(1)
 	}
 	return nil, nil, args.Error(1)
 }
 
+func (m *zkTest) GetW(key string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	args := m.Called(key)
+	w, _ := args.Get(1).(chan zk.Event)
+	if v, ok := args.Get(0).([]byte); ok {
+		return v, m.stats[key], w, args.Error(2)
+	}
+	return nil, nil, w, args.Error(2)
+}
+
+func (m *zkTest) ChildrenW(key string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	args := m.Called(key)
+	w, _ := args.Get(1).(chan zk.Event)
+	if v, ok := args.Get(0).([]string); ok {
+		return v, m.stats[key], w, args.Error(2)
+	}
+	return nil, nil, w, args.Error(2)
+}
+
 //
 // Tests
 //
@@ -59,11 +78,11 @@ func TestZKGetIdentifiers(t *testing.T) {
 
 	zk := ZookeeperConfigProvider{client: backend}
 
-	res, err := zk.getIdentifiers("/test/")
+	res, err := zk.getIdentifiers("/test/", map[string]int64{})
 	assert.Nil(t, res)
 	assert.NotNil(t, err)
 
-	res, err = zk.getIdentifiers("/datadog/tpl")
+	res, err = zk.getIdentifiers("/datadog/tpl", map[string]int64{})
 	require.Nil(t, err)
 
 	assert.Len(t, res, 2)
@@ -76,37 +95,37 @@ func TestZKGetTemplates(t *testing.T) {
 
 	backend.On("Get", "/error1/check_names").Return(nil, fmt.Errorf("some error")).Times(1)
 	zk := ZookeeperConfigProvider{client: backend}
-	res := zk.getTemplates("/error1/")
+	res := zk.getTemplates("/error1/", map[string]int64{})
 	assert.Nil(t, res)
 
 	backend.On("Get", "/error2/check_names").Return([]byte("[\"first_name\"]"), nil).Times(1)
 	backend.On("Get", "/error2/init_configs").Return(nil, fmt.Errorf("some error")).Times(1)
-	res = zk.getTemplates("/error2/")
+	res = zk.getTemplates("/error2/", map[string]int64{})
 	assert.Nil(t, res)
 
 	backend.On("Get", "/error3/check_names").Return([]byte("[\"first_name\"]"), nil).Times(1)
 	backend.On("Get", "/error3/init_configs").Return([]byte("[{}]"), nil).Times(1)
 	backend.On("Get", "/error3/instances").Return(nil, fmt.Errorf("some error")).Times(1)
-	res = zk.getTemplates("/error3/")
+	res = zk.getTemplates("/error3/", map[string]int64{})
 	assert.Nil(t, res)
 
 	backend.On("Get", "/error4/check_names").Return([]byte("[\"first_name\"]"), nil).Times(1)
 	backend.On("Get", "/error4/instances").Return([]byte("[{}]"), nil).Times(1)
 	backend.On("Get", "/error4/init_configs").Return([]byte("[{}, {}]"), nil).Times(1)
-	res = zk.getTemplates("/error4/")
+	res = zk.getTemplates("/error4/", map[string]int64{})
 	assert.Len(t, res, 0)
 
 	backend.On("Get", "/error5/check_names").Return([]byte(""), nil).Times(1)
 	backend.On("Get", "/error5/instances").Return([]byte("[{}]"), nil).Times(1)
 	backend.On("Get", "/error5/init_configs").Return([]byte("[{}]"), nil).Times(1)
-	res = zk.getTemplates("/error5/")
+	res = zk.getTemplates("/error5/", map[string]int64{})
 	assert.Len(t, res, 0)
 
 	backend.On("Get", "/config/check_names").Return([]byte("[\"first_name\", \"second_name\"]"), nil).Times(1)
 	backend.On("Get", "/config/instances").Return([]byte("[{\"test\": 21, \"test2\": \"data\"}, {\"data1\": \"21\", \"data2\": {\"number\": 21}}]"), nil).Times(1)
 	backend.On("Get", "/config/init_configs").Return([]byte("[{\"a\": \"b\"}, {}]"), nil).Times(1)
 	//zk = ZookeeperConfigProvider{client: backend}
-	res = zk.getTemplates("/config/")
+	res = zk.getTemplates("/config/", map[string]int64{})
 	assert.NotNil(t, res)
 	assert.Len(t, res, 2)
 
@@ -168,3 +187,85 @@ func TestZKCollect(t *testing.T) {
 	require.Len(t, res[2].Instances, 1)
 	assert.Equal(t, "{}", string(res[2].Instances[0]))
 }
+
+func TestZKWatch(t *testing.T) {
+	backend := &zkTest{}
+	z := ZookeeperConfigProvider{client: backend, templateDir: "/datadog/tpl"}
+
+	dirWatch := make(chan zk.Event, 1)
+	nginxWatch := make(chan zk.Event, 1)
+	instancesWatch := make(chan zk.Event, 1)
+	otherWatch := make(chan zk.Event, 1)
+	backend.On("ChildrenW", "/datadog/tpl").Return([]string{"nginx"}, dirWatch, nil)
+	backend.On("ChildrenW", "/datadog/tpl/nginx").Return([]string{"check_names", "instances", "init_configs", "other"}, nginxWatch, nil)
+	backend.On("GetW", "/datadog/tpl/nginx/check_names").Return([]byte{}, otherWatch, nil)
+	backend.On("GetW", "/datadog/tpl/nginx/init_configs").Return([]byte{}, otherWatch, nil)
+	backend.On("GetW", "/datadog/tpl/nginx/instances").Return([]byte{}, instancesWatch, nil)
+
+	// a template key changes
+	instancesWatch <- zk.Event{Type: zk.EventNodeDataChanged, Path: "/datadog/tpl/nginx/instances"}
+	assert.Nil(t, z.Watch(make(chan struct{})))
+
+	// the session expired
+	dirWatch <- zk.Event{Type: zk.EventNotWatching, Path: "/datadog/tpl", Err: zk.ErrSessionExpired}
+	assert.NotNil(t, z.Watch(make(chan struct{})))
+
+	// nothing changes until stopped
+	stop := make(chan struct{})
+	close(stop)
+	assert.Nil(t, z.Watch(stop))
+	backend.AssertExpectations(t)
+
+	backend = &zkTest{}
+	z.client = backend
+	backend.On("ChildrenW", "/datadog/tpl").Return(nil, nil, fmt.Errorf("no node"))
+	assert.NotNil(t, z.Watch(stop))
+}
+
+func TestZKWatchChangedSinceCollect(t *testing.T) {
+	backend := &zkTest{stats: map[string]*zk.Stat{
+		"/datadog/tpl":                    {Cversion: 1},
+		"/datadog/tpl/nginx":              {Cversion: 3},
+		"/datadog/tpl/nginx/check_names":  {Mzxid: 10},
+		"/datadog/tpl/nginx/init_configs": {Mzxid: 11},
+		"/datadog/tpl/nginx/instances":    {Mzxid: 12},
+	}}
+	z := ZookeeperConfigProvider{client: backend, templateDir: "/datadog/tpl"}
+
+	keys := []string{"check_names", "instances", "init_configs"}
+	backend.On("Children", "/datadog/tpl").Return([]string{"nginx"}, nil)
+	backend.On("Children", "/datadog/tpl/nginx").Return(keys, nil)
+	backend.On("Get", "/datadog/tpl/nginx/check_names").Return([]byte("[\"nginx\"]"), nil)
+	backend.On("Get", "/datadog/tpl/nginx/init_configs").Return([]byte("[{}]"), nil)
+	backend.On("Get", "/datadog/tpl/nginx/instances").Return([]byte("[{}]"), nil)
+	res, err := z.Collect()
+	require.Nil(t, err)
+	require.Len(t, res, 1)
+
+	watch := make(chan zk.Event, 1)
+	backend.On("ChildrenW", "/datadog/tpl").Return([]string{"nginx"}, watch, nil)
+	backend.On("ChildrenW", "/datadog/tpl/nginx").Return(keys, watch, nil)
+	backend.On("GetW", "/datadog/tpl/nginx/check_names").Return([]byte{}, watch, nil)
+	backend.On("GetW", "/datadog/tpl/nginx/init_configs").Return([]byte{}, watch, nil)
+	backend.On("GetW", "/datadog/tpl/nginx/instances").Return([]byte{}, watch, nil)
+
+	// nothing changed since the collect, the watches are waited for
+	failed := zk.Event{Type: zk.EventNotWatching, Err: zk.ErrSessionExpired}
+	watch <- failed
+	assert.NotNil(t, z.Watch(make(chan struct{})))
+
+	// a key changed before the watches were set, they're not waited for
+	backend.stats["/datadog/tpl/nginx/instances"] = &zk.Stat{Mzxid: 13}
+	assert.Nil(t, z.Watch(make(chan struct{})))
+
+	// so did the children of an identifier
+	backend.stats["/datadog/tpl/nginx/instances"] = &zk.Stat{Mzxid: 12}
+	backend.stats["/datadog/tpl/nginx"] = &zk.Stat{Cversion: 4}
+	assert.Nil(t, z.Watch(make(chan struct{})))
+
+	// the next collect catches up
+	_, err = z.Collect()
+	require.Nil(t, err)
+	watch <- failed
+	assert.NotNil(t, z.Watch(make(chan struct{})))
+}
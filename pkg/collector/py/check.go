@@ -32,6 +32,9 @@ type PythonCheck struct {
 	ModuleName   string
 	config       *python.PyObject
 	interval     time.Duration
+	timeout      time.Duration
+	schedule     check.Schedule
+	retryPolicy  *check.RetryPolicy
 	lastWarnings []error
 }
 
@@ -89,6 +92,21 @@ func (c *PythonCheck) Run() error {
 	return errors.New(resultStr)
 }
 
+// Timeout returns the run timeout configured for the instance, if any
+func (c *PythonCheck) Timeout() time.Duration {
+	return c.timeout
+}
+
+// Schedule returns the schedule configured for the instance, if any
+func (c *PythonCheck) Schedule() check.Schedule {
+	return c.schedule
+}
+
+// RetryPolicy returns the retry policy configured for the instance, if any
+func (c *PythonCheck) RetryPolicy() *check.RetryPolicy {
+	return c.retryPolicy
+}
+
 // Stop does nothing
 func (c *PythonCheck) Stop() {}
 
@@ -193,6 +211,27 @@ func (c *PythonCheck) Configure(data check.ConfigData, initConfig check.ConfigDa
 		}
 	}
 
+	// See if a run timeout was specified
+	if x, ok := rawInstances["check_timeout"]; ok {
+		if t, ok := x.(int); ok {
+			c.timeout = time.Duration(t) * time.Second
+		}
+	}
+
+	// See if a schedule was specified, it replaces the collection interval
+	c.schedule, err = check.GetSchedule(rawInstances)
+	if err != nil {
+		log.Errorf("error in the schedule of %s: %s", c.ModuleName, err)
+		return err
+	}
+
+	// See if retries were configured
+	c.retryPolicy, err = check.GetRetryPolicy(data)
+	if err != nil {
+		log.Errorf("error in the retry settings of %s: %s", c.ModuleName, err)
+		return err
+	}
+
 	// To be retrocompatible with the Python code, still use an `instance` dictionary
 	// to contain the (now) unique instance for the check
 	conf := make(check.ConfigRawMap)
@@ -8,9 +8,11 @@ package py
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/collector/loaders"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	"github.com/sbinet/go-python"
 
 	log "github.com/cihub/seelog"
@@ -77,11 +79,13 @@ func (cl *PythonCheckLoader) Load(config check.Config) ([]check.Check, error) {
 	}
 
 	// Get an AgentCheck for each configuration instance and add it to the registry
+	errs := []string{}
 	for _, i := range config.Instances {
 		check := NewPythonCheck(moduleName, checkClass)
 		// The GIL should be unlocked at this point, `check.Configure` uses its own stickyLock and stickyLocks must not be nested
 		if err := check.Configure(i, config.InitConfig); err != nil {
-			log.Errorf("py.loader: could not configure check '%s': %s", moduleName, err)
+			log.Errorf("py.loader: could not configure check '%s': %s", moduleName, secrets.Scrub(err.Error()))
+			errs = append(errs, err.Error())
 			continue
 		}
 		checks = append(checks, check)
@@ -90,6 +94,10 @@ func (cl *PythonCheckLoader) Load(config check.Config) ([]check.Check, error) {
 	defer glock.unlock()
 	checkClass.DecRef()
 
+	if len(checks) == 0 && len(errs) > 0 {
+		return checks, fmt.Errorf("could not configure any instance of %s: %s", moduleName, strings.Join(errs, "; "))
+	}
+
 	log.Debugf("python loader: done loading check %s", moduleName)
 	return checks, nil
 }
@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package runner
+
+import (
+	"expvar"
+	"sync/atomic"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+var (
+	// scaleInterval is how often the pool size is evaluated, it's a variable
+	// so tests can make it shorter
+	scaleInterval = 5 * time.Second
+	// queueWaitThreshold is the average time checks can wait for a worker
+	// before the pool grows
+	queueWaitThreshold = 1 * time.Second
+
+	utilizationStat expvar.Float // ratio of busy workers at the last evaluation
+	queueWaitStat   expvar.Int   // average queue wait time in ms over the last evaluation period
+)
+
+// workerPool keeps track of the runner workers and of the checks waiting for
+// one, so the number of workers can follow the load
+type workerPool struct {
+	min         int32
+	max         int32
+	workers     int32         // current number of workers, atomic
+	busyWorkers int32         // workers running a check, atomic
+	queued      int32         // checks waiting to be picked up, atomic
+	waitSum     int64         // total wait time in ns since the last evaluation, atomic
+	waitCount   int64         // number of checks picked up since the last evaluation, atomic
+	shrink      chan struct{} // a worker receiving from this channel exits
+	stop        chan struct{} // closed when the runner stops
+}
+
+func newWorkerPool(min, max int) *workerPool {
+	return &workerPool{
+		min:    int32(min),
+		max:    int32(max),
+		shrink: make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+}
+
+func (p *workerPool) workerAdded() {
+	atomic.AddInt32(&p.workers, 1)
+	runnerStats.Add("Workers", 1)
+}
+
+func (p *workerPool) workerDone() {
+	atomic.AddInt32(&p.workers, -1)
+	runnerStats.Add("Workers", -1)
+}
+
+func (p *workerPool) busy(delta int32) {
+	atomic.AddInt32(&p.busyWorkers, delta)
+	runnerStats.Add("BusyWorkers", int64(delta))
+}
+
+// evaluate publishes the pool stats for the elapsed period and returns how
+// many workers should be added (positive) or removed (negative). The pool
+// grows when checks are waiting and either every worker is busy or checks
+// waited more than queueWaitThreshold on average, by at most the number of
+// waiting checks. It shrinks by one when nothing is waiting and less than
+// half the workers are busy.
+func (p *workerPool) evaluate() int {
+	workers := atomic.LoadInt32(&p.workers)
+	busy := atomic.LoadInt32(&p.busyWorkers)
+	queued := atomic.LoadInt32(&p.queued)
+	waitSum := atomic.SwapInt64(&p.waitSum, 0)
+	waitCount := atomic.SwapInt64(&p.waitCount, 0)
+
+	var avgWait time.Duration
+	if waitCount > 0 {
+		avgWait = time.Duration(waitSum / waitCount)
+	}
+	queueWaitStat.Set(int64(avgWait / time.Millisecond))
+
+	var utilization float64
+	if workers > 0 {
+		utilization = float64(busy) / float64(workers)
+	}
+	utilizationStat.Set(utilization)
+
+	if queued > 0 && (busy >= workers || avgWait >= queueWaitThreshold) {
+		delta := p.max - workers
+		if queued < delta {
+			delta = queued
+		}
+		if delta > 0 {
+			return int(delta)
+		}
+		return 0
+	}
+
+	if queued == 0 && workers > p.min && 2*busy < workers {
+		return -1
+	}
+
+	return 0
+}
+
+// CheckQueued implements scheduler.QueueObserver
+func (r *Runner) CheckQueued() {
+	atomic.AddInt32(&r.pool.queued, 1)
+	runnerStats.Add("QueuedChecks", 1)
+}
+
+// CheckDequeued implements scheduler.QueueObserver
+func (r *Runner) CheckDequeued(wait time.Duration) {
+	atomic.AddInt32(&r.pool.queued, -1)
+	atomic.AddInt64(&r.pool.waitSum, int64(wait))
+	atomic.AddInt64(&r.pool.waitCount, 1)
+	runnerStats.Add("QueuedChecks", -1)
+}
+
+// addWorkers starts n more workers
+func (r *Runner) addWorkers(n int) {
+	for i := 0; i < n; i++ {
+		r.pool.workerAdded()
+		go r.work()
+	}
+}
+
+// scale resizes the pool every `interval` until the runner stops
+func (r *Runner) scale(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.pool.stop:
+			return
+		case <-ticker.C:
+			delta := r.pool.evaluate()
+			if delta > 0 {
+				log.Infof("Checks are waiting to be run, adding %d workers to the runner", delta)
+				r.addWorkers(delta)
+			} else if delta < 0 {
+				// only an idle worker can pick this up, don't wait for one
+				select {
+				case r.pool.shrink <- struct{}{}:
+					log.Debug("Load decreased, removing a worker from the runner")
+				default:
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package runner
+
+import (
+	"expvar"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type BlockingCheck struct {
+	TestCheck
+	id      string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *BlockingCheck) Run() error   { close(c.started); <-c.release; return nil }
+func (c *BlockingCheck) ID() check.ID { return check.ID(c.id) }
+
+func newBlockingCheck(id string) *BlockingCheck {
+	return &BlockingCheck{id: id, started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func TestPoolEvaluate(t *testing.T) {
+	p := newWorkerPool(1, 4)
+	p.workers = 2
+
+	// nothing to do
+	p.busyWorkers = 1
+	assert.Equal(t, 0, p.evaluate())
+
+	// every worker is busy and checks are waiting
+	p.busyWorkers = 2
+	p.queued = 1
+	assert.Equal(t, 1, p.evaluate())
+	p.queued = 5
+	assert.Equal(t, 2, p.evaluate())
+
+	// checks are waiting too long
+	p.busyWorkers = 1
+	p.queued = 1
+	assert.Equal(t, 0, p.evaluate())
+	p.waitSum = int64(3 * queueWaitThreshold)
+	p.waitCount = 2
+	assert.Equal(t, 1, p.evaluate())
+	assert.Equal(t, int64(1500), queueWaitStat.Value())
+
+	// the pool is full
+	p.workers = 4
+	p.busyWorkers = 4
+	assert.Equal(t, 0, p.evaluate())
+	assert.Equal(t, 1.0, utilizationStat.Value())
+
+	// load decreased
+	p.queued = 0
+	p.busyWorkers = 1
+	assert.Equal(t, -1, p.evaluate())
+	assert.Equal(t, 0.25, utilizationStat.Value())
+
+	// never below the minimum
+	p.workers = 1
+	p.busyWorkers = 0
+	assert.Equal(t, 0, p.evaluate())
+}
+
+func TestPoolScaling(t *testing.T) {
+	defer func(d time.Duration) { scaleInterval = d }(scaleInterval)
+	scaleInterval = 10 * time.Millisecond
+
+	r := NewRunner(1)
+	defer r.Stop()
+
+	c1 := newBlockingCheck("c1")
+	r.pending <- c1
+	<-c1.started
+
+	// the only worker is busy, a new one is started for the waiting check
+	c2 := newBlockingCheck("c2")
+	r.CheckQueued()
+	select {
+	case r.pending <- c2:
+		r.CheckDequeued(0)
+	case <-time.After(time.Second):
+		require.FailNow(t, "the pool didn't grow")
+	}
+	<-c2.started
+	assert.Equal(t, int32(2), atomic.LoadInt32(&r.pool.workers))
+
+	// back to the minimum once the checks are done
+	close(c1.release)
+	close(c2.release)
+	assert.Condition(t, func() bool {
+		for i := 0; i < 100; i++ {
+			if atomic.LoadInt32(&r.pool.workers) == 1 {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	})
+}
+
+func TestSkippedRuns(t *testing.T) {
+	skipped := runnerStats.Get("SkippedRuns").(*expvar.Int).Value()
+
+	r := NewRunner(1)
+	defer r.Stop()
+	c := &TestCheck{}
+	r.m.Lock()
+	r.runningChecks[c.ID()] = c
+	r.m.Unlock()
+
+	r.pending <- c
+	assert.Condition(t, func() bool {
+		for i := 0; i < 100; i++ {
+			if runnerStats.Get("SkippedRuns").(*expvar.Int).Value() == skipped+1 {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	})
+	assert.False(t, c.hasRun)
+}
@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package runner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	log "github.com/cihub/seelog"
+)
+
+var (
+	// long running checks are retried when they fail 3 times in a row
+	// without running for at least 5 seconds
+	defaultLongRunningPolicy = check.RetryPolicy{MaxAttempts: 3, MinRuntime: 5 * time.Second}
+	// regular checks are not retried, they'll run again at the next interval
+	defaultPolicy = check.RetryPolicy{MaxAttempts: 1}
+
+	defaultCircuitBreakerBackoff = 1 * time.Minute
+	maxCircuitBreakerBackoff     = 1 * time.Hour
+)
+
+// getRetryPolicy returns the retry policy of a check, the defaults fill the
+// settings the check doesn't set
+func getRetryPolicy(c check.Check) check.RetryPolicy {
+	p := defaultPolicy
+	if c.Interval() == 0 {
+		p = defaultLongRunningPolicy
+	}
+
+	if wr, ok := c.(check.WithRetryPolicy); ok && wr.RetryPolicy() != nil {
+		custom := wr.RetryPolicy()
+		if custom.MaxAttempts > 0 {
+			p.MaxAttempts = custom.MaxAttempts
+		}
+		if custom.Backoff > 0 {
+			p.Backoff = custom.Backoff
+		}
+		if custom.MinRuntime > 0 {
+			p.MinRuntime = custom.MinRuntime
+		}
+		p.CircuitBreakerThreshold = custom.CircuitBreakerThreshold
+		p.CircuitBreakerBackoff = custom.CircuitBreakerBackoff
+	}
+
+	if p.CircuitBreakerThreshold > 0 && p.CircuitBreakerBackoff == 0 {
+		p.CircuitBreakerBackoff = defaultCircuitBreakerBackoff
+	}
+	return p
+}
+
+// retry invokes the callback until it succeeds or fails `MaxAttempts` times
+// in a row, waiting `Backoff` before the first retry and doubling the wait
+// afterwards. A failed attempt that lasted at least `MinRuntime` (when set)
+// resets the count. Timeouts are not retried, and no retry starts after the
+// deadline (when set), so a failing check doesn't hold its worker for long. It
+// returns the number of retries along with the last error.
+func retry(policy check.RetryPolicy, deadline time.Time, callback func() error) (retries int, err error) {
+	attempts := 0
+	backoff := policy.Backoff
+
+	for {
+		t0 := time.Now()
+		err = callback()
+		if err == nil || isTimeout(err) {
+			return retries, err
+		}
+
+		// how much did the callback run?
+		if policy.MinRuntime > 0 && time.Since(t0) >= policy.MinRuntime {
+			// the callback failed after MinRuntime, reset the counter
+			attempts = 0
+			backoff = policy.Backoff
+		} else {
+			// the callback failed too soon, retry but increment the counter
+			attempts++
+		}
+
+		if attempts >= policy.MaxAttempts {
+			if retries == 0 {
+				return retries, err
+			}
+			// give up
+			return retries, fmt.Errorf("bail out after %d attempts, last error: %v", attempts, err)
+		}
+
+		if !deadline.IsZero() && !time.Now().Add(backoff).Before(deadline) {
+			if retries == 0 {
+				return retries, err
+			}
+			// no time left for another attempt
+			return retries, fmt.Errorf("bail out after %d attempts, no time left to retry, last error: %v", retries+1, err)
+		}
+
+		log.Warnf("Retrying in %v, got an error executing the callback: %v", backoff, err)
+		retries++
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// circuit tracks the consecutive failures of a check
+type circuit struct {
+	failures  int       // consecutive failed runs
+	trips     int       // times the circuit opened since the last successful run
+	openUntil time.Time // the check is disabled until then
+}
+
+// circuitBreaker disables the checks failing `CircuitBreakerThreshold` runs in
+// a row for `CircuitBreakerBackoff`. When the run attempted once the check is
+// re-enabled fails, the check is disabled again for twice as long.
+type circuitBreaker struct {
+	circuits map[check.ID]*circuit
+	m        sync.Mutex
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{
+		circuits: make(map[check.ID]*circuit),
+	}
+}
+
+// allow returns whether a check can run and, if it can't, until when it's
+// disabled
+func (cb *circuitBreaker) allow(id check.ID, now time.Time) (bool, time.Time) {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+
+	c, found := cb.circuits[id]
+	if !found || !now.Before(c.openUntil) {
+		return true, time.Time{}
+	}
+	return false, c.openUntil
+}
+
+// record tracks the outcome of a run and returns until when the check is
+// disabled, the zero time if it's enabled
+func (cb *circuitBreaker) record(id check.ID, policy check.RetryPolicy, failed bool, now time.Time) time.Time {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+
+	if !failed || policy.CircuitBreakerThreshold <= 0 {
+		delete(cb.circuits, id)
+		return time.Time{}
+	}
+
+	c, found := cb.circuits[id]
+	if !found {
+		c = &circuit{}
+		cb.circuits[id] = c
+	}
+	c.failures++
+
+	// open when the threshold is reached, or again when the first run after
+	// the check was re-enabled failed
+	if c.trips == 0 && c.failures < policy.CircuitBreakerThreshold {
+		return time.Time{}
+	}
+
+	max := maxCircuitBreakerBackoff
+	if policy.CircuitBreakerBackoff > max {
+		max = policy.CircuitBreakerBackoff
+	}
+	delay := policy.CircuitBreakerBackoff
+	for i := 0; i < c.trips && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	c.trips++
+	c.openUntil = now.Add(delay)
+	return c.openUntil
+}
+
+// reset forgets the failures of a check, it's called when the check is stopped
+// so an instance scheduled again with the same ID starts with a closed circuit
+func (cb *circuitBreaker) reset(id check.ID) {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+	delete(cb.circuits, id)
+}
+
+// message returns the `datadog.agent.check_status` message of a disabled check
+func (cb *circuitBreaker) message(id check.ID, until time.Time) string {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+
+	failures := 0
+	if c, found := cb.circuits[id]; found {
+		failures = c.failures
+	}
+	return fmt.Sprintf("check disabled after %d consecutive failed runs, next attempt at %s", failures, until.UTC().Format(time.RFC3339))
+}
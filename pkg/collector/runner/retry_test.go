@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package runner
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/stretchr/testify/assert"
+)
+
+type FailingCheck struct {
+	TestCheck
+	runs   int32
+	policy *check.RetryPolicy
+}
+
+func (c *FailingCheck) Run() error                      { atomic.AddInt32(&c.runs, 1); return errors.New("boom") }
+func (c *FailingCheck) ID() check.ID                    { return check.ID("FailingCheck") }
+func (c *FailingCheck) RetryPolicy() *check.RetryPolicy { return c.policy }
+
+type LongRunningCheck struct {
+	TestCheck
+}
+
+func (c *LongRunningCheck) Interval() time.Duration { return 0 }
+
+func TestGetRetryPolicy(t *testing.T) {
+	assert.Equal(t, check.RetryPolicy{MaxAttempts: 1}, getRetryPolicy(&TestCheck{}))
+	assert.Equal(t, check.RetryPolicy{MaxAttempts: 3, MinRuntime: 5 * time.Second}, getRetryPolicy(&LongRunningCheck{}))
+
+	c := &FailingCheck{policy: &check.RetryPolicy{Backoff: time.Second, CircuitBreakerThreshold: 3}}
+	assert.Equal(t, check.RetryPolicy{
+		MaxAttempts:             1,
+		Backoff:                 time.Second,
+		CircuitBreakerThreshold: 3,
+		CircuitBreakerBackoff:   time.Minute,
+	}, getRetryPolicy(c))
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	failTwice := func() error {
+		calls++
+		if calls <= 2 {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	retries, err := retry(check.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}, time.Time{}, failTwice)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, retries)
+
+	calls = 0
+	retries, err = retry(check.RetryPolicy{MaxAttempts: 2}, time.Time{}, failTwice)
+	assert.Equal(t, "bail out after 2 attempts, last error: boom", err.Error())
+	assert.Equal(t, 1, retries)
+
+	// no retry, the error is returned as is
+	calls = 0
+	retries, err = retry(check.RetryPolicy{MaxAttempts: 1}, time.Time{}, failTwice)
+	assert.Equal(t, "boom", err.Error())
+	assert.Equal(t, 0, retries)
+
+	// timeouts are not retried
+	calls = 0
+	retries, err = retry(check.RetryPolicy{MaxAttempts: 3}, time.Time{}, func() error {
+		calls++
+		return &check.TimeoutError{Timeout: time.Second}
+	})
+	assert.True(t, isTimeout(err))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 0, retries)
+
+	// failing after MinRuntime resets the attempts
+	calls = 0
+	retries, err = retry(check.RetryPolicy{MaxAttempts: 1, MinRuntime: time.Millisecond}, time.Time{}, func() error {
+		calls++
+		if calls <= 2 {
+			time.Sleep(2 * time.Millisecond)
+			return errors.New("boom")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, retries)
+
+	// no retry starts after the deadline
+	calls = 0
+	retries, err = retry(check.RetryPolicy{MaxAttempts: 3, Backoff: time.Hour}, time.Now().Add(time.Minute), failTwice)
+	assert.Equal(t, "boom", err.Error())
+	assert.Equal(t, 0, retries)
+	calls = 0
+	retries, err = retry(check.RetryPolicy{MaxAttempts: 3, Backoff: 20 * time.Millisecond}, time.Now().Add(30*time.Millisecond), failTwice)
+	assert.Equal(t, "bail out after 2 attempts, no time left to retry, last error: boom", err.Error())
+	assert.Equal(t, 1, retries)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := newCircuitBreaker()
+	policy := check.RetryPolicy{CircuitBreakerThreshold: 2, CircuitBreakerBackoff: time.Minute}
+	now := time.Date(2017, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, cb.record("foo", policy, true, now).IsZero())
+	enabled, _ := cb.allow("foo", now)
+	assert.True(t, enabled)
+
+	// threshold reached
+	until := cb.record("foo", policy, true, now)
+	assert.Equal(t, now.Add(time.Minute), until)
+	enabled, until = cb.allow("foo", now.Add(59*time.Second))
+	assert.False(t, enabled)
+	assert.Equal(t, now.Add(time.Minute), until)
+	assert.Equal(t, "check disabled after 2 consecutive failed runs, next attempt at 2017-10-02T12:01:00Z", cb.message("foo", until))
+
+	// re-enabled, fails again: disabled twice as long
+	now = now.Add(time.Minute)
+	enabled, _ = cb.allow("foo", now)
+	assert.True(t, enabled)
+	assert.Equal(t, now.Add(2*time.Minute), cb.record("foo", policy, true, now))
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, now.Add(4*time.Minute), cb.record("foo", policy, true, now))
+
+	// capped
+	policy.CircuitBreakerBackoff = 40 * time.Minute
+	assert.Equal(t, now.Add(time.Hour), cb.record("foo", policy, true, now))
+
+	// a successful run closes the circuit
+	assert.True(t, cb.record("foo", policy, false, now).IsZero())
+	enabled, _ = cb.allow("foo", now)
+	assert.True(t, enabled)
+	assert.True(t, cb.record("foo", policy, true, now).IsZero())
+
+	// disabled circuit breaker
+	assert.True(t, cb.record("bar", check.RetryPolicy{}, true, now).IsZero())
+}
+
+func TestRunCircuitBreaker(t *testing.T) {
+	r := NewRunner(1)
+	defer r.Stop()
+	c := &FailingCheck{policy: &check.RetryPolicy{MaxAttempts: 2, CircuitBreakerThreshold: 2}}
+
+	for i := 0; i < 3; i++ {
+		r.pending <- c
+	}
+	// make sure the last one was processed
+	r.pending <- &TestCheck{}
+
+	// 2 runs of 2 attempts, the third run is skipped
+	assert.Equal(t, int32(4), atomic.LoadInt32(&c.runs))
+	enabled, _ := r.circuits.allow(c.ID(), time.Now())
+	assert.False(t, enabled)
+
+	checkStats.M.RLock()
+	s := checkStats.Stats[c.ID()]
+	checkStats.M.RUnlock()
+	assert.Equal(t, uint64(2), s.TotalRetries)
+	assert.True(t, s.DisabledUntil > 0)
+
+	// a stopped check is forgotten
+	assert.Nil(t, r.StopCheck(c.ID()))
+	enabled, _ = r.circuits.allow(c.ID(), time.Now())
+	assert.True(t, enabled)
+}
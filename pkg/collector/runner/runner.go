@@ -6,6 +6,7 @@
 package runner
 
 import (
+	"errors"
 	"expvar"
 	"fmt"
 	"sync"
@@ -16,6 +17,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	"github.com/DataDog/datadog-agent/pkg/util"
 	log "github.com/cihub/seelog"
 )
@@ -36,6 +38,12 @@ var (
 func init() {
 	runnerStats = expvar.NewMap("runner")
 	runnerStats.Set("Checks", expvar.Func(expCheckStats))
+	runnerStats.Set("Utilization", &utilizationStat)
+	runnerStats.Set("QueueWaitTime", &queueWaitStat)
+	// make sure the pool counters are always published
+	runnerStats.Add("BusyWorkers", 0)
+	runnerStats.Add("QueuedChecks", 0)
+	runnerStats.Add("SkippedRuns", 0)
 	checkStats = &runnerCheckStats{
 		Stats: make(map[check.ID]*check.Stats),
 	}
@@ -43,29 +51,40 @@ func init() {
 
 // Runner ...
 type Runner struct {
-	pending       chan check.Check         // The channel where checks come from
-	done          chan bool                // Guard for the main loop
-	runningChecks map[check.ID]check.Check // the list of checks running
-	m             sync.Mutex               // to control races on runningChecks
-	running       uint32                   // Flag to see if the Runner is, well, running
+	pending           chan check.Check         // The channel where checks come from
+	done              chan bool                // Guard for the main loop
+	runningChecks     map[check.ID]check.Check // the list of checks running
+	quarantinedChecks map[check.ID]check.Check // the checks whose run timed out and was abandoned
+	circuits          *circuitBreaker          // disables the checks failing too many times in a row
+	m                 sync.Mutex               // to control races on runningChecks and quarantinedChecks
+	running           uint32                   // Flag to see if the Runner is, well, running
+	pool              *workerPool              // Keeps track of the workers and scales their number
 }
 
-// NewRunner takes the number of desired goroutines processing incoming checks.
+// NewRunner takes the number of goroutines processing incoming checks at
+// startup. The pool then grows up to `check_runners_max` workers when checks
+// wait to be run and shrinks back to `numWorkers` when the load decreases.
 func NewRunner(numWorkers int) *Runner {
 	r := &Runner{
 		// initialize the channel
-		pending:       make(chan check.Check),
-		runningChecks: make(map[check.ID]check.Check),
-		running:       1,
+		pending:           make(chan check.Check),
+		runningChecks:     make(map[check.ID]check.Check),
+		quarantinedChecks: make(map[check.ID]check.Check),
+		circuits:          newCircuitBreaker(),
+		running:           1,
 	}
 
-	// start the workers
-	for i := 0; i < numWorkers; i++ {
-		go r.work()
+	maxWorkers := config.Datadog.GetInt("check_runners_max")
+	if maxWorkers < numWorkers {
+		maxWorkers = numWorkers
 	}
+	r.pool = newWorkerPool(numWorkers, maxWorkers)
+
+	// start the workers
+	r.addWorkers(numWorkers)
+	go r.scale(scaleInterval)
 
-	log.Infof("Runner started with %d workers.", numWorkers)
-	runnerStats.Add("Workers", int64(numWorkers))
+	log.Infof("Runner started with %d workers, up to %d.", numWorkers, maxWorkers)
 	return r
 }
 
@@ -79,6 +98,7 @@ func (r *Runner) Stop() {
 	log.Info("Runner is shutting down...")
 
 	close(r.pending)
+	close(r.pool.stop)
 	atomic.StoreUint32(&r.running, 0)
 
 	// stop checks that are still running
@@ -108,9 +128,11 @@ func (r *Runner) GetChan() chan<- check.Check {
 }
 
 // StopCheck invokes the `Stop` method on a check if it's running. If the check
-// is not running, this is a noop
+// is not running, this is a noop. The circuit breaker forgets the check either
+// way.
 func (r *Runner) StopCheck(id check.ID) error {
 	done := make(chan bool)
+	r.circuits.reset(id)
 
 	r.m.Lock()
 	defer r.m.Unlock()
@@ -133,97 +155,231 @@ func (r *Runner) StopCheck(id check.ID) error {
 	}
 }
 
-// work waits for checks and run them as long as they arrive on the channel
+// work waits for checks and run them as long as they arrive on the channel,
+// or until the pool shrinks
 func (r *Runner) work() {
 	log.Debug("Ready to process checks...")
 
-	for check := range r.pending {
-		// see if the check is already running
-		r.m.Lock()
-		if _, isRunning := r.runningChecks[check.ID()]; isRunning {
-			log.Debugf("Check %s is already running, skip execution...", check)
-			r.m.Unlock()
-			continue
-		} else {
-			r.runningChecks[check.ID()] = check
-			runnerStats.Add("RunningChecks", 1)
+	for {
+		select {
+		case <-r.pool.shrink:
+			r.pool.workerDone()
+			log.Debug("Worker removed from the pool.")
+			return
+		case check, ok := <-r.pending:
+			if !ok {
+				r.pool.workerDone()
+				log.Debug("Finished processing checks.")
+				return
+			}
+			r.pool.busy(1)
+			r.process(check)
+			r.pool.busy(-1)
 		}
+	}
+}
+
+// process runs a check and publishes the outcome
+func (r *Runner) process(check check.Check) {
+	// see if the check was disabled by the circuit breaker
+	if enabled, until := r.circuits.allow(check.ID(), time.Now()); !enabled {
+		log.Debugf("Check %s is disabled until %v, skip execution...", check, until)
+		sendServiceCheck(check, metrics.ServiceCheckCritical, r.circuits.message(check.ID(), until))
+		return
+	}
+
+	// see if the check is already running
+	r.m.Lock()
+	if _, isRunning := r.runningChecks[check.ID()]; isRunning {
+		log.Debugf("Check %s is already running, skip execution...", check)
 		r.m.Unlock()
+		runnerStats.Add("SkippedRuns", 1)
+		return
+	}
+	r.runningChecks[check.ID()] = check
+	runnerStats.Add("RunningChecks", 1)
+	r.m.Unlock()
 
-		doLog, lastLog := shouldLog(check.ID())
+	doLog, lastLog := shouldLog(check.ID())
 
-		if doLog {
-			log.Infof("Running check %s", check)
-		} else {
-			log.Debugf("Running check %s", check)
-		}
+	if doLog {
+		log.Infof("Running check %s", check)
+	} else {
+		log.Debugf("Running check %s", check)
+	}
 
-		// run the check
-		var err error
-		t0 := time.Now()
-
-		if check.Interval() == 0 {
-			// retry long running checks, bail out if they return an error 3 times
-			// in a row without running for at least 5 seconds
-			// TODO: this should be check-configurable, with meaningful default values
-			err = retry(5*time.Second, 3, check.Run)
-		} else {
-			// normal check run
-			err = check.Run()
-		}
+	// run the check, retrying according to its policy
+	var err error
+	var retries int
+	policy := getRetryPolicy(check)
+	t0 := time.Now()
+
+	if check.Interval() == 0 {
+		// long running checks have a worker of their own
+		retries, err = retry(policy, time.Time{}, check.Run)
+	} else if timeout := getTimeout(check); timeout > 0 {
+		// normal check run, abandoned if it doesn't complete in time, the
+		// retries only get the time left
+		deadline := t0.Add(timeout)
+		retries, err = retry(policy, deadline, func() error { return r.runBefore(check, deadline, timeout) })
+	} else {
+		// no timeout, don't retry past the next scheduled run
+		retries, err = retry(policy, t0.Add(check.Interval()), check.Run)
+	}
+	timedOut := isTimeout(err)
+	if err != nil && !timedOut {
+		// the error might quote the decrypted secrets of the instance
+		err = errors.New(secrets.Scrub(err.Error()))
+	}
+	if retries > 0 {
+		runnerStats.Add("Retries", int64(retries))
+	}
 
-		warnings := check.GetWarnings()
+	// disable the check if it keeps failing
+	disabledUntil := r.circuits.record(check.ID(), policy, err != nil, time.Now())
 
-		// use the default sender for the service checks
-		sender, e := aggregator.GetDefaultSender()
-		if e != nil {
-			log.Errorf("Error getting default sender: %v. Not sending status check for %s", e, check)
-		}
-		serviceCheckTags := []string{fmt.Sprintf("check:%s", check.String())}
-		serviceCheckStatus := metrics.ServiceCheckOK
+	// the abandoned run still owns the check, don't touch it
+	var warnings []error
+	if !timedOut {
+		warnings = check.GetWarnings()
+	}
 
-		hostname := getHostname()
+	serviceCheckStatus := metrics.ServiceCheckOK
+	serviceCheckMessage := ""
 
-		if len(warnings) != 0 {
-			// len returns int, and this expect int64, so it has to be converted
-			runnerStats.Add("Warnings", int64(len(warnings)))
-			serviceCheckStatus = metrics.ServiceCheckWarning
-		}
+	if len(warnings) != 0 {
+		// len returns int, and this expect int64, so it has to be converted
+		runnerStats.Add("Warnings", int64(len(warnings)))
+		serviceCheckStatus = metrics.ServiceCheckWarning
+	}
 
-		if err != nil {
-			log.Errorf("Error running check %s: %s", check, err)
-			runnerStats.Add("Errors", 1)
-			serviceCheckStatus = metrics.ServiceCheckCritical
-		}
+	if err != nil {
+		log.Errorf("Error running check %s: %s", check, err)
+		runnerStats.Add("Errors", 1)
+		serviceCheckStatus = metrics.ServiceCheckCritical
+	}
 
-		if sender != nil {
-			sender.ServiceCheck("datadog.agent.check_status", serviceCheckStatus, hostname, serviceCheckTags, "")
-			sender.Commit()
-		}
+	if timedOut {
+		serviceCheckMessage = err.Error()
+	}
 
-		// remove the check from the running list
+	if !disabledUntil.IsZero() {
+		log.Warnf("Check %s failed too many times in a row, disabling it until %v", check, disabledUntil)
+		runnerStats.Add("ChecksDisabled", 1)
+		serviceCheckMessage = fmt.Sprintf("%s, last error: %v", r.circuits.message(check.ID(), disabledUntil), err)
+	}
+
+	sendServiceCheck(check, serviceCheckStatus, serviceCheckMessage)
+
+	var mStats map[string]int64
+	if !timedOut {
+		// remove the check from the running list, a timed out check
+		// is removed when its abandoned run eventually returns
 		r.m.Lock()
 		delete(r.runningChecks, check.ID())
 		r.m.Unlock()
-
-		// publish statistics about this run
 		runnerStats.Add("RunningChecks", -1)
-		runnerStats.Add("Runs", 1)
-		mStats, _ := check.GetMetricStats()
-		addWorkStats(check, time.Since(t0), err, warnings, mStats)
-
-		l := "Done running check %s"
-		if doLog {
-			if lastLog {
-				l = l + fmt.Sprintf(" first runs done, next runs will be logged every %v runs", config.Datadog.GetInt64("logging_frequency"))
-			}
-			log.Infof(l, check)
-		} else {
-			log.Debugf(l, check)
+		mStats, _ = check.GetMetricStats()
+	}
+
+	// publish statistics about this run
+	runnerStats.Add("Runs", 1)
+	s := addWorkStats(check, time.Since(t0), err, warnings, mStats)
+	s.AddRetries(uint64(retries))
+	if disabledUntil.IsZero() {
+		s.SetDisabledUntil(0)
+	} else {
+		s.SetDisabledUntil(disabledUntil.Unix())
+	}
+
+	l := "Done running check %s"
+	if doLog {
+		if lastLog {
+			l = l + fmt.Sprintf(" first runs done, next runs will be logged every %v runs", config.Datadog.GetInt64("logging_frequency"))
 		}
+		log.Infof(l, check)
+	} else {
+		log.Debugf(l, check)
+	}
+}
+
+// getTimeout returns how long a check run can last before being abandoned,
+// zero means no timeout
+func getTimeout(c check.Check) time.Duration {
+	if wt, ok := c.(check.WithTimeout); ok && wt.Timeout() > 0 {
+		return wt.Timeout()
+	}
+	return time.Duration(config.Datadog.GetInt("check_timeout")) * time.Second
+}
+
+// isTimeout returns whether the error is a check run timeout
+func isTimeout(err error) bool {
+	_, ok := err.(*check.TimeoutError)
+	return ok
+}
+
+// runWithTimeout runs the check and waits for it at most `timeout`. When the
+// run doesn't complete in time, the check is quarantined until the abandoned
+// run returns and its `Stop` method is invoked.
+func (r *Runner) runWithTimeout(c check.Check, timeout time.Duration) error {
+	if timeout <= 0 {
+		return c.Run()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
 	}
 
-	log.Debug("Finished processing checks.")
+	r.quarantine(c, done)
+	return &check.TimeoutError{Timeout: timeout}
+}
+
+// runBefore runs the check with the time left until the deadline as timeout,
+// `timeout` is the one reported when no time is left
+func (r *Runner) runBefore(c check.Check, deadline time.Time, timeout time.Duration) error {
+	left := time.Until(deadline)
+	if left <= 0 {
+		// not a timeout: no run was abandoned, nothing is quarantined
+		return fmt.Errorf("no time left to run the check before its %v timeout", timeout)
+	}
+	return r.runWithTimeout(c, left)
+}
+
+// quarantine keeps a timed out check in the running list, so it's not
+// scheduled again, until its abandoned run returns
+func (r *Runner) quarantine(c check.Check, done <-chan error) {
+	log.Warnf("Check %s didn't complete in time, abandoning the run and stopping the check", c)
+
+	r.m.Lock()
+	r.quarantinedChecks[c.ID()] = c
+	r.m.Unlock()
+	runnerStats.Add("Timeouts", 1)
+	runnerStats.Add("QuarantinedChecks", 1)
+
+	// Stop might hang as well, don't wait for it
+	go c.Stop()
+
+	go func() {
+		err := <-done
+		log.Infof("Abandoned run of check %s returned, releasing it from quarantine (error: %v)", c, err)
+
+		r.m.Lock()
+		delete(r.quarantinedChecks, c.ID())
+		delete(r.runningChecks, c.ID())
+		r.m.Unlock()
+		runnerStats.Add("QuarantinedChecks", -1)
+		runnerStats.Add("RunningChecks", -1)
+	}()
 }
 
 func shouldLog(id check.ID) (doLog bool, lastLog bool) {
@@ -249,7 +405,7 @@ func shouldLog(id check.ID) (doLog bool, lastLog bool) {
 	return
 }
 
-func addWorkStats(c check.Check, execTime time.Duration, err error, warnings []error, mStats map[string]int64) {
+func addWorkStats(c check.Check, execTime time.Duration, err error, warnings []error, mStats map[string]int64) *check.Stats {
 	var s *check.Stats
 	var found bool
 
@@ -262,6 +418,20 @@ func addWorkStats(c check.Check, execTime time.Duration, err error, warnings []e
 	checkStats.M.Unlock()
 
 	s.Add(execTime, err, warnings, mStats)
+	return s
+}
+
+// sendServiceCheck sends the `datadog.agent.check_status` service check of a
+// check with the default sender
+func sendServiceCheck(c check.Check, status metrics.ServiceCheckStatus, message string) {
+	sender, err := aggregator.GetDefaultSender()
+	if err != nil {
+		log.Errorf("Error getting default sender: %v. Not sending status check for %s", err, c)
+		return
+	}
+	tags := []string{fmt.Sprintf("check:%s", c.String())}
+	sender.ServiceCheck("datadog.agent.check_status", status, getHostname(), tags, message)
+	sender.Commit()
 }
 
 func expCheckStats() interface{} {
@@ -283,32 +453,3 @@ func getHostname() string {
 	hostname, _ := util.GetHostname()
 	return hostname
 }
-
-func retry(retryDuration time.Duration, retries int, callback func() error) (err error) {
-	attempts := 0
-
-	for {
-		t0 := time.Now()
-		err = callback()
-		if err == nil {
-			return nil
-		}
-
-		// how much did the callback run?
-		execDuration := time.Now().Sub(t0)
-		if execDuration < retryDuration {
-			// the callback failed too soon, retry but increment the counter
-			attempts++
-		} else {
-			// the callback failed after the retryDuration, reset the counter
-			attempts = 0
-		}
-
-		if attempts == retries {
-			// give up
-			return fmt.Errorf("bail out, last error: %v", err)
-		}
-
-		log.Warnf("Retrying, got an error executing the callback: %v", err)
-	}
-}
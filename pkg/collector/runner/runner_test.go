@@ -12,6 +12,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // FIXTURE
@@ -133,3 +134,92 @@ func TestStopCheck(t *testing.T) {
 	err = r.StopCheck(c2.ID())
 	assert.Equal(t, "timeout during stop operation on check id TestCheck", err.Error())
 }
+
+type HangingCheck struct {
+	TestCheck
+	release chan struct{}
+	stopped chan struct{}
+}
+
+func (c *HangingCheck) Run() error             { <-c.release; return nil }
+func (c *HangingCheck) Stop()                  { close(c.stopped) }
+func (c *HangingCheck) ID() check.ID           { return check.ID("HangingCheck") }
+func (c *HangingCheck) Timeout() time.Duration { return 50 * time.Millisecond }
+
+func TestRunTimeout(t *testing.T) {
+	r := NewRunner(1)
+	defer r.Stop()
+	c := &HangingCheck{release: make(chan struct{}), stopped: make(chan struct{})}
+
+	r.pending <- c
+	select {
+	case <-c.stopped:
+	case <-time.After(time.Second):
+		require.FailNow(t, "the check wasn't stopped after timing out")
+	}
+
+	// the worker is available again
+	select {
+	case r.pending <- &TestCheck{}:
+	case <-time.After(time.Second):
+		require.FailNow(t, "the worker is still busy with the timed out check")
+	}
+
+	assert.Condition(t, func() bool {
+		for i := 0; i < 100; i++ {
+			checkStats.M.RLock()
+			s, found := checkStats.Stats[c.ID()]
+			checkStats.M.RUnlock()
+			if found {
+				return s.TotalTimeouts == 1 && s.LongestExecutionTime >= 50 && s.LastError != ""
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	})
+
+	r.m.Lock()
+	_, quarantined := r.quarantinedChecks[c.ID()]
+	_, running := r.runningChecks[c.ID()]
+	r.m.Unlock()
+	assert.True(t, quarantined)
+	assert.True(t, running)
+
+	// the abandoned run returns, the check is released
+	close(c.release)
+	assert.Condition(t, func() bool {
+		for i := 0; i < 100; i++ {
+			r.m.Lock()
+			_, quarantined = r.quarantinedChecks[c.ID()]
+			_, running = r.runningChecks[c.ID()]
+			r.m.Unlock()
+			if !quarantined && !running {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	})
+}
+
+func TestGetTimeout(t *testing.T) {
+	assert.Equal(t, 300*time.Second, getTimeout(&TestCheck{}))
+	assert.Equal(t, 50*time.Millisecond, getTimeout(&HangingCheck{}))
+}
+
+func TestRunBeforeNoTimeLeft(t *testing.T) {
+	r := NewRunner(1)
+	defer r.Stop()
+	c := &TestCheck{}
+
+	// not a timeout: the check didn't run and isn't quarantined
+	err := r.runBefore(c, time.Now().Add(-time.Second), 50*time.Millisecond)
+	require.Error(t, err)
+	assert.False(t, isTimeout(err))
+	assert.False(t, c.hasRun)
+
+	r.m.Lock()
+	_, quarantined := r.quarantinedChecks[c.ID()]
+	r.m.Unlock()
+	assert.False(t, quarantined)
+}
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package scheduler
+
+import "time"
+
+// clock abstracts the time source of the job queues and of the scheduled
+// jobs, so tests can drive them deterministically
+type clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) ticker
+	NewTimer(d time.Duration) timer
+}
+
+// ticker is the subset of time.Ticker the job queues use
+type ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// timer is the subset of time.Timer the scheduled jobs use
+type timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// realClock is the clock based on the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) ticker {
+	return &realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+func (realClock) NewTimer(d time.Duration) timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
@@ -14,34 +14,54 @@ import (
 	log "github.com/cihub/seelog"
 )
 
+// bucketDuration is the resolution used to spread the checks of a queue
+// across its interval
+const bucketDuration = 1 * time.Second
+
 // jobQueue contains a list of checks (called jobs) that need to be
 // scheduled at a certain interval.
+// To avoid running all of them at once, the interval is split into buckets
+// that fire one after the other: every bucket holds a share of the checks,
+// bucket sizes never differ by more than one.
 type jobQueue struct {
-	interval time.Duration
-	stop     chan bool
-	ticker   *time.Ticker
-	jobs     []check.Check
-	running  bool
-	mu       sync.RWMutex // to protect critical sections in struct's fields
+	interval   time.Duration
+	tick       time.Duration // the time between two buckets
+	stop       chan bool
+	clock      clock
+	jobs       []check.Check
+	buckets    [][]check.Check
+	nextBucket int // the bucket to schedule on the next tick
+	running    bool
+	mu         sync.RWMutex // to protect critical sections in struct's fields
 }
 
 // newJobQueue creates a new jobQueue instance
 // the stop channel is buffered so the scheduler loop can send a message to stop
 // without blocking
-func newJobQueue(interval time.Duration) *jobQueue {
+func newJobQueue(interval time.Duration, c clock) *jobQueue {
+	nb := int(interval / bucketDuration)
+	if nb < 1 {
+		nb = 1
+	}
+
 	return &jobQueue{
 		interval: interval,
-		ticker:   time.NewTicker(time.Duration(interval)),
+		tick:     interval / time.Duration(nb),
 		stop:     make(chan bool, 1),
+		clock:    c,
+		buckets:  make([][]check.Check, nb),
 	}
 }
 
-// addJob is a convenience method to add a check to a queue
+// addJob is a convenience method to add a check to a queue, in its least
+// loaded bucket
 func (jq *jobQueue) addJob(c check.Check) {
 	jq.mu.Lock()
 	defer jq.mu.Unlock()
 
 	jq.jobs = append(jq.jobs, c)
+	idx := jq.smallestBucket()
+	jq.buckets[idx] = append(jq.buckets[idx], c)
 }
 
 func (jq *jobQueue) removeJob(id check.ID) error {
@@ -51,6 +71,7 @@ func (jq *jobQueue) removeJob(id check.ID) error {
 	for i, c := range jq.jobs {
 		if c.ID() == id {
 			jq.jobs = append(jq.jobs[:i], jq.jobs[i+1:]...)
+			jq.removeFromBuckets(id)
 			return nil
 		}
 	}
@@ -58,24 +79,82 @@ func (jq *jobQueue) removeJob(id check.ID) error {
 	return fmt.Errorf("check with id %s is not in this Job Queue", id)
 }
 
+// removeFromBuckets removes a check from its bucket then, if the buckets
+// are not balanced anymore, moves a check from the largest bucket to the
+// one that just shrank.
+// Must be called with the lock held.
+func (jq *jobQueue) removeFromBuckets(id check.ID) {
+	for b, bucket := range jq.buckets {
+		for i, c := range bucket {
+			if c.ID() != id {
+				continue
+			}
+			jq.buckets[b] = append(bucket[:i], bucket[i+1:]...)
+
+			largest := jq.largestBucket()
+			if len(jq.buckets[largest])-len(jq.buckets[b]) > 1 {
+				last := len(jq.buckets[largest]) - 1
+				moved := jq.buckets[largest][last]
+				jq.buckets[largest] = jq.buckets[largest][:last]
+				jq.buckets[b] = append(jq.buckets[b], moved)
+				log.Debugf("Moved check %s to bucket %d of queue %v", moved, b, jq.interval)
+			}
+			return
+		}
+	}
+}
+
+// smallestBucket returns the index of the first bucket with the fewest checks
+func (jq *jobQueue) smallestBucket() int {
+	idx := 0
+	for i, bucket := range jq.buckets {
+		if len(bucket) < len(jq.buckets[idx]) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// largestBucket returns the index of the first bucket with the most checks
+func (jq *jobQueue) largestBucket() int {
+	idx := 0
+	for i, bucket := range jq.buckets {
+		if len(bucket) > len(jq.buckets[idx]) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// nextJobs returns the checks of the bucket due on this tick and moves on
+// to the next bucket
+func (jq *jobQueue) nextJobs() []check.Check {
+	jq.mu.Lock()
+	defer jq.mu.Unlock()
+
+	jobs := append([]check.Check{}, jq.buckets[jq.nextBucket]...)
+	jq.nextBucket = (jq.nextBucket + 1) % len(jq.buckets)
+	return jobs
+}
+
 // run schedules the checks in the queue by posting them to the
-// execution pipeline.
+// execution pipeline, one bucket at a time.
 // This doesn't block.
-func (jq *jobQueue) run(out chan<- check.Check) {
+func (jq *jobQueue) run(out chan<- check.Check, observer QueueObserver) {
+	t := jq.clock.NewTicker(jq.tick)
 	go func() {
 		for {
 			select {
 			case <-jq.stop:
 				// someone asked to stop this queue
-				jq.ticker.Stop()
-			case <-jq.ticker.C:
-				// normal case, (re)schedule the queue
-				jq.mu.RLock()
-				for _, check := range jq.jobs {
+				t.Stop()
+				return
+			case <-t.C():
+				// normal case, (re)schedule the checks of the current bucket
+				for _, check := range jq.nextJobs() {
 					log.Debugf("Enqueuing check %s for queue %d", check, jq.interval)
-					out <- check
+					enqueue(out, check, observer)
 				}
-				jq.mu.RUnlock()
 			}
 		}
 	}()
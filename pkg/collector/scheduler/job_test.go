@@ -0,0 +1,215 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock only moves forward when Advance is called
+type fakeClock struct {
+	now     time.Time
+	tickers []*fakeTicker
+	timers  []*fakeTimer
+	m       sync.Mutex
+}
+
+type fakeTicker struct {
+	clock   *fakeClock
+	c       chan time.Time
+	d       time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+func (t *fakeTicker) Stop() {
+	t.clock.m.Lock()
+	defer t.clock.m.Unlock()
+	t.stopped = true
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	c        chan time.Time
+	deadline time.Time
+	done     bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+func (t *fakeTimer) Stop() bool {
+	t.clock.m.Lock()
+	defer t.clock.m.Unlock()
+	wasActive := !t.done
+	t.done = true
+	return wasActive
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) timer {
+	c.m.Lock()
+	defer c.m.Unlock()
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), deadline: c.now.Add(d)}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// activeTimers returns the number of timers that are neither stopped nor fired
+func (c *fakeClock) activeTimers() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	n := 0
+	for _, t := range c.timers {
+		if !t.done {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) ticker {
+	c.m.Lock()
+	defer c.m.Unlock()
+	t := &fakeTicker{clock: c, c: make(chan time.Time, 1), d: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward, firing the tickers and timers on the way. Like
+// time.Ticker, ticks are dropped when the previous one wasn't consumed.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		for !t.stopped && !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.d)
+		}
+	}
+	for _, t := range c.timers {
+		if !t.done && !t.deadline.After(c.now) {
+			t.c <- t.deadline
+			t.done = true
+		}
+	}
+}
+
+type namedCheck struct {
+	TestCheck
+	name string
+}
+
+func (c *namedCheck) String() string { return c.name }
+func (c *namedCheck) ID() check.ID   { return check.ID(c.name) }
+
+func bucketSizes(jq *jobQueue) []int {
+	sizes := []int{}
+	for _, b := range jq.buckets {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+// receive reads n checks from the pipe and returns their names
+func receive(t *testing.T, pipe <-chan check.Check, n int) []string {
+	names := []string{}
+	for i := 0; i < n; i++ {
+		select {
+		case c := <-pipe:
+			names = append(names, c.String())
+		case <-time.After(time.Second):
+			t.Fatalf("check not scheduled, got %v, expected %d checks", names, n)
+		}
+	}
+	return names
+}
+
+func TestNewJobQueueBuckets(t *testing.T) {
+	assert.Len(t, newJobQueue(15*time.Second, realClock{}).buckets, 15)
+	assert.Equal(t, time.Second, newJobQueue(15*time.Second, realClock{}).tick)
+	assert.Len(t, newJobQueue(1500*time.Millisecond, realClock{}).buckets, 1)
+	assert.Equal(t, 1500*time.Millisecond, newJobQueue(1500*time.Millisecond, realClock{}).tick)
+}
+
+func TestJobQueueBalance(t *testing.T) {
+	jq := newJobQueue(4*time.Second, realClock{})
+	for _, name := range []string{"c0", "c1", "c2", "c3", "c4", "c5"} {
+		jq.addJob(&namedCheck{name: name})
+	}
+	assert.Equal(t, []int{2, 2, 1, 1}, bucketSizes(jq))
+
+	// still balanced, nothing moves
+	require.Nil(t, jq.removeJob("c1"))
+	assert.Equal(t, []int{2, 1, 1, 1}, bucketSizes(jq))
+
+	// c4 moves from the first bucket to the emptied one
+	require.Nil(t, jq.removeJob("c2"))
+	assert.Equal(t, []int{1, 1, 1, 1}, bucketSizes(jq))
+	assert.Equal(t, "c4", jq.buckets[2][0].String())
+
+	// new checks go to the first smallest bucket
+	jq.addJob(&namedCheck{name: "c6"})
+	assert.Equal(t, "c6", jq.buckets[0][1].String())
+
+	assert.NotNil(t, jq.removeJob("c1"))
+	assert.Len(t, jq.jobs, 5)
+}
+
+func TestSpreadSchedule(t *testing.T) {
+	pipe := make(chan check.Check)
+	fc := &fakeClock{}
+	s := NewScheduler(pipe)
+	s.clock = fc
+	defer s.Stop()
+
+	for _, name := range []string{"c0", "c1", "c2", "c3", "c4", "c5"} {
+		s.Enter(&namedCheck{TestCheck: TestCheck{intl: 4 * time.Second}, name: name})
+	}
+	s.Run()
+
+	// one bucket per second
+	for cycle := 0; cycle < 2; cycle++ {
+		fc.Advance(time.Second)
+		assert.Equal(t, []string{"c0", "c4"}, receive(t, pipe, 2))
+		fc.Advance(time.Second)
+		assert.Equal(t, []string{"c1", "c5"}, receive(t, pipe, 2))
+		fc.Advance(time.Second)
+		assert.Equal(t, []string{"c2"}, receive(t, pipe, 1))
+		fc.Advance(time.Second)
+		assert.Equal(t, []string{"c3"}, receive(t, pipe, 1))
+	}
+
+	// rebalance on cancel
+	s.Cancel("c2")
+	fc.Advance(time.Second)
+	assert.Equal(t, []string{"c0"}, receive(t, pipe, 1))
+	fc.Advance(time.Second)
+	assert.Equal(t, []string{"c1", "c5"}, receive(t, pipe, 2))
+	fc.Advance(time.Second)
+	assert.Equal(t, []string{"c4"}, receive(t, pipe, 1))
+
+	// nothing else was scheduled
+	select {
+	case c := <-pipe:
+		t.Errorf("unexpected check scheduled: %s", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
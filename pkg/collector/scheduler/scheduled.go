@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	log "github.com/cihub/seelog"
+)
+
+// scheduledJob runs a check according to its check.Schedule instead of
+// at a fixed interval
+type scheduledJob struct {
+	check    check.Check
+	schedule check.Schedule
+	stop     chan struct{} // closed to stop the job, a new one is made at every start
+	running  bool
+	lastRun  time.Time
+	nextRun  time.Time
+	m        sync.RWMutex // protects lastRun and nextRun
+}
+
+func newScheduledJob(c check.Check, s check.Schedule) *scheduledJob {
+	return &scheduledJob{
+		check:    c,
+		schedule: s,
+	}
+}
+
+// run posts the check to the execution pipeline at the times given by the
+// schedule. A check scheduled `once` is posted right away, the first time
+// the job runs only.
+// This doesn't block.
+func (j *scheduledJob) run(out chan<- check.Check, observer QueueObserver, c clock) {
+	j.stop = make(chan struct{})
+	stop := j.stop
+
+	if _, once := j.schedule.(check.OnceSchedule); once {
+		if j.hasRun() {
+			return
+		}
+		j.setLastRun(c.Now())
+		go enqueue(out, j.check, observer)
+		return
+	}
+
+	go func() {
+		for {
+			now := c.Now()
+			next := j.schedule.Next(now)
+			j.setNextRun(next)
+			if next.IsZero() {
+				log.Warnf("Check %s won't run anymore, its schedule %q doesn't match any time after %v", j.check, j.schedule, now)
+				return
+			}
+
+			t := c.NewTimer(next.Sub(now))
+			select {
+			case <-stop:
+				t.Stop()
+				return
+			case <-t.C():
+				log.Debugf("Enqueuing check %s for schedule %q", j.check, j.schedule)
+				j.setLastRun(c.Now())
+				enqueue(out, j.check, observer)
+			}
+		}
+	}()
+}
+
+// halt stops the job, it can be run again afterwards
+func (j *scheduledJob) halt() {
+	if j.stop != nil {
+		close(j.stop)
+		j.stop = nil
+	}
+}
+
+func (j *scheduledJob) hasRun() bool {
+	j.m.RLock()
+	defer j.m.RUnlock()
+	return !j.lastRun.IsZero()
+}
+
+func (j *scheduledJob) setLastRun(t time.Time) {
+	j.m.Lock()
+	defer j.m.Unlock()
+	j.lastRun = t
+}
+
+func (j *scheduledJob) setNextRun(t time.Time) {
+	j.m.Lock()
+	defer j.m.Unlock()
+	j.nextRun = t
+}
+
+// stats returns the schedule and the last and next run times as unix
+// timestamps, 0 when there's none
+func (j *scheduledJob) stats() map[string]interface{} {
+	j.m.RLock()
+	defer j.m.RUnlock()
+
+	unix := func(t time.Time) int64 {
+		if t.IsZero() {
+			return 0
+		}
+		return t.Unix()
+	}
+	return map[string]interface{}{
+		"Schedule": j.schedule.String(),
+		"LastRun":  unix(j.lastRun),
+		"NextRun":  unix(j.nextRun),
+	}
+}
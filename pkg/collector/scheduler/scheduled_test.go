@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scheduledCheck struct {
+	namedCheck
+	schedule check.Schedule
+}
+
+func (c *scheduledCheck) Schedule() check.Schedule { return c.schedule }
+
+func newScheduledCheck(t *testing.T, name, expr string) *scheduledCheck {
+	s, err := check.ParseSchedule(expr)
+	require.Nil(t, err)
+	return &scheduledCheck{namedCheck: namedCheck{name: name}, schedule: s}
+}
+
+func TestScheduleOnce(t *testing.T) {
+	pipe := make(chan check.Check)
+	fc := &fakeClock{now: time.Date(2017, 10, 2, 12, 0, 0, 0, time.UTC)}
+	s := NewScheduler(pipe)
+	s.clock = fc
+	defer s.Stop()
+
+	require.Nil(t, s.Enter(newScheduledCheck(t, "validation", "once")))
+	s.Run()
+	assert.Equal(t, []string{"validation"}, receive(t, pipe, 1))
+
+	stats := s.scheduled["validation"].stats()
+	assert.Equal(t, "once", stats["Schedule"])
+	assert.Equal(t, fc.now.Unix(), stats["LastRun"])
+	assert.Equal(t, int64(0), stats["NextRun"])
+
+	// not run again on reload
+	require.Nil(t, s.Reload())
+	fc.Advance(time.Hour)
+	select {
+	case c := <-pipe:
+		t.Errorf("unexpected check scheduled: %s", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestScheduleCron(t *testing.T) {
+	pipe := make(chan check.Check)
+	fc := &fakeClock{now: time.Date(2017, 10, 2, 12, 0, 0, 0, time.UTC)}
+	s := NewScheduler(pipe)
+	s.clock = fc
+	defer s.Stop()
+
+	require.Nil(t, s.Enter(newScheduledCheck(t, "backup", "30 2 * * *")))
+	s.Run()
+	require.True(t, consistently(func() bool { return fc.activeTimers() == 1 }))
+
+	stats := s.scheduled["backup"].stats()
+	assert.Equal(t, "30 2 * * *", stats["Schedule"])
+	assert.Equal(t, int64(0), stats["LastRun"])
+	assert.Equal(t, time.Date(2017, 10, 3, 2, 30, 0, 0, time.UTC).Unix(), stats["NextRun"])
+
+	// not yet
+	fc.Advance(14 * time.Hour)
+	select {
+	case c := <-pipe:
+		t.Errorf("unexpected check scheduled: %s", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fc.Advance(30 * time.Minute)
+	assert.Equal(t, []string{"backup"}, receive(t, pipe, 1))
+	require.True(t, consistently(func() bool { return fc.activeTimers() == 1 }))
+	stats = s.scheduled["backup"].stats()
+	assert.Equal(t, time.Date(2017, 10, 3, 2, 30, 0, 0, time.UTC).Unix(), stats["LastRun"])
+	assert.Equal(t, time.Date(2017, 10, 4, 2, 30, 0, 0, time.UTC).Unix(), stats["NextRun"])
+
+	// cancelling stops the job
+	require.Nil(t, s.Cancel("backup"))
+	assert.Len(t, s.scheduled, 0)
+	require.True(t, consistently(func() bool { return fc.activeTimers() == 0 }))
+}
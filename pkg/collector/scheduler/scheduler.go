@@ -28,6 +28,13 @@ func init() {
 	schedulerStats = expvar.NewMap("scheduler")
 }
 
+// QueueObserver is notified when a check starts waiting to be picked up by
+// the runner, and when it's picked up after having waited `wait`
+type QueueObserver interface {
+	CheckQueued()
+	CheckDequeued(wait time.Duration)
+}
+
 // Scheduler keeps things rolling.
 // More docs to come...
 type Scheduler struct {
@@ -37,8 +44,11 @@ type Scheduler struct {
 	started      chan bool                   // Used to internally communicate the queues are up
 	jobQueues    map[time.Duration]*jobQueue // We have one scheduling queue for every interval
 	checkToQueue map[check.ID]*jobQueue      // Keep track of what is the queue for any Check
+	scheduled    map[check.ID]*scheduledJob  // The checks run according to a schedule instead of an interval
 	mu           sync.Mutex                  // To protect critical sections in struct's fields
 	running      uint32                      // Flag to see if the scheduler is running
+	observer     QueueObserver               // Optional, notified about checks waiting on checksPipe
+	clock        clock                       // The time source of the queues
 }
 
 // NewScheduler create a Scheduler and returns a pointer to it.
@@ -50,20 +60,26 @@ func NewScheduler(checksPipe chan<- check.Check) *Scheduler {
 		started:      make(chan bool, 1),
 		jobQueues:    make(map[time.Duration]*jobQueue),
 		checkToQueue: make(map[check.ID]*jobQueue),
+		scheduled:    make(map[check.ID]*scheduledJob),
 		running:      0,
+		clock:        realClock{},
 	}
 }
 
 // Enter schedules a `Check`s for execution accordingly to the `Check.Interval()` value.
 // If the interval is 0, the check is supposed to run only once.
+// Checks implementing `check.WithSchedule` and having a schedule are run
+// according to it instead.
 func (s *Scheduler) Enter(check check.Check) error {
+	if schedule := getSchedule(check); schedule != nil {
+		return s.enterScheduled(check, schedule)
+	}
+
 	// send immediately to the checks Pipe if this is a one-time schedule
 	// do not block, in case the runner has not started
 	if check.Interval() == 0 {
 		log.Infof("Scheduling check %v for one-time execution", check)
-		go func() {
-			s.checksPipe <- check
-		}()
+		go enqueue(s.checksPipe, check, s.observer)
 		schedulerStats.Add("ChecksEntered", 1)
 		return nil
 	}
@@ -79,7 +95,7 @@ func (s *Scheduler) Enter(check check.Check) error {
 	defer s.mu.Unlock()
 
 	if _, ok := s.jobQueues[check.Interval()]; !ok {
-		s.jobQueues[check.Interval()] = newJobQueue(check.Interval())
+		s.jobQueues[check.Interval()] = newJobQueue(check.Interval(), s.clock)
 		s.startQueue(s.jobQueues[check.Interval()])
 		schedulerStats.Add("QueuesCount", 1)
 	}
@@ -92,12 +108,54 @@ func (s *Scheduler) Enter(check check.Check) error {
 	return nil
 }
 
+// getSchedule returns the schedule of a check, nil if it runs at an interval
+func getSchedule(c check.Check) check.Schedule {
+	if ws, ok := c.(check.WithSchedule); ok {
+		return ws.Schedule()
+	}
+	return nil
+}
+
+// enterScheduled schedules a check according to its schedule
+func (s *Scheduler) enterScheduled(c check.Check, schedule check.Schedule) error {
+	log.Infof("Scheduling check %v with schedule %q", c, schedule)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if j, found := s.scheduled[c.ID()]; found {
+		j.halt()
+	}
+	j := newScheduledJob(c, schedule)
+	s.scheduled[c.ID()] = j
+	s.startJob(j)
+
+	schedulerStats.Add("ChecksEntered", 1)
+	schedulerStats.Set("Schedules", expvar.Func(expSchedules(s)))
+	return nil
+}
+
+// SetQueueObserver sets the observer notified about the checks waiting to
+// be picked up from the checks pipe. Call it before scheduling any check.
+func (s *Scheduler) SetQueueObserver(o QueueObserver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observer = o
+}
+
 // Cancel remove a Check from the scheduled queue. If the check is not
 // in the scheduler, this is a noop.
 func (s *Scheduler) Cancel(id check.ID) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if j, found := s.scheduled[id]; found {
+		j.halt()
+		delete(s.scheduled, id)
+		schedulerStats.Add("ChecksEntered", -1)
+		return nil
+	}
+
 	if _, ok := s.checkToQueue[id]; !ok {
 		return nil
 	}
@@ -198,6 +256,10 @@ func (s *Scheduler) stopQueues() {
 			q.running = false
 		}
 	}
+	for _, j := range s.scheduled {
+		j.halt()
+		j.running = false
+	}
 }
 
 // startQueues loads the timer for each queue
@@ -205,14 +267,43 @@ func (s *Scheduler) startQueues() {
 	for _, q := range s.jobQueues {
 		s.startQueue(q)
 	}
+	for _, j := range s.scheduled {
+		s.startJob(j)
+	}
 }
 
 // simple wrapper to have this in just one place
 func (s *Scheduler) startQueue(q *jobQueue) {
-	q.run(s.checksPipe)
+	if q.running {
+		return
+	}
+	q.run(s.checksPipe, s.observer)
 	q.running = true
 }
 
+// startJob runs a scheduled job, unless it's already running
+func (s *Scheduler) startJob(j *scheduledJob) {
+	if j.running {
+		return
+	}
+	j.run(s.checksPipe, s.observer, s.clock)
+	j.running = true
+}
+
+// enqueue posts a check to the pipe, notifying the observer, if any, about
+// how long it waited there
+func enqueue(out chan<- check.Check, c check.Check, o QueueObserver) {
+	if o == nil {
+		out <- c
+		return
+	}
+
+	o.CheckQueued()
+	t0 := time.Now()
+	out <- c
+	o.CheckDequeued(time.Since(t0))
+}
+
 // expQueues return a function to get the stats for the queues
 func expQueues(s *Scheduler) func() interface{} {
 	return func() interface{} {
@@ -222,9 +313,25 @@ func expQueues(s *Scheduler) func() interface{} {
 			queueStats := map[string]interface{}{
 				"Interval": interval / time.Second,
 				"Size":     len(queue.jobs),
+				"Buckets":  len(queue.buckets),
 			}
 			queues = append(queues, queueStats)
 		}
 		return queues
 	}
 }
+
+// expSchedules return a function to get the schedule and the run times of
+// the scheduled checks
+func expSchedules(s *Scheduler) func() interface{} {
+	return func() interface{} {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		schedules := make(map[string]interface{}, len(s.scheduled))
+		for id, j := range s.scheduled {
+			schedules[string(id)] = j.stats()
+		}
+		return schedules
+	}
+}
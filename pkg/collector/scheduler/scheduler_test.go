@@ -6,6 +6,7 @@
 package scheduler
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -161,3 +162,46 @@ func TestTinyInterval(t *testing.T) {
 	err := s.Enter(&TestCheck{intl: 1 * time.Millisecond})
 	assert.NotNil(t, err)
 }
+
+type testObserver struct {
+	queued int
+	waits  []time.Duration
+	m      sync.Mutex
+}
+
+func (o *testObserver) CheckQueued() {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.queued++
+}
+
+func (o *testObserver) CheckDequeued(wait time.Duration) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.queued--
+	o.waits = append(o.waits, wait)
+}
+
+func TestQueueObserver(t *testing.T) {
+	pipe := make(chan check.Check)
+	s := NewScheduler(pipe)
+	o := &testObserver{}
+	s.SetQueueObserver(o)
+
+	// one-time schedule
+	c := &TestCheck{}
+	s.Enter(c)
+	assert.True(t, consistently(func() bool {
+		o.m.Lock()
+		defer o.m.Unlock()
+		return o.queued == 1
+	}))
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, c, <-pipe)
+	assert.True(t, consistently(func() bool {
+		o.m.Lock()
+		defer o.m.Unlock()
+		return o.queued == 0 && len(o.waits) == 1 && o.waits[0] >= 20*time.Millisecond
+	}))
+}
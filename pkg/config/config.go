@@ -22,7 +22,7 @@ import (
 // Datadog is the global configuration object
 var Datadog = viper.New()
 
-// MetadataProviders helps unmarshalling `metadata_providers` config param
+// MetadataProviders helps unmarshalling `metadata_collectors` config param
 type MetadataProviders struct {
 	Name     string        `mapstructure:"name"`
 	Interval time.Duration `mapstructure:"interval"`
@@ -70,6 +70,13 @@ func init() {
 	Datadog.SetDefault("conf_path", ".")
 	Datadog.SetDefault("confd_path", defaultConfdPath)
 	Datadog.SetDefault("additional_checksd", defaultAdditionalChecksPath)
+	Datadog.SetDefault("plugin_checks_path", "")
+	Datadog.SetDefault("managed_confd_path", "")
+	Datadog.SetDefault("confd_watch", true)
+	Datadog.SetDefault("secret_backend_command", "")
+	Datadog.SetDefault("secret_backend_arguments", []string{})
+	Datadog.SetDefault("secret_backend_timeout", 5)
+	Datadog.SetDefault("secret_backend_output_max_size", 1024*1024)
 	Datadog.SetDefault("log_file", defaultLogPath)
 	Datadog.SetDefault("log_level", "info")
 	Datadog.SetDefault("log_to_syslog", false)
@@ -83,7 +90,13 @@ func init() {
 	Datadog.SetDefault("default_integration_http_timeout", 9)
 	Datadog.SetDefault("enable_metadata_collection", true)
 	Datadog.SetDefault("check_runners", int64(4))
+	Datadog.SetDefault("check_runners_max", int64(16))
+	Datadog.SetDefault("check_timeout", 300)
 	Datadog.SetDefault("expvar_port", "5000")
+	Datadog.SetDefault("metrics_endpoint_enabled", false)
+	Datadog.SetDefault("metrics_endpoint_port", 5002)
+	Datadog.SetDefault("metrics_endpoint_non_local_traffic", false)
+	Datadog.SetDefault("metrics_endpoint_expiry_seconds", 300)
 	if IsContainerized() {
 		Datadog.SetDefault("container_proc_root", "/host/proc")
 		Datadog.SetDefault("container_cgroup_root", "/host/sys/fs/cgroup/")
@@ -97,6 +110,8 @@ func init() {
 	Datadog.SetDefault("use_v2_api.series", false)
 	Datadog.SetDefault("use_v2_api.events", false)
 	Datadog.SetDefault("use_v2_api.service_checks", false)
+	Datadog.SetDefault("serializer_compression_kind", "") // Notice: empty means the build time default
+	// serializer_compression_level has no default: when it's not set the default level of the algorithm is used
 	// Forwarder
 	Datadog.SetDefault("forwarder_timeout", 20)
 	Datadog.SetDefault("forwarder_retry_queue_max_size", 30)
@@ -114,6 +129,7 @@ func init() {
 	// Autoconfig
 	Datadog.SetDefault("autoconf_template_dir", "/datadog/check_configs")
 	Datadog.SetDefault("exclude_pause_container", true)
+	Datadog.SetDefault("process_listener_rules", []map[string]string{})
 	// Docker
 	Datadog.SetDefault("docker_labels_as_tags", map[string]string{})
 	Datadog.SetDefault("docker_env_as_tags", map[string]string{})
@@ -147,6 +163,10 @@ func init() {
 	Datadog.BindEnv("dogstatsd_stats_port")
 	Datadog.BindEnv("dogstatsd_non_local_traffic")
 	Datadog.BindEnv("dogstatsd_origin_detection")
+	Datadog.BindEnv("metrics_endpoint_enabled")
+	Datadog.BindEnv("metrics_endpoint_port")
+	Datadog.BindEnv("metrics_endpoint_non_local_traffic")
+	Datadog.BindEnv("metrics_endpoint_expiry_seconds")
 	Datadog.BindEnv("log_file")
 	Datadog.BindEnv("log_level")
 	Datadog.BindEnv("kubernetes_kubelet_host")
@@ -155,6 +175,8 @@ func init() {
 	Datadog.BindEnv("kubernetes_pod_label_to_tag_prefix")
 	Datadog.BindEnv("forwarder_timeout")
 	Datadog.BindEnv("forwarder_retry_queue_max_size")
+	Datadog.BindEnv("serializer_compression_kind")
+	Datadog.BindEnv("serializer_compression_level")
 	Datadog.BindEnv("cloud_foundry")
 	Datadog.BindEnv("bosh_id")
 }
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package metadata
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+)
+
+// countingForwarder wraps a Forwarder and counts the bytes submitted through
+// it, so the scheduler can report the payload size of each collector run.
+type countingForwarder struct {
+	forwarder.Forwarder
+	size int64
+}
+
+// Size returns the number of bytes submitted so far
+func (f *countingForwarder) Size() int64 {
+	return atomic.LoadInt64(&f.size)
+}
+
+func (f *countingForwarder) count(payload forwarder.Payloads) {
+	for _, p := range payload {
+		if p != nil {
+			atomic.AddInt64(&f.size, int64(len(*p)))
+		}
+	}
+}
+
+// SubmitV1Series counts and forwards the payload
+func (f *countingForwarder) SubmitV1Series(payload forwarder.Payloads, extra http.Header) error {
+	f.count(payload)
+	return f.Forwarder.SubmitV1Series(payload, extra)
+}
+
+// SubmitV1Intake counts and forwards the payload
+func (f *countingForwarder) SubmitV1Intake(payload forwarder.Payloads, extra http.Header) error {
+	f.count(payload)
+	return f.Forwarder.SubmitV1Intake(payload, extra)
+}
+
+// SubmitV1CheckRuns counts and forwards the payload
+func (f *countingForwarder) SubmitV1CheckRuns(payload forwarder.Payloads, extra http.Header) error {
+	f.count(payload)
+	return f.Forwarder.SubmitV1CheckRuns(payload, extra)
+}
+
+// SubmitSeries counts and forwards the payload
+func (f *countingForwarder) SubmitSeries(payload forwarder.Payloads, extra http.Header) error {
+	f.count(payload)
+	return f.Forwarder.SubmitSeries(payload, extra)
+}
+
+// SubmitEvents counts and forwards the payload
+func (f *countingForwarder) SubmitEvents(payload forwarder.Payloads, extra http.Header) error {
+	f.count(payload)
+	return f.Forwarder.SubmitEvents(payload, extra)
+}
+
+// SubmitServiceChecks counts and forwards the payload
+func (f *countingForwarder) SubmitServiceChecks(payload forwarder.Payloads, extra http.Header) error {
+	f.count(payload)
+	return f.Forwarder.SubmitServiceChecks(payload, extra)
+}
+
+// SubmitSketchSeries counts and forwards the payload
+func (f *countingForwarder) SubmitSketchSeries(payload forwarder.Payloads, extra http.Header) error {
+	f.count(payload)
+	return f.Forwarder.SubmitSketchSeries(payload, extra)
+}
+
+// SubmitHostMetadata counts and forwards the payload
+func (f *countingForwarder) SubmitHostMetadata(payload forwarder.Payloads, extra http.Header) error {
+	f.count(payload)
+	return f.Forwarder.SubmitHostMetadata(payload, extra)
+}
+
+// SubmitMetadata counts and forwards the payload
+func (f *countingForwarder) SubmitMetadata(payload forwarder.Payloads, extra http.Header) error {
+	f.count(payload)
+	return f.Forwarder.SubmitMetadata(payload, extra)
+}
@@ -6,7 +6,11 @@
 package metadata
 
 import (
+	"expvar"
 	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common/signals"
@@ -17,19 +21,134 @@ import (
 // Catalog keeps track of metadata collectors by name
 var catalog = make(map[string]Collector)
 
+var metadataExpvar = expvar.NewMap("metadata")
+
+// maxJitterRatio bounds the random delay added before the first run of a
+// collector, as a fraction of its interval, so that agents started at the
+// same time don't hit the intake all at once.
+const maxJitterRatio = 10
+
+// startJitter returns the random delay applied before the first run of a
+// collector. It's a variable so tests can disable it.
+var startJitter = func(interval time.Duration) time.Duration {
+	if interval < maxJitterRatio {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(interval / maxJitterRatio)))
+}
+
+// CollectorStatus holds the runtime information about a scheduled collector
+type CollectorStatus struct {
+	Name            string
+	Interval        string
+	Runs            int64
+	Errors          int64
+	LastRun         int64 // unix timestamp, 0 if never run
+	LastSuccess     int64 // unix timestamp, 0 if never succeeded
+	LastError       string
+	LastErrorTime   int64 // unix timestamp, 0 if never failed
+	LastPayloadSize int64 // bytes submitted to the forwarder during the last run
+	NextRun         int64 // unix timestamp
+}
+
+// scheduledCollector runs a Collector periodically and keeps track of its status
+type scheduledCollector struct {
+	name      string
+	collector Collector
+	interval  time.Duration
+	stop      chan struct{}
+	sendMu    sync.Mutex // serializes scheduled and forced runs
+	m         sync.RWMutex
+	status    CollectorStatus
+}
+
+func newScheduledCollector(name string, c Collector, interval time.Duration) *scheduledCollector {
+	return &scheduledCollector{
+		name:      name,
+		collector: c,
+		interval:  interval,
+		stop:      make(chan struct{}),
+		status: CollectorStatus{
+			Name:     name,
+			Interval: interval.String(),
+		},
+	}
+}
+
+func (sc *scheduledCollector) run(srl *serializer.Serializer, delay time.Duration) {
+	sc.setNextRun(time.Now().Add(delay))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-sc.stop:
+			return
+		case <-timer.C:
+			if err := sc.send(srl); err != nil {
+				log.Errorf("Unable to send '%s' metadata: %v", sc.name, err)
+			}
+			sc.setNextRun(time.Now().Add(sc.interval))
+			timer.Reset(sc.interval)
+		}
+	}
+}
+
+// send runs the collector once, recording the outcome and the payload size
+func (sc *scheduledCollector) send(srl *serializer.Serializer) error {
+	sc.sendMu.Lock()
+	defer sc.sendMu.Unlock()
+
+	// give the collector its own serializer so we can measure what it submits
+	fwd := &countingForwarder{Forwarder: srl.Forwarder}
+	s := *srl
+	s.Forwarder = fwd
+
+	start := time.Now()
+	err := sc.collector.Send(&s)
+
+	sc.m.Lock()
+	defer sc.m.Unlock()
+	sc.status.Runs++
+	sc.status.LastRun = start.Unix()
+	sc.status.LastPayloadSize = fwd.Size()
+	if err != nil {
+		sc.status.Errors++
+		sc.status.LastError = err.Error()
+		sc.status.LastErrorTime = start.Unix()
+	} else {
+		sc.status.LastSuccess = start.Unix()
+	}
+	return err
+}
+
+func (sc *scheduledCollector) setNextRun(t time.Time) {
+	sc.m.Lock()
+	sc.status.NextRun = t.Unix()
+	sc.m.Unlock()
+}
+
+func (sc *scheduledCollector) getStatus() CollectorStatus {
+	sc.m.RLock()
+	defer sc.m.RUnlock()
+	return sc.status
+}
+
 // Scheduler takes care of sending metadata at specific
 // time intervals
 type Scheduler struct {
-	srl      *serializer.Serializer
-	hostname string
-	tickers  []*time.Ticker
+	srl        *serializer.Serializer
+	hostname   string
+	collectors map[string]*scheduledCollector
+	m          sync.RWMutex
 }
 
 // NewScheduler builds and returns a new Metadata Scheduler
 func NewScheduler(s *serializer.Serializer, hostname string) *Scheduler {
 	scheduler := &Scheduler{
-		srl:      s,
-		hostname: hostname,
+		srl:        s,
+		hostname:   hostname,
+		collectors: make(map[string]*scheduledCollector),
 	}
 
 	err := scheduler.firstRun()
@@ -37,34 +156,85 @@ func NewScheduler(s *serializer.Serializer, hostname string) *Scheduler {
 		log.Errorf("Unable to send host metadata at first run: %v", err)
 	}
 
+	metadataExpvar.Set("Collectors", expvar.Func(func() interface{} {
+		return scheduler.GetStatus()
+	}))
+
 	return scheduler
 }
 
 // Stop scheduling collectors
 func (c *Scheduler) Stop() {
-	for _, t := range c.tickers {
-		t.Stop()
+	c.m.Lock()
+	defer c.m.Unlock()
+	for name, sc := range c.collectors {
+		close(sc.stop)
+		delete(c.collectors, name)
 	}
 }
 
-// AddCollector schedules a Metadata Collector at the given interval
+// AddCollector schedules a Metadata Collector at the given interval. The
+// first run happens after one interval plus a random jitter.
 func (c *Scheduler) AddCollector(name string, interval time.Duration) error {
 	p, found := catalog[name]
 	if !found {
 		return fmt.Errorf("Unable to find metadata collector: %s", name)
 	}
+	if interval <= 0 {
+		return fmt.Errorf("Invalid interval for metadata collector %s: %v", name, interval)
+	}
 
-	ticker := time.NewTicker(interval)
-	go func() {
-		for _ = range ticker.C {
-			p.Send(c.srl)
-		}
-	}()
-	c.tickers = append(c.tickers, ticker)
+	c.m.Lock()
+	defer c.m.Unlock()
+	if _, scheduled := c.collectors[name]; scheduled {
+		return fmt.Errorf("Metadata collector %s is already scheduled", name)
+	}
+
+	sc := newScheduledCollector(name, p, interval)
+	c.collectors[name] = sc
+	go sc.run(c.srl, interval+startJitter(interval))
 
 	return nil
 }
 
+// RemoveCollector stops a scheduled Metadata Collector
+func (c *Scheduler) RemoveCollector(name string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	sc, found := c.collectors[name]
+	if !found {
+		return fmt.Errorf("Metadata collector %s is not scheduled", name)
+	}
+	close(sc.stop)
+	delete(c.collectors, name)
+	return nil
+}
+
+// SendNow synchronously runs a scheduled Metadata Collector, regardless
+// of its interval. The regular schedule is left untouched.
+func (c *Scheduler) SendNow(name string) error {
+	c.m.RLock()
+	sc, found := c.collectors[name]
+	c.m.RUnlock()
+	if !found {
+		return fmt.Errorf("Metadata collector %s is not scheduled", name)
+	}
+	return sc.send(c.srl)
+}
+
+// GetStatus returns the status of every scheduled collector, sorted by name
+func (c *Scheduler) GetStatus() []CollectorStatus {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	statuses := make([]CollectorStatus, 0, len(c.collectors))
+	for _, sc := range c.collectors {
+		statuses = append(statuses, sc.getStatus())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
 // Always send host metadata at the first run
 func (c *Scheduler) firstRun() error {
 	p, found := catalog["host"]
@@ -6,14 +6,18 @@
 package metadata
 
 import (
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/py"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	python "github.com/sbinet/go-python"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Setup the test module
@@ -36,3 +40,107 @@ func TestNewScheduler(t *testing.T) {
 	assert.Equal(t, fwd, c.srl.Forwarder)
 	assert.Equal(t, "hostname", c.hostname)
 }
+
+type testCollector struct {
+	err error
+}
+
+func (c *testCollector) Send(s *serializer.Serializer) error {
+	if c.err != nil {
+		return c.err
+	}
+	return s.SendJSONToV1Intake(map[string]string{"foo": "bar"})
+}
+
+func newTestScheduler() (*Scheduler, *forwarder.MockedForwarder) {
+	fwd := &forwarder.MockedForwarder{}
+	fwd.On("SubmitV1Intake", mock.Anything, mock.Anything).Return(nil)
+	return &Scheduler{
+		srl:        serializer.NewSerializer(fwd),
+		collectors: make(map[string]*scheduledCollector),
+	}, fwd
+}
+
+func TestSendNow(t *testing.T) {
+	RegisterCollector("test_ok", &testCollector{})
+	RegisterCollector("test_ko", &testCollector{err: fmt.Errorf("boom")})
+	defer delete(catalog, "test_ok")
+	defer delete(catalog, "test_ko")
+
+	c, fwd := newTestScheduler()
+	defer c.Stop()
+
+	require.NoError(t, c.AddCollector("test_ok", time.Hour))
+	require.NoError(t, c.AddCollector("test_ko", time.Hour))
+	assert.Error(t, c.AddCollector("test_ok", time.Hour))
+	assert.Error(t, c.AddCollector("unknown", time.Hour))
+
+	require.NoError(t, c.SendNow("test_ok"))
+	assert.EqualError(t, c.SendNow("test_ko"), "boom")
+	assert.Error(t, c.SendNow("unknown"))
+	fwd.AssertNumberOfCalls(t, "SubmitV1Intake", 1)
+
+	statuses := c.GetStatus()
+	require.Len(t, statuses, 2)
+
+	ko := statuses[0]
+	assert.Equal(t, "test_ko", ko.Name)
+	assert.Equal(t, int64(1), ko.Runs)
+	assert.Equal(t, int64(1), ko.Errors)
+	assert.Equal(t, "boom", ko.LastError)
+	assert.NotZero(t, ko.LastErrorTime)
+	assert.Zero(t, ko.LastSuccess)
+	assert.Zero(t, ko.LastPayloadSize)
+
+	ok := statuses[1]
+	assert.Equal(t, "test_ok", ok.Name)
+	assert.Equal(t, "1h0m0s", ok.Interval)
+	assert.Equal(t, int64(1), ok.Runs)
+	assert.Zero(t, ok.Errors)
+	assert.NotZero(t, ok.LastSuccess)
+	assert.Equal(t, int64(len(`{"foo":"bar"}`)), ok.LastPayloadSize)
+	assert.True(t, ok.NextRun >= time.Now().Add(time.Hour).Unix()-1)
+}
+
+func TestRemoveCollector(t *testing.T) {
+	RegisterCollector("test_ok", &testCollector{})
+	defer delete(catalog, "test_ok")
+
+	c, _ := newTestScheduler()
+	require.NoError(t, c.AddCollector("test_ok", time.Hour))
+	require.NoError(t, c.RemoveCollector("test_ok"))
+	assert.Error(t, c.RemoveCollector("test_ok"))
+	assert.Empty(t, c.GetStatus())
+
+	// can be scheduled again once removed
+	require.NoError(t, c.AddCollector("test_ok", time.Hour))
+	c.Stop()
+	assert.Empty(t, c.GetStatus())
+}
+
+func TestScheduledRun(t *testing.T) {
+	RegisterCollector("test_ok", &testCollector{})
+	defer delete(catalog, "test_ok")
+
+	c, _ := newTestScheduler()
+	defer c.Stop()
+
+	require.NoError(t, c.AddCollector("test_ok", 10*time.Millisecond))
+	assert.Condition(t, func() bool {
+		for i := 0; i < 100; i++ {
+			if s := c.GetStatus(); len(s) == 1 && s[0].Runs >= 2 {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	})
+}
+
+func TestStartJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		j := startJitter(time.Minute)
+		assert.True(t, j >= 0 && j < 6*time.Second, j.String())
+	}
+	assert.Equal(t, time.Duration(0), startJitter(time.Nanosecond))
+}
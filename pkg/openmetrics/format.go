@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// Format is an exposition format
+type Format int
+
+// Enumeration of the supported exposition formats
+const (
+	FormatPrometheus Format = iota
+	FormatOpenMetrics
+)
+
+const (
+	prometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	openMetricsMediaType   = "application/openmetrics-text"
+)
+
+// ContentType returns the HTTP Content-Type header value of the format
+func (f Format) ContentType() string {
+	if f == FormatOpenMetrics {
+		return openMetricsContentType
+	}
+	return prometheusContentType
+}
+
+// negotiateFormat returns the OpenMetrics format if the client accepts it,
+// the Prometheus text format otherwise
+func negotiateFormat(h http.Header) Format {
+	for _, accept := range strings.Split(h.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == openMetricsMediaType {
+			return FormatOpenMetrics
+		}
+	}
+	return FormatPrometheus
+}
+
+// sanitizeName replaces any character that isn't valid in a Prometheus
+// metric name (or label name if isLabel is true) with an underscore
+func sanitizeName(name string, isLabel bool) string {
+	if name == "" {
+		return "_"
+	}
+
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' ||
+			(c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			(c == ':' && !isLabel) ||
+			(c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	sanitized := string(b)
+	if b[0] >= '0' && b[0] <= '9' {
+		sanitized = "_" + sanitized
+	}
+	// label names starting with "__" are reserved for internal use
+	if isLabel && strings.HasPrefix(sanitized, "__") {
+		sanitized = "tag" + sanitized
+	}
+	return sanitized
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// renderLabels returns the label set of a serie, sorted by label name,
+// in the exposition format: {name="value",...}
+func renderLabels(serie *metrics.Serie) string {
+	values := make(map[string][]string)
+	if serie.Host != "" {
+		values["host"] = append(values["host"], serie.Host)
+	}
+	if serie.Device != "" {
+		values["device"] = append(values["device"], serie.Device)
+	}
+	for _, tag := range serie.Tags {
+		key, value := tag, "true"
+		if idx := strings.Index(tag, ":"); idx >= 0 {
+			key, value = tag[:idx], tag[idx+1:]
+		}
+		key = sanitizeName(key, true)
+		values[key] = append(values[key], value)
+	}
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		v := values[name]
+		sort.Strings(v)
+		labels = append(labels, name+`="`+labelValueReplacer.Replace(strings.Join(v, ","))+`"`)
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"expvar"
+	"fmt"
+	stdLog "log"
+	"net"
+	"net/http"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+var (
+	openmetricsExpvar = expvar.NewMap("openmetrics")
+)
+
+// Server serves the content of a Store on the `/metrics` endpoint
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer returns a running Server exposing the store on the port
+// configured with `metrics_endpoint_port`
+func NewServer(store *Store) (*Server, error) {
+	host := "localhost"
+	if config.Datadog.GetBool("metrics_endpoint_non_local_traffic") {
+		host = ""
+	}
+	addr := fmt.Sprintf("%s:%d", host, config.Datadog.GetInt("metrics_endpoint_port"))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen on %s: %s", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(store))
+
+	s := &Server{
+		listener: listener,
+		server: &http.Server{
+			Handler:  mux,
+			ErrorLog: stdLog.New(&config.ErrorLogWriter{}, "", 0), // log errors to seelog
+		},
+	}
+	go s.server.Serve(listener)
+	log.Infof("Exposing the aggregated metrics on http://%s/metrics", addr)
+
+	return s, nil
+}
+
+// Stop closes the listener
+func (s *Server) Stop() {
+	s.listener.Close()
+}
+
+// Handler returns an http.Handler writing the content of the store in the
+// format requested by the client
+func Handler(store *Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := negotiateFormat(r.Header)
+		w.Header().Set("Content-Type", format.ContentType())
+		if err := store.Write(w, format); err != nil {
+			log.Debugf("Error writing the metrics endpoint response: %s", err)
+			openmetricsExpvar.Add("WriteErrors", 1)
+			return
+		}
+		openmetricsExpvar.Add("Requests", 1)
+	})
+}
@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+type metricType int
+
+const (
+	gaugeType metricType = iota
+	counterType
+)
+
+// sample is the last known value of a context
+type sample struct {
+	name      string
+	labels    string
+	mType     metricType
+	value     float64
+	updatedAt time.Time
+}
+
+// Store keeps the last values of the series flushed by the aggregator so
+// they can be exposed in the Prometheus and OpenMetrics formats
+type Store struct {
+	m       sync.RWMutex
+	samples map[string]*sample
+	expiry  time.Duration
+	timeNow func() time.Time // for testing purposes
+}
+
+// NewStore returns a new Store, the contexts that aren't updated for the
+// expiry duration are dropped
+func NewStore(expiry time.Duration) *Store {
+	return &Store{
+		samples: make(map[string]*sample),
+		expiry:  expiry,
+		timeNow: time.Now,
+	}
+}
+
+// Update records the values of the flushed series. Gauges and rates
+// keep their last value, counts are accumulated.
+// It doesn't keep any reference to the series.
+func (s *Store) Update(series metrics.Series) {
+	now := s.timeNow()
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, serie := range series {
+		if len(serie.Points) == 0 {
+			continue
+		}
+
+		name := sanitizeName(serie.Name, false)
+		mType := gaugeType
+		if serie.MType == metrics.APICountType {
+			mType = counterType
+			if !strings.HasSuffix(name, "_total") {
+				name += "_total"
+			}
+		}
+		labels := renderLabels(serie)
+		key := name + labels
+
+		smp, found := s.samples[key]
+		if !found || smp.mType != mType {
+			smp = &sample{name: name, labels: labels, mType: mType}
+			s.samples[key] = smp
+		}
+		smp.updatedAt = now
+
+		switch mType {
+		case counterType:
+			for _, p := range serie.Points {
+				smp.value += p.Value
+			}
+		default:
+			smp.value = serie.Points[len(serie.Points)-1].Value
+		}
+	}
+
+	s.expire(now)
+}
+
+// expire drops the contexts that weren't updated recently, must be called
+// with the lock held
+func (s *Store) expire(now time.Time) {
+	if s.expiry <= 0 {
+		return
+	}
+	for key, smp := range s.samples {
+		if now.Sub(smp.updatedAt) > s.expiry {
+			delete(s.samples, key)
+		}
+	}
+}
+
+// Write writes every known context to w in the given format
+func (s *Store) Write(w io.Writer, format Format) error {
+	s.m.RLock()
+	samples := make([]sample, 0, len(s.samples))
+	for _, smp := range s.samples {
+		samples = append(samples, *smp)
+	}
+	s.m.RUnlock()
+
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].name != samples[j].name {
+			return samples[i].name < samples[j].name
+		}
+		return samples[i].labels < samples[j].labels
+	})
+
+	buf := bufio.NewWriter(w)
+	lastFamily := ""
+	for _, smp := range samples {
+		if smp.name != lastFamily {
+			writeTypeLine(buf, smp, format)
+			lastFamily = smp.name
+		}
+		buf.WriteString(smp.name)
+		buf.WriteString(smp.labels)
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(smp.value, 'g', -1, 64))
+		buf.WriteByte('\n')
+	}
+	if format == FormatOpenMetrics {
+		buf.WriteString("# EOF\n")
+	}
+	return buf.Flush()
+}
+
+func writeTypeLine(buf *bufio.Writer, smp sample, format Format) {
+	family, typeName := smp.name, "gauge"
+	if smp.mType == counterType {
+		typeName = "counter"
+		// OpenMetrics counter families don't carry the _total suffix of their samples
+		if format == FormatOpenMetrics {
+			family = family[:len(family)-len("_total")]
+		}
+	}
+	buf.WriteString("# TYPE ")
+	buf.WriteString(family)
+	buf.WriteByte(' ')
+	buf.WriteString(typeName)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func testSeries() metrics.Series {
+	return metrics.Series{
+		{
+			Name:   "my.gauge",
+			Points: []metrics.Point{{Ts: 10, Value: 1}, {Ts: 20, Value: 2}},
+			Tags:   []string{"env:prod", "role:db", "role:cache", "canary"},
+			Host:   "myhost",
+			MType:  metrics.APIGaugeType,
+		},
+		{
+			Name:   "my.rate",
+			Points: []metrics.Point{{Ts: 20, Value: 0.5}},
+			Host:   "myhost",
+			Device: "/dev/sda1",
+			MType:  metrics.APIRateType,
+		},
+		{
+			Name:   "my.count",
+			Points: []metrics.Point{{Ts: 10, Value: 3}, {Ts: 20, Value: 4}},
+			Tags:   []string{"path:\"quoted\""},
+			MType:  metrics.APICountType,
+		},
+		{
+			Name:   "my.requests.total",
+			Points: []metrics.Point{{Ts: 10, Value: 1}},
+			MType:  metrics.APICountType,
+		},
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	assert.Equal(t, "system_cpu_user", sanitizeName("system.cpu.user", false))
+	assert.Equal(t, "ns:metric_name", sanitizeName("ns:metric-name", false))
+	assert.Equal(t, "_2xx_count", sanitizeName("2xx.count", false))
+	assert.Equal(t, "kube_pod_name", sanitizeName("kube-pod:name", true))
+	assert.Equal(t, "tag__reserved", sanitizeName("__reserved", true))
+	assert.Equal(t, "_", sanitizeName("", true))
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	h := make(http.Header)
+	assert.Equal(t, FormatPrometheus, negotiateFormat(h))
+
+	h.Set("Accept", "text/plain;version=0.0.4;q=0.5,*/*;q=0.1")
+	assert.Equal(t, FormatPrometheus, negotiateFormat(h))
+
+	h.Set("Accept", "application/openmetrics-text; version=1.0.0,text/plain;version=0.0.4;q=0.5")
+	assert.Equal(t, FormatOpenMetrics, negotiateFormat(h))
+}
+
+func TestWritePrometheus(t *testing.T) {
+	store := NewStore(time.Minute)
+	store.Update(testSeries())
+	store.Update(testSeries())
+
+	var b bytes.Buffer
+	require.NoError(t, store.Write(&b, FormatPrometheus))
+
+	expected := `# TYPE my_count_total counter
+my_count_total{path="\"quoted\""} 14
+# TYPE my_gauge gauge
+my_gauge{canary="true",env="prod",host="myhost",role="cache,db"} 2
+# TYPE my_rate gauge
+my_rate{device="/dev/sda1",host="myhost"} 0.5
+# TYPE my_requests_total counter
+my_requests_total 2
+`
+	assert.Equal(t, expected, b.String())
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	store := NewStore(time.Minute)
+	store.Update(testSeries())
+
+	var b bytes.Buffer
+	require.NoError(t, store.Write(&b, FormatOpenMetrics))
+
+	expected := `# TYPE my_count counter
+my_count_total{path="\"quoted\""} 7
+# TYPE my_gauge gauge
+my_gauge{canary="true",env="prod",host="myhost",role="cache,db"} 2
+# TYPE my_rate gauge
+my_rate{device="/dev/sda1",host="myhost"} 0.5
+# TYPE my_requests counter
+my_requests_total 1
+# EOF
+`
+	assert.Equal(t, expected, b.String())
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Now()
+	store := NewStore(time.Minute)
+	store.timeNow = func() time.Time { return now }
+	store.Update(testSeries())
+	assert.Len(t, store.samples, 4)
+
+	now = now.Add(30 * time.Second)
+	store.Update(testSeries()[:1])
+	assert.Len(t, store.samples, 4)
+
+	now = now.Add(31 * time.Second)
+	store.Update(nil)
+	require.Len(t, store.samples, 1)
+	assert.Contains(t, store.samples, `my_gauge{canary="true",env="prod",host="myhost",role="cache,db"}`)
+}
+
+func TestHandler(t *testing.T) {
+	store := NewStore(time.Minute)
+	store.Update(testSeries())
+	handler := Handler(store)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, prometheusContentType, rec.Header().Get("Content-Type"))
+	assert.NotContains(t, rec.Body.String(), "# EOF")
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", openMetricsContentType)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, openMetricsContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "# EOF")
+}
@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+// +build !windows
+
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkRights makes sure the secret backend command is a regular executable
+// file owned by the user running the agent, that no other user can read,
+// write or execute
+func checkRights(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s can be accessed by other users than its owner, its mode is %#o", path, info.Mode().Perm())
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		return fmt.Errorf("%s isn't executable by its owner", path)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("can't get the owner of %s", path)
+	}
+	if int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("%s is owned by uid %d instead of the user running the agent (uid %d)", path, stat.Uid, os.Geteuid())
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// payloadVersion is the version of the protocol spoken with the secret
+// backend command
+const payloadVersion = "1.0"
+
+// secretRequest is written to the standard input of the command
+type secretRequest struct {
+	Version string   `json:"version"`
+	Secrets []string `json:"secrets"`
+}
+
+// secretResult is the result of a handle in the output of the command
+type secretResult struct {
+	Value    string `json:"value"`
+	ErrorMsg string `json:"error"`
+}
+
+// limitBuffer is a bytes.Buffer refusing to grow past max bytes
+type limitBuffer struct {
+	max int
+	buf *bytes.Buffer
+}
+
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	if len(p)+b.buf.Len() > b.max {
+		return 0, fmt.Errorf("the output is larger than %d bytes", b.max)
+	}
+	return b.buf.Write(p)
+}
+
+// fetchSecrets runs the secret backend command to get the secrets of the
+// handles. The errors never contain the output of the command since it may
+// hold secrets.
+func fetchSecrets(handles []string) (map[string]string, error) {
+	command := config.Datadog.GetString("secret_backend_command")
+	if err := checkRights(command); err != nil {
+		return nil, fmt.Errorf("invalid secret_backend_command: %s", err)
+	}
+
+	payload, err := json.Marshal(secretRequest{Version: payloadVersion, Secrets: handles})
+	if err != nil {
+		return nil, fmt.Errorf("can't serialize the secret handles: %s", err)
+	}
+
+	timeout := time.Duration(config.Datadog.GetInt("secret_backend_timeout")) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command, config.Datadog.GetStringSlice("secret_backend_arguments")...)
+	cmd.Stdin = bytes.NewReader(payload)
+	stdout := &limitBuffer{max: config.Datadog.GetInt("secret_backend_output_max_size"), buf: &bytes.Buffer{}}
+	cmd.Stdout = stdout
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("the secret backend command timed out after %s", timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the secret backend command failed: %s", err)
+	}
+
+	results := map[string]secretResult{}
+	if err := json.Unmarshal(stdout.buf.Bytes(), &results); err != nil {
+		return nil, fmt.Errorf("the output of the secret backend command isn't a valid JSON object")
+	}
+
+	secrets := make(map[string]string)
+	for _, handle := range handles {
+		result, found := results[handle]
+		if !found {
+			return nil, fmt.Errorf("secret %s is missing from the output of the secret backend command", handle)
+		}
+		if result.ErrorMsg != "" {
+			return nil, fmt.Errorf("the secret backend command can't fetch secret %s: %s", handle, result.ErrorMsg)
+		}
+		if result.Value == "" {
+			return nil, fmt.Errorf("the secret backend command returned an empty value for secret %s", handle)
+		}
+		secrets[handle] = result.Value
+	}
+	return secrets, nil
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+// +build !windows
+
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// writeCommand writes a shell script used as secret backend command
+func writeCommand(t *testing.T, dir, script string, mode os.FileMode) string {
+	path := filepath.Join(dir, "secrets.sh")
+	require.Nil(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), mode))
+	// the umask may have removed some bits
+	require.Nil(t, os.Chmod(path, mode))
+	config.Datadog.Set("secret_backend_command", path)
+	return path
+}
+
+func TestFetchSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	config.Datadog.Set("secret_backend_arguments", []string{})
+	config.Datadog.Set("secret_backend_timeout", 5)
+	config.Datadog.Set("secret_backend_output_max_size", 1024)
+
+	// the request is checked by the script
+	writeCommand(t, dir, `read req
+[ "$req" = '{"version":"1.0","secrets":["pass","token"]}' ] || exit 3
+echo '{"pass": {"value": "p4ss", "error": null}, "token": {"value": "t0k3n"}}'
+`, 0700)
+	secrets, err := fetchSecrets([]string{"pass", "token"})
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"pass": "p4ss", "token": "t0k3n"}, secrets)
+
+	for script, expected := range map[string]string{
+		`echo '{"pass": {"value": "p4ss"}}'`:          "secret token is missing from the output of the secret backend command",
+		`echo '{"pass": {"error": "access denied"}}'`: "the secret backend command can't fetch secret pass: access denied",
+		`echo '{"pass": {"value": ""}, "token": {}}'`: "the secret backend command returned an empty value for secret pass",
+		`echo 'p4ss'`:            "the output of the secret backend command isn't a valid JSON object",
+		`exit 1`:                 "the secret backend command failed: exit status 1",
+		`exec sleep 5`:           "the secret backend command timed out after 1s",
+		`head -c 2048 /dev/zero`: "the secret backend command failed: the output is larger than 1024 bytes",
+	} {
+		config.Datadog.Set("secret_backend_timeout", 1)
+		writeCommand(t, dir, script, 0700)
+		_, err = fetchSecrets([]string{"pass", "token"})
+		assert.EqualError(t, err, expected, script)
+	}
+}
+
+func TestCheckRights(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeCommand(t, dir, "", 0700)
+	assert.Nil(t, checkRights(path))
+
+	path = writeCommand(t, dir, "", 0750)
+	assert.NotNil(t, checkRights(path))
+	path = writeCommand(t, dir, "", 0704)
+	assert.NotNil(t, checkRights(path))
+	path = writeCommand(t, dir, "", 0600)
+	assert.NotNil(t, checkRights(path))
+
+	assert.NotNil(t, checkRights(dir))
+	assert.NotNil(t, checkRights(filepath.Join(dir, "missing")))
+
+	// the command isn't run when its rights are wrong
+	writeCommand(t, dir, `echo '{}'`, 0777)
+	_, err = fetchSecrets([]string{"pass"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package secrets
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+const scrubbedSecret = "********"
+
+var (
+	encPattern = regexp.MustCompile(`^ENC\[(.+)\]$`)
+
+	// handle --> secret, the secrets are fetched once and kept for the
+	// lifetime of the agent
+	secretCache = make(map[string]string)
+	cacheLock   sync.Mutex
+
+	// runs the secret backend command, replaced in tests
+	secretFetcher = fetchSecrets
+)
+
+// Decrypt replaces the `ENC[<handle>]` values of a YAML document with the
+// secrets returned by the `secret_backend_command` executable. The document is
+// returned untouched when it has no secret or isn't valid YAML.
+//
+// The decrypted document must only be handed to the code that needs the
+// secrets, e.g. a check loader, and never be logged or stored: the configs
+// keep their `ENC[<handle>]` values everywhere else.
+func Decrypt(data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// leave it to the consumer of the document to report the error
+		return data, nil
+	}
+
+	handles := []string{}
+	walk(doc, func(s string) string {
+		if handle, ok := parseHandle(s); ok {
+			handles = append(handles, handle)
+		}
+		return s
+	})
+	if len(handles) == 0 {
+		return data, nil
+	}
+
+	secrets, err := getSecrets(handles)
+	if err != nil {
+		return nil, err
+	}
+
+	doc = walk(doc, func(s string) string {
+		if handle, ok := parseHandle(s); ok {
+			return secrets[handle]
+		}
+		return s
+	})
+	return yaml.Marshal(doc)
+}
+
+// Scrub replaces the known secrets found in a string, it's used on the error
+// messages of the code that was handed decrypted documents
+func Scrub(s string) string {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	// replace the longest secrets first in case one contains another
+	values := make([]string, 0, len(secretCache))
+	for _, v := range secretCache {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	for _, v := range values {
+		s = strings.Replace(s, v, scrubbedSecret, -1)
+	}
+	return s
+}
+
+// getSecrets returns the secrets of the handles, the ones that aren't cached
+// are fetched in a single run of the secret backend command
+func getSecrets(handles []string) (map[string]string, error) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	secrets := make(map[string]string)
+	missing := []string{}
+	for _, handle := range handles {
+		if secret, found := secretCache[handle]; found {
+			secrets[handle] = secret
+		} else if _, queued := secrets[handle]; !queued {
+			// mark the handle so it's only queued once
+			secrets[handle] = ""
+			missing = append(missing, handle)
+		}
+	}
+	if len(missing) == 0 {
+		return secrets, nil
+	}
+
+	if config.Datadog.GetString("secret_backend_command") == "" {
+		return nil, fmt.Errorf("secret_backend_command is not set, can't decrypt the secrets %s", strings.Join(missing, ", "))
+	}
+	fetched, err := secretFetcher(missing)
+	if err != nil {
+		return nil, err
+	}
+	for handle, secret := range fetched {
+		secretCache[handle] = secret
+		secrets[handle] = secret
+	}
+	return secrets, nil
+}
+
+// parseHandle returns the handle of an `ENC[<handle>]` value
+func parseHandle(s string) (string, bool) {
+	m := encPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// walk calls f on every string value of a YAML document and returns the
+// document with the values replaced by the results, the keys are left as-is
+func walk(node interface{}, f func(string) string) interface{} {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range n {
+			n[k] = walk(v, f)
+		}
+	case []interface{}:
+		for i, v := range n {
+			n[i] = walk(v, f)
+		}
+	case string:
+		return f(n)
+	}
+	return node
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package secrets
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// setupFetcher replaces the secret backend command with a map of secrets and
+// returns the handles it was asked for
+func setupFetcher(t *testing.T, secrets map[string]string) *[][]string {
+	calls := [][]string{}
+	secretCache = make(map[string]string)
+	config.Datadog.Set("secret_backend_command", "/bin/secrets")
+	secretFetcher = func(handles []string) (map[string]string, error) {
+		calls = append(calls, handles)
+		res := make(map[string]string)
+		for _, h := range handles {
+			s, found := secrets[h]
+			if !found {
+				return nil, errors.New("unknown handle " + h)
+			}
+			res[h] = s
+		}
+		return res, nil
+	}
+	return &calls
+}
+
+func TestDecrypt(t *testing.T) {
+	calls := setupFetcher(t, map[string]string{"db_pass": "p4ssw0rd", "api": "deadbeef"})
+
+	data := []byte("host: localhost\npassword: ENC[db_pass]\nnested:\n  - token: ENC[api]\n  - ENC[db_pass]\n")
+	decrypted, err := Decrypt(data)
+	require.Nil(t, err)
+	assert.Equal(t, "host: localhost\nnested:\n- token: deadbeef\n- p4ssw0rd\npassword: p4ssw0rd\n", string(decrypted))
+	// every handle is fetched once
+	require.Len(t, *calls, 1)
+	handles := (*calls)[0]
+	sort.Strings(handles)
+	assert.Equal(t, []string{"api", "db_pass"}, handles)
+
+	// the secrets are cached
+	_, err = Decrypt([]byte("password: ENC[db_pass]"))
+	require.Nil(t, err)
+	assert.Len(t, *calls, 1)
+
+	// documents without secret are untouched
+	data = []byte("host:   localhost   # comment\n")
+	decrypted, err = Decrypt(data)
+	require.Nil(t, err)
+	assert.Equal(t, data, decrypted)
+
+	data = []byte("password: ENC[db_pass]\n  - invalid")
+	decrypted, err = Decrypt(data)
+	require.Nil(t, err)
+	assert.Equal(t, data, decrypted)
+
+	_, err = Decrypt([]byte("password: ENC[unknown]"))
+	assert.EqualError(t, err, "unknown handle unknown")
+}
+
+func TestDecryptWithoutCommand(t *testing.T) {
+	setupFetcher(t, map[string]string{})
+	config.Datadog.Set("secret_backend_command", "")
+
+	_, err := Decrypt([]byte("password: ENC[db_pass]"))
+	assert.EqualError(t, err, "secret_backend_command is not set, can't decrypt the secrets db_pass")
+}
+
+func TestScrub(t *testing.T) {
+	setupFetcher(t, map[string]string{"short": "pass", "long": "password"})
+	_, err := Decrypt([]byte("a: ENC[short]\nb: ENC[long]"))
+	require.Nil(t, err)
+
+	assert.Equal(t, "can't connect with ******** or ********", Scrub("can't connect with password or pass"))
+}
@@ -7,6 +7,7 @@ package serializer
 
 import (
 	"encoding/json"
+	"expvar"
 	"fmt"
 	"net/http"
 
@@ -25,19 +26,42 @@ const (
 	payloadVersionHTTPHeader = "DD-Agent-Payload"
 )
 
+const (
+	eventsPayloadType        = "events"
+	serviceChecksPayloadType = "service_checks"
+	seriesPayloadType        = "series"
+	sketchesPayloadType      = "sketches"
+)
+
 var (
 	// AgentPayloadVersion is the versions of the agent-payload repository
 	// used to serialize to protobuf
 	AgentPayloadVersion string
 
-	jsonExtraHeaders                    http.Header
-	protobufExtraHeaders                http.Header
-	jsonExtraHeadersWithCompression     http.Header
-	protobufExtraHeadersWithCompression http.Header
+	jsonExtraHeaders     http.Header
+	protobufExtraHeaders http.Header
+
+	serializerExpvar = expvar.NewMap("serializer")
+	compressionStats = map[string]*expvar.Map{}
+
+	// payloadTypeDefaultKinds lists the payload types that don't use the
+	// build time default compression when no kind is configured, neither
+	// globally nor for the payload type
+	payloadTypeDefaultKinds = map[string]string{
+		// TODO: use the default compression once the backend supports it on this endpoint
+		sketchesPayloadType: compression.NoneKind,
+	}
 )
 
 func init() {
 	initExtraHeaders()
+
+	for _, payloadType := range []string{eventsPayloadType, serviceChecksPayloadType, seriesPayloadType, sketchesPayloadType} {
+		stats := &expvar.Map{}
+		stats.Init()
+		serializerExpvar.Set(payloadType, stats)
+		compressionStats[payloadType] = stats
+	}
 }
 
 // initExtraHeaders initializes the global extraHeaders variables.
@@ -46,57 +70,130 @@ func initExtraHeaders() {
 	jsonExtraHeaders = make(http.Header)
 	jsonExtraHeaders.Set("Content-Type", jsonContentType)
 
-	jsonExtraHeadersWithCompression = make(http.Header)
-	for k := range jsonExtraHeaders {
-		jsonExtraHeadersWithCompression.Set(k, jsonExtraHeaders.Get(k))
-	}
-
 	protobufExtraHeaders = make(http.Header)
 	protobufExtraHeaders.Set("Content-Type", protobufContentType)
 	protobufExtraHeaders.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
+}
+
+// extraHeadersWithEncoding returns a copy of the given headers with the
+// "Content-Encoding" header set, or the headers themselves if no encoding is used
+func extraHeadersWithEncoding(headers http.Header, contentEncoding string) http.Header {
+	if contentEncoding == "" {
+		return headers
+	}
+	withEncoding := make(http.Header)
+	for k := range headers {
+		withEncoding.Set(k, headers.Get(k))
+	}
+	withEncoding.Set("Content-Encoding", contentEncoding)
+	return withEncoding
+}
 
-	protobufExtraHeadersWithCompression = make(http.Header)
-	for k := range protobufExtraHeaders {
-		protobufExtraHeadersWithCompression.Set(k, protobufExtraHeaders.Get(k))
+// newCompressorFromConfig returns the compressor configured for the given
+// payload type. The `serializer_compression_kind` and `serializer_compression_level`
+// settings apply to every payload type and can be overridden per payload type
+// in `serializer_compression_per_payload`. The payload types of
+// `payloadTypeDefaultKinds` only differ when no kind is set. A level that isn't
+// set selects the default level of the algorithm, 0 is a valid level.
+func newCompressorFromConfig(payloadType string) (compression.Compressor, error) {
+	kind := config.Datadog.GetString("serializer_compression_kind")
+	level := compression.DefaultLevel
+	if config.Datadog.IsSet("serializer_compression_level") {
+		level = config.Datadog.GetInt("serializer_compression_level")
 	}
 
-	if compression.ContentEncoding != "" {
-		jsonExtraHeadersWithCompression.Set("Content-Encoding", compression.ContentEncoding)
-		protobufExtraHeadersWithCompression.Set("Content-Encoding", compression.ContentEncoding)
+	if defaultKind, found := payloadTypeDefaultKinds[payloadType]; found && kind == "" {
+		kind = defaultKind
 	}
+
+	key := "serializer_compression_per_payload." + payloadType
+	if config.Datadog.IsSet(key + ".kind") {
+		kind = config.Datadog.GetString(key + ".kind")
+	}
+	if config.Datadog.IsSet(key + ".level") {
+		level = config.Datadog.GetInt(key + ".level")
+	}
+
+	return compression.NewCompressor(kind, level)
 }
 
 // Serializer serializes metrics to the correct format and routes the payloads to the correct endpoint in the Forwarder
 type Serializer struct {
 	Forwarder forwarder.Forwarder
+
+	// compressors holds the compressor to use per payload type, the zero
+	// value Serializer uses the build time default compression
+	compressors map[string]compression.Compressor
 }
 
-func (s Serializer) serializePayload(payload marshaler.Marshaler, compress bool, useV1API bool) (forwarder.Payloads, http.Header, error) {
+// NewSerializer returns a Serializer using the compression configured in
+// the main agent config for each payload type. Invalid settings are logged
+// and the build time default compression is used instead.
+func NewSerializer(f forwarder.Forwarder) *Serializer {
+	s := &Serializer{
+		Forwarder:   f,
+		compressors: make(map[string]compression.Compressor),
+	}
+
+	for payloadType := range compressionStats {
+		c, err := newCompressorFromConfig(payloadType)
+		if err != nil {
+			log.Errorf("Invalid compression settings for %s payloads, using the default compression: %s", payloadType, err)
+			continue
+		}
+		s.compressors[payloadType] = c
+	}
+
+	return s
+}
+
+func (s *Serializer) getCompressor(payloadType string) compression.Compressor {
+	if c, found := s.compressors[payloadType]; found {
+		return c
+	}
+	kind := compression.DefaultKind
+	if defaultKind, found := payloadTypeDefaultKinds[payloadType]; found {
+		kind = defaultKind
+	}
+	c, err := compression.NewCompressor(kind, compression.DefaultLevel)
+	if err != nil {
+		// can't happen, default kinds are always built in
+		return compression.None
+	}
+	return c
+}
+
+func (s *Serializer) serializePayload(payload marshaler.Marshaler, payloadType string, useV1API bool) (forwarder.Payloads, http.Header, error) {
 	var marshalType split.MarshalType
 	var extraHeaders http.Header
 
 	if useV1API {
 		marshalType = split.MarshalJSON
-		if compress {
-			extraHeaders = jsonExtraHeadersWithCompression
-		} else {
-			extraHeaders = jsonExtraHeaders
-		}
+		extraHeaders = jsonExtraHeaders
 	} else {
 		marshalType = split.Marshal
-		if compress {
-			extraHeaders = protobufExtraHeadersWithCompression
-		} else {
-			extraHeaders = protobufExtraHeaders
-		}
+		extraHeaders = protobufExtraHeaders
 	}
 
-	payloads, err := split.Payloads(payload, compress, marshalType)
+	compressor := s.getCompressor(payloadType)
+	extraHeaders = extraHeadersWithEncoding(extraHeaders, compressor.ContentEncoding())
+
+	payloads, rawSize, err := split.Payloads(payload, compressor, marshalType)
 
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not split payload into small enough chunks: %s", err)
 	}
 
+	if stats, found := compressionStats[payloadType]; found {
+		compressedSize := 0
+		for _, p := range payloads {
+			compressedSize += len(*p)
+		}
+		stats.Add("Payloads", int64(len(payloads)))
+		stats.Add("BytesBeforeCompression", int64(rawSize))
+		stats.Add("BytesAfterCompression", int64(compressedSize))
+	}
+
 	return payloads, extraHeaders, nil
 }
 
@@ -104,8 +201,7 @@ func (s Serializer) serializePayload(payload marshaler.Marshaler, compress bool,
 func (s *Serializer) SendEvents(e marshaler.Marshaler) error {
 	useV1API := !config.Datadog.GetBool("use_v2_api.events")
 
-	compress := true
-	eventPayloads, extraHeaders, err := s.serializePayload(e, compress, useV1API)
+	eventPayloads, extraHeaders, err := s.serializePayload(e, eventsPayloadType, useV1API)
 	if err != nil {
 		return fmt.Errorf("dropping event payload: %s", err)
 	}
@@ -120,8 +216,7 @@ func (s *Serializer) SendEvents(e marshaler.Marshaler) error {
 func (s *Serializer) SendServiceChecks(sc marshaler.Marshaler) error {
 	useV1API := !config.Datadog.GetBool("use_v2_api.service_checks")
 
-	compress := true
-	serviceCheckPayloads, extraHeaders, err := s.serializePayload(sc, compress, useV1API)
+	serviceCheckPayloads, extraHeaders, err := s.serializePayload(sc, serviceChecksPayloadType, useV1API)
 	if err != nil {
 		return fmt.Errorf("dropping service check payload: %s", err)
 	}
@@ -136,8 +231,7 @@ func (s *Serializer) SendServiceChecks(sc marshaler.Marshaler) error {
 func (s *Serializer) SendSeries(series marshaler.Marshaler) error {
 	useV1API := !config.Datadog.GetBool("use_v2_api.series")
 
-	compress := true
-	seriesPayloads, extraHeaders, err := s.serializePayload(series, compress, useV1API)
+	seriesPayloads, extraHeaders, err := s.serializePayload(series, seriesPayloadType, useV1API)
 	if err != nil {
 		return fmt.Errorf("dropping series payload: %s", err)
 	}
@@ -150,9 +244,8 @@ func (s *Serializer) SendSeries(series marshaler.Marshaler) error {
 
 // SendSketch serializes a list of SketSeriesList and sends the payload to the forwarder
 func (s *Serializer) SendSketch(sketches marshaler.Marshaler) error {
-	compress := false // TODO: enable compression once the backend supports it on this endpoint
 	useV1API := false // Sketches only have a v2 endpoint
-	splitSketches, extraHeaders, err := s.serializePayload(sketches, compress, useV1API)
+	splitSketches, extraHeaders, err := s.serializePayload(sketches, sketchesPayloadType, useV1API)
 	if err != nil {
 		return fmt.Errorf("dropping sketch payload: %s", err)
 	}
@@ -162,7 +255,7 @@ func (s *Serializer) SendSketch(sketches marshaler.Marshaler) error {
 
 // SendMetadata serializes a metadata payload and sends it to the forwarder
 func (s *Serializer) SendMetadata(m marshaler.Marshaler) error {
-	smallEnough, payload, err := split.CheckSizeAndSerialize(m, false, split.MarshalJSON)
+	smallEnough, payload, err := split.CheckSizeAndSerialize(m, compression.None, split.MarshalJSON)
 	if err != nil {
 		return fmt.Errorf("could not determine size of metadata payload: %s", err)
 	} else if !smallEnough {
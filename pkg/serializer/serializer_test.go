@@ -6,6 +6,7 @@
 package serializer
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"testing"
@@ -19,17 +20,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
-var initialContentEncoding = compression.ContentEncoding
-
-func resetContentEncoding() {
-	compression.ContentEncoding = initialContentEncoding
-	initExtraHeaders()
-}
-
-func TestInitExtraHeadersNoopCompression(t *testing.T) {
-	compression.ContentEncoding = ""
-	defer resetContentEncoding()
-
+func TestInitExtraHeaders(t *testing.T) {
 	initExtraHeaders()
 
 	expected := make(http.Header)
@@ -40,46 +31,76 @@ func TestInitExtraHeadersNoopCompression(t *testing.T) {
 	expected.Set(payloadVersionHTTPHeader, "")
 	expected.Set("Content-Type", protobufContentType)
 	assert.Equal(t, expected, protobufExtraHeaders)
-
-	// No "Content-Encoding" header
-	expected = make(http.Header)
-	expected.Set("Content-Type", jsonContentType)
-	assert.Equal(t, expected, jsonExtraHeadersWithCompression)
-
-	expected = make(http.Header)
-	expected.Set("Content-Type", protobufContentType)
-	expected.Set(payloadVersionHTTPHeader, "")
-	assert.Equal(t, expected, protobufExtraHeadersWithCompression)
 }
 
-func TestInitExtraHeadersWithCompression(t *testing.T) {
-	compression.ContentEncoding = "zstd"
-	defer resetContentEncoding()
-
-	initExtraHeaders()
+func TestExtraHeadersWithEncoding(t *testing.T) {
+	// No "Content-Encoding" header
+	assert.Equal(t, jsonExtraHeaders, extraHeadersWithEncoding(jsonExtraHeaders, ""))
 
+	// "Content-Encoding" header present with correct value
 	expected := make(http.Header)
-	expected.Set("Content-Type", jsonContentType)
-	assert.Equal(t, expected, jsonExtraHeaders)
-
-	expected = make(http.Header)
 	expected.Set("Content-Type", protobufContentType)
+	expected.Set("Content-Encoding", "zstd")
 	expected.Set(payloadVersionHTTPHeader, "")
-	assert.Equal(t, expected, protobufExtraHeaders)
+	assert.Equal(t, expected, extraHeadersWithEncoding(protobufExtraHeaders, "zstd"))
 
-	// "Content-Encoding" header present with correct value
-	expected = make(http.Header)
-	expected.Set("Content-Type", jsonContentType)
-	expected.Set("Content-Encoding", compression.ContentEncoding)
-	assert.Equal(t, expected, jsonExtraHeadersWithCompression)
+	// the original headers are left untouched
+	assert.Empty(t, protobufExtraHeaders.Get("Content-Encoding"))
+}
 
-	expected = make(http.Header)
-	expected.Set("Content-Type", protobufContentType)
-	expected.Set("Content-Encoding", compression.ContentEncoding)
-	expected.Set(payloadVersionHTTPHeader, "")
-	assert.Equal(t, expected, protobufExtraHeadersWithCompression)
+func TestNewCompressorFromConfig(t *testing.T) {
+	defer config.Datadog.Set("serializer_compression_kind", "")
+	defer config.Datadog.Set("serializer_compression_level", nil)
+	defer config.Datadog.Set("serializer_compression_per_payload", nil)
+
+	c, err := newCompressorFromConfig(seriesPayloadType)
+	require.NoError(t, err)
+	assert.Equal(t, defaultCompressor.ContentEncoding(), c.ContentEncoding())
+
+	// sketches aren't compressed by default
+	c, err = newCompressorFromConfig(sketchesPayloadType)
+	require.NoError(t, err)
+	assert.Equal(t, "", c.ContentEncoding())
+
+	config.Datadog.Set("serializer_compression_kind", compression.GzipKind)
+	c, err = newCompressorFromConfig(eventsPayloadType)
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", c.ContentEncoding())
+
+	// the global kind applies to the sketches as well
+	c, err = newCompressorFromConfig(sketchesPayloadType)
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", c.ContentEncoding())
+
+	// an explicit level of 0 is kept: no compression
+	payload := bytes.Repeat([]byte("test.metric"), 100)
+	config.Datadog.Set("serializer_compression_level", 0)
+	c, err = newCompressorFromConfig(eventsPayloadType)
+	require.NoError(t, err)
+	compressed, err := c.Compress(nil, payload)
+	require.NoError(t, err)
+	assert.True(t, len(compressed) > len(payload))
+	config.Datadog.Set("serializer_compression_level", nil)
+
+	config.Datadog.Set("serializer_compression_per_payload", map[string]interface{}{
+		"series": map[string]interface{}{"kind": "zlib", "level": 9},
+	})
+	c, err = newCompressorFromConfig(seriesPayloadType)
+	require.NoError(t, err)
+	assert.Equal(t, "deflate", c.ContentEncoding())
+
+	config.Datadog.Set("serializer_compression_kind", "unknown")
+	_, err = newCompressorFromConfig(eventsPayloadType)
+	assert.Error(t, err)
+
+	// invalid settings fall back to the default compression
+	s := NewSerializer(&forwarder.MockedForwarder{})
+	assert.Equal(t, defaultCompressor.ContentEncoding(), s.getCompressor(eventsPayloadType).ContentEncoding())
+	assert.Equal(t, "deflate", s.getCompressor(seriesPayloadType).ContentEncoding())
 }
 
+var defaultCompressor, _ = compression.NewCompressor(compression.DefaultKind, compression.DefaultLevel)
+
 var (
 	jsonPayloads     = forwarder.Payloads{}
 	protobufPayloads = forwarder.Payloads{}
@@ -112,7 +133,7 @@ func mkPayloads(payload []byte, compress bool) (forwarder.Payloads, error) {
 	payloads := forwarder.Payloads{}
 	var err error
 	if compress {
-		payload, err = compression.Compress(nil, payload)
+		payload, err = defaultCompressor.Compress(nil, payload)
 		if err != nil {
 			return nil, err
 		}
@@ -123,7 +144,7 @@ func mkPayloads(payload []byte, compress bool) (forwarder.Payloads, error) {
 
 func TestSendV1Events(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
-	f.On("SubmitV1Intake", jsonPayloads, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitV1Intake", jsonPayloads, extraHeadersWithEncoding(jsonExtraHeaders, defaultCompressor.ContentEncoding())).Return(nil).Times(1)
 
 	s := Serializer{Forwarder: f}
 
@@ -139,7 +160,7 @@ func TestSendV1Events(t *testing.T) {
 
 func TestSendEvents(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
-	f.On("SubmitEvents", protobufPayloads, protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitEvents", protobufPayloads, extraHeadersWithEncoding(protobufExtraHeaders, defaultCompressor.ContentEncoding())).Return(nil).Times(1)
 	config.Datadog.Set("use_v2_api.events", true)
 	defer config.Datadog.Set("use_v2_api.events", nil)
 
@@ -157,7 +178,7 @@ func TestSendEvents(t *testing.T) {
 
 func TestSendV1ServiceChecks(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
-	f.On("SubmitV1CheckRuns", jsonPayloads, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitV1CheckRuns", jsonPayloads, extraHeadersWithEncoding(jsonExtraHeaders, defaultCompressor.ContentEncoding())).Return(nil).Times(1)
 
 	s := Serializer{Forwarder: f}
 
@@ -173,7 +194,7 @@ func TestSendV1ServiceChecks(t *testing.T) {
 
 func TestSendServiceChecks(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
-	f.On("SubmitServiceChecks", protobufPayloads, protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitServiceChecks", protobufPayloads, extraHeadersWithEncoding(protobufExtraHeaders, defaultCompressor.ContentEncoding())).Return(nil).Times(1)
 	config.Datadog.Set("use_v2_api.service_checks", true)
 	defer config.Datadog.Set("use_v2_api.service_checks", nil)
 
@@ -191,7 +212,7 @@ func TestSendServiceChecks(t *testing.T) {
 
 func TestSendV1Series(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
-	f.On("SubmitV1Series", jsonPayloads, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitV1Series", jsonPayloads, extraHeadersWithEncoding(jsonExtraHeaders, defaultCompressor.ContentEncoding())).Return(nil).Times(1)
 
 	s := Serializer{Forwarder: f}
 
@@ -207,7 +228,7 @@ func TestSendV1Series(t *testing.T) {
 
 func TestSendSeries(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
-	f.On("SubmitSeries", protobufPayloads, protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitSeries", protobufPayloads, extraHeadersWithEncoding(protobufExtraHeaders, defaultCompressor.ContentEncoding())).Return(nil).Times(1)
 	config.Datadog.Set("use_v2_api.series", true)
 	defer config.Datadog.Set("use_v2_api.series", nil)
 
@@ -31,28 +31,36 @@ var splitterExpvar = expvar.NewMap("splitter")
 
 // CheckSizeAndSerialize Check the size of a payload and marshall it (optionally compress it)
 // The dual role makes sense as you will never serialize without checking the size of the payload
-func CheckSizeAndSerialize(m marshaler.Marshaler, compress bool, mType MarshalType) (bool, []byte, error) {
-	payload, _, err := serializeMarshaller(m, compress, mType)
+func CheckSizeAndSerialize(m marshaler.Marshaler, compressor compression.Compressor, mType MarshalType) (bool, []byte, error) {
+	smallEnough, payload, _, err := checkSizeAndSerialize(m, compressor, mType)
+	return smallEnough, payload, err
+}
+
+// checkSizeAndSerialize behaves like CheckSizeAndSerialize but also returns the size of the payload before compression
+func checkSizeAndSerialize(m marshaler.Marshaler, compressor compression.Compressor, mType MarshalType) (bool, []byte, int, error) {
+	payload, rawPayload, err := serializeMarshaller(m, compressor, mType)
 	if err != nil {
-		return false, nil, err
+		return false, nil, 0, err
 	}
-	return checkSize(payload), payload, nil
+	return checkSize(payload), payload, len(rawPayload), nil
 }
 
-// Payloads serializes a metadata payload and sends it to the forwarder
-func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarder.Payloads, error) {
+// Payloads serializes a metadata payload and sends it to the forwarder.
+// It also returns the total size of the returned payloads before compression.
+func Payloads(m marshaler.Marshaler, compressor compression.Compressor, mType MarshalType) (forwarder.Payloads, int, error) {
 	marshallers := []marshaler.Marshaler{m}
 	smallEnoughPayloads := forwarder.Payloads{}
-	nottoobig, payload, err := CheckSizeAndSerialize(m, compress, mType)
+	rawSize := 0
+	nottoobig, payload, payloadRawSize, err := checkSizeAndSerialize(m, compressor, mType)
 	if err != nil {
-		return smallEnoughPayloads, err
+		return smallEnoughPayloads, rawSize, err
 	}
 	// If the payload's size is fine, just return it
 	if nottoobig {
 		log.Debug("The payload was not too big, returning the full payload")
 		splitterExpvar.Add("NotTooBig", 1)
 		smallEnoughPayloads = append(smallEnoughPayloads, &payload)
-		return smallEnoughPayloads, nil
+		return smallEnoughPayloads, payloadRawSize, nil
 	}
 	splitterExpvar.Add("TooBig", 1)
 	toobig := !nottoobig
@@ -68,9 +76,9 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 		for _, toSplit := range tempSlice {
 			var e error
 			// we have to do this every time to get the proper payload
-			payload, compressedPayload, e := serializeMarshaller(toSplit, compress, mType)
+			payload, compressedPayload, e := serializeMarshaller(toSplit, compressor, mType)
 			if e != nil {
-				return smallEnoughPayloads, e
+				return smallEnoughPayloads, rawSize, e
 			}
 			payloadSize := len(payload)
 			compressedSize := len(compressedPayload)
@@ -82,12 +90,12 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 			chunks, err := toSplit.SplitPayload(numChunks)
 			log.Debugf("payload was split into %f chunks", len(chunks))
 			if err != nil {
-				return smallEnoughPayloads, err
+				return smallEnoughPayloads, rawSize, err
 			}
 			// after the payload has been split, loop through the chunks
 			for _, chunk := range chunks {
 				// serialize the payload
-				smallEnough, payload, err := CheckSizeAndSerialize(chunk, compress, mType)
+				smallEnough, payload, payloadRawSize, err := checkSizeAndSerialize(chunk, compressor, mType)
 				if err != nil {
 					log.Debugf("Error serializing a chunk: %s", err)
 					continue
@@ -95,6 +103,7 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 				if smallEnough {
 					// if the payload is small enough, return it straight away
 					smallEnoughPayloads = append(smallEnoughPayloads, &payload)
+					rawSize += payloadRawSize
 					log.Debugf("chunk was small enough: %v, smallEnoughPayloads are of length: %v", len(payload), len(smallEnoughPayloads))
 				} else {
 					// if it is not, append it to the list of payloads
@@ -112,11 +121,11 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 		}
 	}
 
-	return smallEnoughPayloads, nil
+	return smallEnoughPayloads, rawSize, nil
 }
 
 // serializeMarshaller serializes the marshaller and returns both the compressed and uncompressed payloads
-func serializeMarshaller(m marshaler.Marshaler, compress bool, mType MarshalType) ([]byte, []byte, error) {
+func serializeMarshaller(m marshaler.Marshaler, compressor compression.Compressor, mType MarshalType) ([]byte, []byte, error) {
 	var payload []byte
 	var compressedPayload []byte
 	var err error
@@ -125,8 +134,8 @@ func serializeMarshaller(m marshaler.Marshaler, compress bool, mType MarshalType
 	if err != nil {
 		return nil, nil, err
 	}
-	if compress {
-		compressedPayload, err = compression.Compress(nil, payload)
+	if compressor != nil {
+		compressedPayload, err = compressor.Compress(nil, payload)
 		if err != nil {
 			return nil, nil, err
 		}
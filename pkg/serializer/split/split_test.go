@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/stretchr/testify/require"
 )
 
@@ -40,7 +41,7 @@ func TestSplitPayloadsSeries(t *testing.T) {
 	}
 
 	originalLength := len(testSeries)
-	payloads, err := Payloads(testSeries, false, MarshalJSON)
+	payloads, _, err := Payloads(testSeries, compression.None, MarshalJSON)
 	require.Nil(t, err)
 	var splitSeries = []metrics.Series{}
 	for _, payload := range payloads {
@@ -77,7 +78,7 @@ func TestSplitPayloadsEvents(t *testing.T) {
 	}
 
 	originalLength := len(testEvent)
-	payloads, err := Payloads(testEvent, false, MarshalJSON)
+	payloads, _, err := Payloads(testEvent, compression.None, MarshalJSON)
 	require.Nil(t, err)
 	unrolledEvents := []interface{}{}
 	for _, payload := range payloads {
@@ -110,7 +111,7 @@ func TestSplitPayloadsServiceChecks(t *testing.T) {
 	}
 
 	originalLength := len(testServiceChecks)
-	payloads, err := Payloads(testServiceChecks, false, MarshalJSON)
+	payloads, _, err := Payloads(testServiceChecks, compression.None, MarshalJSON)
 	require.Nil(t, err)
 	unrolledServiceChecks := []interface{}{}
 	for _, payload := range payloads {
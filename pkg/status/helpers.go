@@ -69,6 +69,10 @@ func lastErrorMessage(value string) template.HTML {
 			return template.HTML(lastErrorArray[0]["message"])
 		}
 	}
+	// errors from Go code (e.g. core checks, timeouts) are plain strings
+	if err != nil && value != "" {
+		return template.HTML(value)
+	}
 	return template.HTML("UNKNOWN ERROR")
 }
 
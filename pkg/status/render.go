@@ -35,15 +35,18 @@ func FormatStatus(data []byte) (string, error) {
 	forwarderStats := stats["forwarderStats"]
 	runnerStats := stats["runnerStats"]
 	autoConfigStats := stats["autoConfigStats"]
+	schedulerStats := stats["schedulerStats"]
 	aggregatorStats := stats["aggregatorStats"]
+	metadataStats := stats["metadataStats"]
 	jmxStats := stats["JMXStatus"]
 	title := fmt.Sprintf("Agent (v%s)", stats["version"])
 	stats["title"] = title
 	renderHeader(b, stats)
-	renderChecksStats(b, runnerStats, autoConfigStats, "")
+	renderChecksStats(b, runnerStats, autoConfigStats, schedulerStats, "")
 	renderJMXFetchStatus(b, jmxStats)
 	renderForwarderStatus(b, forwarderStats)
 	renderAggregatorStatus(b, aggregatorStats)
+	renderMetadataStatus(b, metadataStats)
 
 	return b.String(), nil
 }
@@ -72,10 +75,19 @@ func renderForwarderStatus(w io.Writer, forwarderStats interface{}) {
 	}
 }
 
-func renderChecksStats(w io.Writer, runnerStats interface{}, autoConfigStats interface{}, onlyCheck string) {
+func renderMetadataStatus(w io.Writer, metadataStats interface{}) {
+	t := template.Must(template.New("metadata.tmpl").Funcs(fmap).ParseFiles(filepath.Join(templateFolder, "metadata.tmpl")))
+	err := t.Execute(w, metadataStats)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func renderChecksStats(w io.Writer, runnerStats interface{}, autoConfigStats interface{}, schedulerStats interface{}, onlyCheck string) {
 	checkStats := make(map[string]interface{})
 	checkStats["RunnerStats"] = runnerStats
 	checkStats["AutoConfigStats"] = autoConfigStats
+	checkStats["SchedulerStats"] = schedulerStats
 	checkStats["OnlyCheck"] = onlyCheck
 	t := template.Must(template.New("collector.tmpl").Funcs(fmap).ParseFiles(filepath.Join(templateFolder, "collector.tmpl")))
 
@@ -92,7 +104,8 @@ func renderCheckStats(data []byte, checkName string) (string, error) {
 	json.Unmarshal(data, &stats)
 	runnerStats := stats["runnerStats"]
 	autoConfigStats := stats["autoConfigStats"]
-	renderChecksStats(b, runnerStats, autoConfigStats, checkName)
+	schedulerStats := stats["schedulerStats"]
+	renderChecksStats(b, runnerStats, autoConfigStats, schedulerStats, checkName)
 
 	return b.String(), nil
 }
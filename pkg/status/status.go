@@ -127,11 +127,21 @@ func expvarStats(stats map[string]interface{}) (map[string]interface{}, error) {
 	json.Unmarshal(autoConfigStatsJSON, &autoConfigStats)
 	stats["autoConfigStats"] = autoConfigStats
 
+	schedulerStatsJSON := []byte(expvar.Get("scheduler").String())
+	schedulerStats := make(map[string]interface{})
+	json.Unmarshal(schedulerStatsJSON, &schedulerStats)
+	stats["schedulerStats"] = schedulerStats
+
 	aggregatorStatsJSON := []byte(expvar.Get("aggregator").String())
 	aggregatorStats := make(map[string]interface{})
 	json.Unmarshal(aggregatorStatsJSON, &aggregatorStats)
 	stats["aggregatorStats"] = aggregatorStats
 
+	metadataStatsJSON := []byte(expvar.Get("metadata").String())
+	metadataStats := make(map[string]interface{})
+	json.Unmarshal(metadataStatsJSON, &metadataStats)
+	stats["metadataStats"] = metadataStats
+
 	if expvar.Get("ntpOffset").String() != "" {
 		stats["ntpOffset"], err = strconv.ParseFloat(expvar.Get("ntpOffset").String(), 64)
 	}
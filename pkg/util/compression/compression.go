@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package compression
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Supported compression kinds, as used in the configuration
+const (
+	NoneKind = "none"
+	ZlibKind = "zlib"
+	GzipKind = "gzip"
+	ZstdKind = "zstd"
+)
+
+// DefaultLevel selects the default level of the algorithm, 0 is a valid
+// level for some of them, e.g. no compression for zlib and gzip
+const DefaultLevel = math.MinInt32
+
+// Compressor compresses and decompresses payloads with a given algorithm
+type Compressor interface {
+	Compress(dst []byte, src []byte) ([]byte, error)
+	Decompress(dst []byte, src []byte) ([]byte, error)
+	// ContentEncoding describes the HTTP header value associated with the
+	// compression method, empty if the payload isn't compressed
+	ContentEncoding() string
+}
+
+// factory builds a Compressor for a given compression level, DefaultLevel
+// meaning the default level of the algorithm
+type factory func(level int) (Compressor, error)
+
+var factories = map[string]factory{
+	NoneKind: func(int) (Compressor, error) { return None, nil },
+	ZlibKind: newZlibCompressor,
+	GzipKind: newGzipCompressor,
+}
+
+// NewCompressor returns a Compressor for the given kind and level. An
+// empty kind selects DefaultKind and DefaultLevel selects the default level
+// of the algorithm.
+func NewCompressor(kind string, level int) (Compressor, error) {
+	if kind == "" {
+		kind = DefaultKind
+	}
+	f, found := factories[kind]
+	if !found {
+		return nil, fmt.Errorf("unknown compression kind %q, supported kinds are: %v", kind, AvailableKinds())
+	}
+	return f(level)
+}
+
+// AvailableKinds returns the sorted list of compression kinds supported by this build
+func AvailableKinds() []string {
+	kinds := make([]string, 0, len(factories))
+	for k := range factories {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
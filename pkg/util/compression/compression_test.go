@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCompressorDefault(t *testing.T) {
+	c, err := NewCompressor("", DefaultLevel)
+	require.NoError(t, err)
+
+	expected, err := NewCompressor(DefaultKind, DefaultLevel)
+	require.NoError(t, err)
+	assert.Equal(t, expected.ContentEncoding(), c.ContentEncoding())
+}
+
+func TestNewCompressorUnknownKind(t *testing.T) {
+	_, err := NewCompressor("lzma", DefaultLevel)
+	assert.Error(t, err)
+}
+
+func TestNewCompressorInvalidLevel(t *testing.T) {
+	_, err := NewCompressor(ZlibKind, 42)
+	assert.Error(t, err)
+	_, err = NewCompressor(GzipKind, -42)
+	assert.Error(t, err)
+}
+
+func TestNewCompressorNoCompressionLevel(t *testing.T) {
+	payload := bytes.Repeat([]byte("test.metric"), 100)
+	for _, kind := range []string{ZlibKind, GzipKind} {
+		// level 0 stores the payload as is, it's not the default level
+		stored, err := NewCompressor(kind, 0)
+		require.NoError(t, err, kind)
+		compressed, err := stored.Compress(nil, payload)
+		require.NoError(t, err, kind)
+		assert.True(t, len(compressed) > len(payload), kind)
+
+		def, err := NewCompressor(kind, DefaultLevel)
+		require.NoError(t, err, kind)
+		compressed, err = def.Compress(nil, payload)
+		require.NoError(t, err, kind)
+		assert.True(t, len(compressed) < len(payload), kind)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	payload := []byte(`{"series":[{"metric":"test.metric","points":[[1,1]]}]}`)
+	encodings := map[string]string{
+		NoneKind: "",
+		ZlibKind: "deflate",
+		GzipKind: "gzip",
+		ZstdKind: "zstd",
+	}
+
+	for _, kind := range AvailableKinds() {
+		for _, level := range []int{DefaultLevel, 1} {
+			c, err := NewCompressor(kind, level)
+			require.NoError(t, err, kind)
+			assert.Equal(t, encodings[kind], c.ContentEncoding())
+
+			compressed, err := c.Compress(nil, payload)
+			require.NoError(t, err, kind)
+			decompressed, err := c.Decompress(nil, compressed)
+			require.NoError(t, err, kind)
+			assert.Equal(t, payload, decompressed, kind)
+		}
+	}
+}
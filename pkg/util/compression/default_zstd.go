@@ -0,0 +1,12 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+// +build zstd
+
+package compression
+
+// DefaultKind is the compression kind used when none is configured,
+// chosen at build time
+const DefaultKind = ZstdKind
@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+)
+
+type gzipCompressor struct {
+	level int
+}
+
+func newGzipCompressor(level int) (Compressor, error) {
+	if level == DefaultLevel {
+		level = gzip.DefaultCompression
+	}
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, fmt.Errorf("invalid gzip compression level: %d", level)
+	}
+	return &gzipCompressor{level: level}, nil
+}
+
+// Compress will compress the data with gzip
+func (c *gzipCompressor) Compress(dst []byte, src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := gzip.NewWriterLevel(&b, c.level)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	dst = b.Bytes()
+	return dst, nil
+}
+
+// Decompress will decompress the data with gzip
+func (c *gzipCompressor) Decompress(dst []byte, src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	dst, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// ContentEncoding returns the HTTP header value for gzip payloads
+func (c *gzipCompressor) ContentEncoding() string {
+	return "gzip"
+}
@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017 Datadog, Inc.
+
+package compression
+
+// None is the Compressor leaving payloads untouched
+var None Compressor = noneCompressor{}
+
+type noneCompressor struct{}
+
+// Compress will not compress anything
+func (noneCompressor) Compress(dst []byte, src []byte) ([]byte, error) {
+	return src, nil
+}
+
+// Decompress will not decompress anything
+func (noneCompressor) Decompress(dst []byte, src []byte) ([]byte, error) {
+	return src, nil
+}
+
+// ContentEncoding is empty since there's no compression
+func (noneCompressor) ContentEncoding() string {
+	return ""
+}
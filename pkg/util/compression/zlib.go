@@ -3,25 +3,37 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2017 Datadog, Inc.
 
-// +build zlib
-
 package compression
 
 import (
 	"bytes"
 	"compress/zlib"
+	"fmt"
 	"io/ioutil"
 )
 
-// ContentEncoding describes the HTTP header value associated with the compression method
-// var instead of const to ease testing
-var ContentEncoding = "deflate"
+type zlibCompressor struct {
+	level int
+}
+
+func newZlibCompressor(level int) (Compressor, error) {
+	if level == DefaultLevel {
+		level = zlib.DefaultCompression
+	}
+	if level < zlib.HuffmanOnly || level > zlib.BestCompression {
+		return nil, fmt.Errorf("invalid zlib compression level: %d", level)
+	}
+	return &zlibCompressor{level: level}, nil
+}
 
 // Compress will compress the data with zlib
-func Compress(dst []byte, src []byte) ([]byte, error) {
+func (c *zlibCompressor) Compress(dst []byte, src []byte) ([]byte, error) {
 	var b bytes.Buffer
-	w := zlib.NewWriter(&b)
-	_, err := w.Write(src)
+	w, err := zlib.NewWriterLevel(&b, c.level)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(src)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +46,7 @@ func Compress(dst []byte, src []byte) ([]byte, error) {
 }
 
 // Decompress will decompress the data with zlib
-func Decompress(dst []byte, src []byte) ([]byte, error) {
+func (c *zlibCompressor) Decompress(dst []byte, src []byte) ([]byte, error) {
 	r, err := zlib.NewReader(bytes.NewReader(src))
 	if err != nil {
 		return nil, err
@@ -47,3 +59,8 @@ func Decompress(dst []byte, src []byte) ([]byte, error) {
 	}
 	return dst, nil
 }
+
+// ContentEncoding returns the HTTP header value for zlib payloads
+func (c *zlibCompressor) ContentEncoding() string {
+	return "deflate"
+}
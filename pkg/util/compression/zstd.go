@@ -7,21 +7,44 @@
 
 package compression
 
-import "github.com/DataDog/zstd"
+import (
+	"fmt"
+
+	"github.com/DataDog/zstd"
+)
 
 // TODO: the intake still uses a pre-v1 (unstable) version of the zstd compression format.
 // The agent shouldn't use zstd compression until the intake supports a stable v1 format.
 
-// ContentEncoding describes the HTTP header value associated with the compression method
-// var instead of const to ease testing
-var ContentEncoding = "zstd"
+func init() {
+	factories[ZstdKind] = newZstdCompressor
+}
+
+type zstdCompressor struct {
+	level int
+}
+
+func newZstdCompressor(level int) (Compressor, error) {
+	if level == DefaultLevel {
+		level = zstd.DefaultCompression
+	}
+	if level < zstd.BestSpeed || level > zstd.BestCompression {
+		return nil, fmt.Errorf("invalid zstd compression level: %d", level)
+	}
+	return &zstdCompressor{level: level}, nil
+}
 
 // Compress will compress the data with zstd
-func Compress(dst []byte, src []byte) ([]byte, error) {
-	return zstd.Compress(dst, src)
+func (c *zstdCompressor) Compress(dst []byte, src []byte) ([]byte, error) {
+	return zstd.CompressLevel(dst, src, c.level)
 }
 
 // Decompress will decompress the data with zstd
-func Decompress(dst []byte, src []byte) ([]byte, error) {
+func (c *zstdCompressor) Decompress(dst []byte, src []byte) ([]byte, error) {
 	return zstd.Decompress(dst, src)
 }
+
+// ContentEncoding returns the HTTP header value for zstd payloads
+func (c *zstdCompressor) ContentEncoding() string {
+	return "zstd"
+}
@@ -24,8 +24,9 @@ type PodWatcher struct {
 }
 
 // NewPodWatcher creates a new watcher. User call must then trigger PullChanges
-// and ExpireContainers when needed.
-func NewPodWatcher() (*PodWatcher, error) {
+// and ExpireContainers when needed. Containers are expired once they haven't
+// been listed for expiryDuration.
+func NewPodWatcher(expiryDuration time.Duration) (*PodWatcher, error) {
 	kubeutil, err := NewKubeUtil()
 	if err != nil {
 		return nil, err
@@ -34,7 +35,7 @@ func NewPodWatcher() (*PodWatcher, error) {
 		kubeUtil:         kubeutil,
 		latestResVersion: -1,
 		lastSeen:         make(map[string]time.Time),
-		expiryDuration:   5 * time.Minute,
+		expiryDuration:   expiryDuration,
 	}
 	return watcher, nil
 }
@@ -119,7 +119,7 @@ func TestPullChanges(t *testing.T) {
 	config.Datadog.SetDefault("kubernetes_kubelet_host", "localhost")
 	config.Datadog.SetDefault("kubernetes_http_kubelet_port", kubeletPort)
 
-	watcher, err := NewPodWatcher()
+	watcher, err := NewPodWatcher(5 * time.Minute)
 	require.Nil(t, err)
 	require.NotNil(t, watcher)
 	<-kubelet.Requests // Throwing away /healthz GET
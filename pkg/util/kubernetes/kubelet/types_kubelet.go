@@ -37,9 +37,33 @@ type PodOwner struct {
 
 // Spec contains fields for unmarshalling a Pod.Spec
 type Spec struct {
-	HostNetwork bool   `json:"hostNetwork,omitempty"`
-	Hostname    string `json:"hostname,omitempty"` // TODO: does it exist?
-	NodeName    string `json:"nodeName,omitempty"`
+	HostNetwork bool            `json:"hostNetwork,omitempty"`
+	Hostname    string          `json:"hostname,omitempty"` // TODO: does it exist?
+	NodeName    string          `json:"nodeName,omitempty"`
+	Containers  []ContainerSpec `json:"containers,omitempty"`
+}
+
+// ContainerSpec contains fields for unmarshalling a Pod.Spec.Containers
+type ContainerSpec struct {
+	Name  string          `json:"name"`
+	Image string          `json:"image,omitempty"`
+	Ports []ContainerPort `json:"ports,omitempty"`
+	Env   []EnvVar        `json:"env,omitempty"`
+}
+
+// ContainerPort contains fields for unmarshalling a Pod.Spec.Containers.Ports
+type ContainerPort struct {
+	ContainerPort int    `json:"containerPort"`
+	HostPort      int    `json:"hostPort,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Protocol      string `json:"protocol"`
+}
+
+// EnvVar contains fields for unmarshalling a Pod.Spec.Containers.Env, the
+// variables set from a source (`valueFrom`) have no value
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
 }
 
 // Status contains fields for unmarshalling a Pod.Status
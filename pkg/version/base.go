@@ -10,6 +10,10 @@ package version
 // AgentVersion contains the version of the Agent
 var AgentVersion string
 
+// AgentBuildTags contains the comma separated list of build tags the Agent
+// was built with, it's set at build time
+var AgentBuildTags string
+
 var agentVersionDefault = "6.0.0"
 
 func init() {
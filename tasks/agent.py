@@ -37,6 +37,7 @@ def build(ctx, rebuild=False, race=False, build_include=None, build_exclude=None
     else:
         build_tags = get_build_tags(build_include, build_exclude)
     ldflags, gcflags = get_build_flags(ctx, use_embedded_libs=use_embedded_libs)
+    ldflags += "-X {}/pkg/version.AgentBuildTags={} ".format(REPO_PATH, ",".join(build_tags))
 
     env = {
         "PKG_CONFIG_PATH": pkg_config_path(use_embedded_libs)
@@ -223,7 +223,7 @@ func main() {
 
 	config.Datadog.Set("dogstatsd_stats_enable", true)
 	config.Datadog.Set("dogstatsd_stats_buffer", 100)
-	s := &serializer.Serializer{Forwarder: f}
+	s := serializer.NewSerializer(f)
 	aggr := aggregator.InitAggregator(s, "localhost")
 	statsd, err := dogstatsd.NewServer(aggr.GetChannels())
 	if err != nil {